@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"meme-trader/internal/api"
 	"meme-trader/internal/config"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout is how long requests in flight get to complete on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	// Load configuration
 	cfg := config.NewConfig()
@@ -25,7 +33,28 @@ func main() {
 	// Start the server
 	addr := fmt.Sprintf(":%s", cfg.ServerPort)
 	log.Printf("Starting server on %s", addr)
-	if err := app.Run(addr); err != nil {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- app.Run(addr)
+	}()
+
+	// Stop on SIGINT or SIGTERM, or when the server fails
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errs:
+		app.Close()
 		log.Fatalf("Server failed to start: %v", err)
+	case sig := <-stop:
+		log.Printf("Received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := app.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down server: %v", err)
+	}
+	if err := app.Close(); err != nil {
+		log.Printf("Failed to close application: %v", err)
 	}
 }
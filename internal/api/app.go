@@ -2,21 +2,37 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"meme-trader/internal/api/handlers"
+	"meme-trader/internal/blockchain"
+	"meme-trader/internal/blockchain/solana"
 	"meme-trader/internal/config"
 	"meme-trader/internal/repository/postgres"
 	"meme-trader/internal/services/memecoin"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 )
 
+// solanaHealthCheckAddress is the system program, which exists on every cluster
+const solanaHealthCheckAddress = "11111111111111111111111111111111"
+
 type App struct {
-	Router  *mux.Router
-	Service *memecoin.Service
+	Router     *mux.Router
+	Service    *memecoin.Service
+	Blockchain blockchain.Service
+
+	db       *postgres.Database
+	serverMu sync.Mutex
+	server   *http.Server // Set once Run starts serving
 }
 
 func NewApp(cfg *config.Config) (*App, error) {
@@ -32,11 +48,20 @@ func NewApp(cfg *config.Config) (*App, error) {
 	// Initialize service
 	service := memecoin.NewService(db, logger)
 
+	// Initialize blockchain service
+	blockchainService, err := newBlockchainService(cfg, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	// Create router
 	router := mux.NewRouter()
 
 	// Initialize handlers
 	memeHandler := handlers.NewMemeHandler(service)
+	blockchainHandler := handlers.NewBlockchainHandler(blockchainService)
+	adminHandler := handlers.NewAdminHandler(blockchainService, cfg.AdminToken)
 
 	// Register routes
 	router.HandleFunc("/api/v1/memecoins", memeHandler.GetTopMemeCoins).Methods("GET", "OPTIONS")
@@ -48,13 +73,65 @@ func NewApp(cfg *config.Config) (*App, error) {
 		}
 		w.WriteHeader(http.StatusOK)
 	}).Methods("POST", "OPTIONS")
+	blockchainHandler.RegisterRoutes(router)
+	adminHandler.RegisterRoutes(router)
 
 	return &App{
-		Router:  router,
-		Service: service,
+		Router:     router,
+		Service:    service,
+		Blockchain: blockchainService,
+		db:         db,
 	}, nil
 }
 
+// newBlockchainService builds a database-backed blockchain service with a
+// Solana provider for the primary endpoint and each fallback endpoint
+func newBlockchainService(cfg *config.Config, db *postgres.Database) (blockchain.Service, error) {
+	endpoints := append([]string{cfg.SolanaEndpoint}, cfg.SolanaFallbackEndpoints...)
+	signer := solana.NewWalletSigner(db)
+
+	service := blockchain.NewServiceWithWalletStore(db)
+	service.SetPriceStore(db)
+	for i, endpoint := range endpoints {
+		opts := solana.ProviderOptions{
+			RPCEndpoint:     endpoint,
+			IsDevnet:        strings.Contains(endpoint, "devnet"),
+			Commitment:      rpc.CommitmentType(cfg.SolanaCommitment),
+			LazyWS:          true,
+			Signer:          signer,
+			Transactions:    db,
+			JupiterEndpoint: cfg.JupiterEndpoint,
+			LookupTables:    cfg.SolanaLookupTables,
+		}
+		name := "solana-primary"
+		if i == 0 {
+			opts.WSEndpoint = cfg.SolanaWSEndpoint
+		} else {
+			name = fmt.Sprintf("solana-fallback-%d", i)
+		}
+
+		provider, err := solana.NewProviderWithOptions(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create solana provider %s: %w", name, err)
+		}
+
+		err = service.RegisterProviderWithConfig(provider, blockchain.ProviderConfig{
+			Name:               name,
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			HealthCheckPeriod:  time.Minute,
+			HealthCheckAddress: solanaHealthCheckAddress,
+			MaxConsecutiveErrs: 3,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to register solana provider %s: %w", name, err)
+		}
+	}
+
+	return service, nil
+}
+
 func (a *App) Run(addr string) error {
 	// Initial fetch of meme coins
 	if err := a.Service.FetchAndUpdateMemeCoins(context.Background()); err != nil {
@@ -85,6 +162,33 @@ func (a *App) Run(addr string) error {
 	// Wrap router with CORS middleware
 	handler := c.Handler(a.Router)
 
+	server := &http.Server{Addr: addr, Handler: handler}
+	a.serverMu.Lock()
+	a.server = server
+	a.serverMu.Unlock()
+
 	log.Printf("Server starting on %s with CORS enabled for development", addr)
-	return http.ListenAndServe(addr, handler)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server from accepting requests and waits for the ones in
+// flight to complete, or for ctx to be done
+func (a *App) Shutdown(ctx context.Context) error {
+	a.serverMu.Lock()
+	server := a.server
+	a.serverMu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Close stops the blockchain providers, with their health checks, transaction
+// trackers and WebSocket connections, and closes the database
+func (a *App) Close() error {
+	return errors.Join(a.Blockchain.Close(), a.db.Close())
 }
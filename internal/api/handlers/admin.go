@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"meme-trader/internal/blockchain"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminHandler struct {
+	service blockchain.Service
+	token   string
+}
+
+func NewAdminHandler(service blockchain.Service, token string) *AdminHandler {
+	return &AdminHandler{service: service, token: token}
+}
+
+func (h *AdminHandler) RegisterRoutes(r *mux.Router) {
+	admin := r.PathPrefix("/api/v1/admin").Subrouter()
+	admin.Use(h.authenticate)
+
+	admin.HandleFunc("/providers", h.GetProviders).Methods("GET")
+	admin.HandleFunc("/providers/{network}/{name}/drain", h.DrainProvider).Methods("POST")
+	admin.HandleFunc("/providers/{network}/{name}/disable", h.DisableProvider).Methods("POST")
+	admin.HandleFunc("/providers/{network}/{name}/enable", h.EnableProvider).Methods("POST")
+	admin.HandleFunc("/providers/{network}/{name}/priority", h.SetProviderPriority).Methods("POST")
+}
+
+// authenticate requires the admin bearer token. With no token configured the
+// admin API is disabled entirely.
+func (h *AdminHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.token == "" {
+			http.Error(w, "Admin API disabled", http.StatusForbidden)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type ProviderResponse struct {
+	Name              string    `json:"name"`
+	Network           string    `json:"network"`
+	Priority          int       `json:"priority"`
+	Weight            int       `json:"weight"`
+	Status            string    `json:"status"`
+	Draining          bool      `json:"draining"`
+	Drained           bool      `json:"drained"`
+	Disabled          bool      `json:"disabled"`
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	RequestsInWindow  int       `json:"requests_in_window"`
+	InFlight          int64     `json:"in_flight"`
+	LatencyP50Ms      float64   `json:"latency_p50_ms"`
+	LatencyP95Ms      float64   `json:"latency_p95_ms"`
+	LatencyP99Ms      float64   `json:"latency_p99_ms"`
+	LastChecked       time.Time `json:"last_checked"`
+	Breaker           string    `json:"breaker"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func (h *AdminHandler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	snapshots := h.service.ProviderSnapshots()
+
+	response := make(map[string][]ProviderResponse, len(snapshots))
+	for network, providers := range snapshots {
+		entries := make([]ProviderResponse, len(providers))
+		for i, p := range providers {
+			entries[i] = ProviderResponse{
+				Name:              p.Name,
+				Network:           string(p.Network),
+				Priority:          p.Priority,
+				Weight:            p.Weight,
+				Status:            p.Status.String(),
+				Draining:          p.Draining,
+				Drained:           p.Drained,
+				Disabled:          p.Disabled,
+				ConsecutiveErrors: p.ConsecutiveErrors,
+				RequestsInWindow:  p.RequestsInWindow,
+				InFlight:          p.InFlight,
+				LatencyP50Ms:      milliseconds(p.LatencyP50),
+				LatencyP95Ms:      milliseconds(p.LatencyP95),
+				LatencyP99Ms:      milliseconds(p.LatencyP99),
+				LastChecked:       p.LastChecked,
+				Breaker:           p.Breaker.String(),
+			}
+		}
+		response[string(network)] = entries
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// providerAction runs an admin action against the provider named in the URL
+func (h *AdminHandler) providerAction(w http.ResponseWriter, r *http.Request, action func(blockchain.Network, string) error) {
+	vars := mux.Vars(r)
+	if err := action(blockchain.Network(vars["network"]), vars["name"]); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func (h *AdminHandler) DrainProvider(w http.ResponseWriter, r *http.Request) {
+	h.providerAction(w, r, h.service.DrainProvider)
+}
+
+func (h *AdminHandler) DisableProvider(w http.ResponseWriter, r *http.Request) {
+	h.providerAction(w, r, h.service.DisableProvider)
+}
+
+func (h *AdminHandler) EnableProvider(w http.ResponseWriter, r *http.Request) {
+	h.providerAction(w, r, h.service.EnableProvider)
+}
+
+type SetPriorityRequest struct {
+	Priority *int `json:"priority"`
+}
+
+func (h *AdminHandler) SetProviderPriority(w http.ResponseWriter, r *http.Request) {
+	var req SetPriorityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Priority == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.providerAction(w, r, func(network blockchain.Network, name string) error {
+		return h.service.SetProviderPriority(network, name, *req.Priority)
+	})
+}
+
+// writeAdminError maps an admin action error to an HTTP response
+func writeAdminError(w http.ResponseWriter, err error) {
+	if errors.Is(err, blockchain.ErrProviderNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"math/big"
 	"meme-trader/internal/blockchain"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -21,12 +23,47 @@ func (h *BlockchainHandler) RegisterRoutes(r *mux.Router) {
 	r.HandleFunc("/api/v1/wallets", h.CreateWallet).Methods("POST")
 	r.HandleFunc("/api/v1/wallets/{network}/{address}", h.GetWallet).Methods("GET")
 	r.HandleFunc("/api/v1/wallets/{network}/{address}/balance", h.GetBalance).Methods("GET")
+	r.HandleFunc("/api/v1/wallets/{network}/{address}/tokens", h.GetTokens).Methods("GET")
+	r.HandleFunc("/api/v1/quote", h.Quote).Methods("GET", "POST")
 	r.HandleFunc("/api/v1/transactions/buy", h.Buy).Methods("POST")
 	r.HandleFunc("/api/v1/transactions/sell", h.Sell).Methods("POST")
+	r.HandleFunc("/api/v1/transactions/build", h.BuildTransaction).Methods("POST")
+	r.HandleFunc("/api/v1/transactions/submit", h.SubmitTransaction).Methods("POST")
+	r.HandleFunc("/api/v1/transfers", h.Transfer).Methods("POST")
 	r.HandleFunc("/api/v1/transactions/{network}/{txID}", h.GetTransaction).Methods("GET")
 	r.HandleFunc("/api/v1/wallets/{network}/{address}/transactions", h.GetTransactions).Methods("GET")
 }
 
+// writeServiceError maps a blockchain service error to an HTTP response
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case blockchain.IsValidationError(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case blockchain.IsSimulationError(err):
+		// The trade would fail on-chain, so it was never sent
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case blockchain.IsAmbiguousWriteError(err):
+		// The trade may have gone through; the client must check history before retrying
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// authorizeWallet requires the access token issued with the wallet a request
+// spends from, as a bearer token, before the server signs with its key
+func (h *BlockchainHandler) authorizeWallet(w http.ResponseWriter, r *http.Request, network blockchain.Network, address string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = ""
+	}
+	if err := h.service.AuthorizeWallet(r.Context(), network, address, token); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
 type CreateWalletRequest struct {
 	Network blockchain.Network `json:"network"`
 }
@@ -68,19 +105,89 @@ func (h *BlockchainHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 	balance, err := h.service.GetBalance(r.Context(), network, address)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(balance)
 }
 
+// GetTokens returns the token holdings of a wallet valued in USD
+func (h *BlockchainHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	network := blockchain.Network(vars["network"])
+	address := vars["address"]
+
+	portfolio, err := h.service.GetPortfolio(r.Context(), network, address)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(portfolio)
+}
+
+type QuoteRequest struct {
+	Network     blockchain.Network `json:"network"`
+	InputMint   string             `json:"input_mint"`
+	OutputMint  string             `json:"output_mint"`
+	Amount      blockchain.Amount  `json:"amount"`
+	SlippageBps uint16             `json:"slippage_bps"`
+}
+
+// Quote previews a swap of an exact amount. GET takes the same fields as
+// query parameters, with amount as a raw integer.
+func (h *BlockchainHandler) Quote(w http.ResponseWriter, r *http.Request) {
+	var req QuoteRequest
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Network = blockchain.Network(query.Get("network"))
+		req.InputMint = query.Get("input_mint")
+		req.OutputMint = query.Get("output_mint")
+
+		amount, ok := new(big.Int).SetString(query.Get("amount"), 10)
+		if !ok {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+		req.Amount = blockchain.Amount{Value: amount}
+
+		if slippage := query.Get("slippage_bps"); slippage != "" {
+			bps, err := strconv.ParseUint(slippage, 10, 16)
+			if err != nil {
+				http.Error(w, "Invalid slippage_bps", http.StatusBadRequest)
+				return
+			}
+			req.SlippageBps = uint16(bps)
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := h.service.Quote(r.Context(), req.Network, blockchain.QuoteRequest{
+		InputMint:   req.InputMint,
+		OutputMint:  req.OutputMint,
+		Amount:      req.Amount,
+		SlippageBps: req.SlippageBps,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(quote)
+}
+
 type BuyRequest struct {
 	Network       blockchain.Network `json:"network"`
 	WalletAddress string             `json:"wallet_address"`
 	TokenAddress  string             `json:"token_address"`
 	Amount        blockchain.Amount  `json:"amount"`
 	MaxPrice      blockchain.Amount  `json:"max_price"`
+	SlippageBps   uint16             `json:"slippage_bps"`
+	PriorityFee   uint64             `json:"priority_fee"`
+	QuoteID       string             `json:"quote_id"`
 }
 
 func (h *BlockchainHandler) Buy(w http.ResponseWriter, r *http.Request) {
@@ -89,15 +196,21 @@ func (h *BlockchainHandler) Buy(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if !h.authorizeWallet(w, r, req.Network, req.WalletAddress) {
+		return
+	}
 
 	tx, err := h.service.Buy(r.Context(), req.Network, blockchain.BuyRequest{
 		WalletAddress: req.WalletAddress,
 		TokenAddress:  req.TokenAddress,
 		Amount:        req.Amount,
 		MaxPrice:      req.MaxPrice,
+		SlippageBps:   req.SlippageBps,
+		PriorityFee:   req.PriorityFee,
+		QuoteID:       req.QuoteID,
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -110,6 +223,9 @@ type SellRequest struct {
 	TokenAddress  string             `json:"token_address"`
 	Amount        blockchain.Amount  `json:"amount"`
 	MinPrice      blockchain.Amount  `json:"min_price"`
+	SlippageBps   uint16             `json:"slippage_bps"`
+	PriorityFee   uint64             `json:"priority_fee"`
+	QuoteID       string             `json:"quote_id"`
 }
 
 func (h *BlockchainHandler) Sell(w http.ResponseWriter, r *http.Request) {
@@ -118,15 +234,119 @@ func (h *BlockchainHandler) Sell(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if !h.authorizeWallet(w, r, req.Network, req.WalletAddress) {
+		return
+	}
 
 	tx, err := h.service.Sell(r.Context(), req.Network, blockchain.SellRequest{
 		WalletAddress: req.WalletAddress,
 		TokenAddress:  req.TokenAddress,
 		Amount:        req.Amount,
 		MinPrice:      req.MinPrice,
+		SlippageBps:   req.SlippageBps,
+		PriorityFee:   req.PriorityFee,
+		QuoteID:       req.QuoteID,
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tx)
+}
+
+type TransferRequest struct {
+	Network                blockchain.Network `json:"network"`
+	WalletAddress          string             `json:"wallet_address"`
+	ToAddress              string             `json:"to_address"`
+	TokenAddress           string             `json:"token_address"` // Empty for SOL
+	Amount                 blockchain.Amount  `json:"amount"`
+	CreateRecipientAccount bool               `json:"create_recipient_account"`
+	PriorityFee            uint64             `json:"priority_fee"`
+}
+
+func (h *BlockchainHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !h.authorizeWallet(w, r, req.Network, req.WalletAddress) {
+		return
+	}
+
+	tx, err := h.service.Transfer(r.Context(), req.Network, blockchain.TransferRequest{
+		WalletAddress:          req.WalletAddress,
+		ToAddress:              req.ToAddress,
+		TokenAddress:           req.TokenAddress,
+		Amount:                 req.Amount,
+		CreateRecipientAccount: req.CreateRecipientAccount,
+		PriorityFee:            req.PriorityFee,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tx)
+}
+
+type BuildTransactionRequest struct {
+	Network       blockchain.Network         `json:"network"`
+	Type          blockchain.TransactionType `json:"type"`
+	WalletAddress string                     `json:"wallet_address"`
+	TokenAddress  string                     `json:"token_address"`
+	Amount        blockchain.Amount          `json:"amount"`
+	MinAmountOut  blockchain.Amount          `json:"min_amount_out"`
+	SlippageBps   uint16                     `json:"slippage_bps"`
+	PriorityFee   uint64                     `json:"priority_fee"`
+}
+
+// BuildTransaction returns an unsigned swap transaction for the wallet to sign
+func (h *BlockchainHandler) BuildTransaction(w http.ResponseWriter, r *http.Request) {
+	var req BuildTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	built, err := h.service.BuildSwap(r.Context(), req.Network, blockchain.BuildSwapRequest{
+		Type:             req.Type,
+		WalletAddress:    req.WalletAddress,
+		TokenAddress:     req.TokenAddress,
+		Amount:           req.Amount,
+		MinimumAmountOut: req.MinAmountOut,
+		SlippageBps:      req.SlippageBps,
+		PriorityFee:      req.PriorityFee,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(built)
+}
+
+type SubmitTransactionRequest struct {
+	Network     blockchain.Network `json:"network"`
+	ID          string             `json:"id"`
+	Transaction string             `json:"transaction"`
+}
+
+// SubmitTransaction broadcasts a transaction signed by the wallet
+func (h *BlockchainHandler) SubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	var req SubmitTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.service.SubmitTransaction(r.Context(), req.Network, blockchain.SubmitTransactionRequest{
+		ID:          req.ID,
+		Transaction: req.Transaction,
+	})
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
@@ -152,18 +372,22 @@ func (h *BlockchainHandler) GetTransactions(w http.ResponseWriter, r *http.Reque
 	network := blockchain.Network(vars["network"])
 	address := vars["address"]
 
-	limit := 100 // Default limit
+	limit := 0 // The provider's default
 	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
 		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
 			limit = l
 		}
 	}
 
-	transactions, err := h.service.GetTransactions(r.Context(), network, address, limit)
+	page, err := h.service.GetTransactions(r.Context(), network, address, blockchain.TransactionsRequest{
+		Limit:  limit,
+		Before: r.URL.Query().Get("before"),
+		Until:  r.URL.Query().Get("until"),
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeServiceError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(transactions)
+	json.NewEncoder(w).Encode(page)
 }
@@ -0,0 +1,207 @@
+package blockchain
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState represents the state of a provider's circuit breaker
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+const (
+	// defaultBreakerFailureThreshold is used when neither BreakerFailureThreshold nor MaxConsecutiveErrs is set
+	defaultBreakerFailureThreshold = 5
+
+	// defaultBreakerOpenDuration is used when ProviderConfig.BreakerOpenDuration is not set
+	defaultBreakerOpenDuration = 30 * time.Second
+
+	// defaultBreakerHalfOpenCalls is used when ProviderConfig.BreakerHalfOpenCalls is not set
+	defaultBreakerHalfOpenCalls = 1
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker stops traffic to a failing provider. After failureThreshold
+// consecutive failures it opens for openDuration, then lets up to
+// halfOpenCalls trial requests through. The breaker closes once all trials
+// succeed and reopens on the first failed trial.
+type circuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+	halfOpenCalls    int
+
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	inFlight  int
+	successes int
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// newCircuitBreaker creates a breaker from a provider configuration
+func newCircuitBreaker(config ProviderConfig) *circuitBreaker {
+	threshold := config.BreakerFailureThreshold
+	if threshold <= 0 {
+		threshold = config.MaxConsecutiveErrs
+	}
+	if threshold <= 0 {
+		threshold = defaultBreakerFailureThreshold
+	}
+
+	openDuration := config.BreakerOpenDuration
+	if openDuration <= 0 {
+		openDuration = defaultBreakerOpenDuration
+	}
+
+	halfOpenCalls := config.BreakerHalfOpenCalls
+	if halfOpenCalls <= 0 {
+		halfOpenCalls = defaultBreakerHalfOpenCalls
+	}
+
+	return &circuitBreaker{
+		failureThreshold: threshold,
+		openDuration:     openDuration,
+		halfOpenCalls:    halfOpenCalls,
+		state:            BreakerClosed,
+		now:              time.Now,
+	}
+}
+
+// refresh moves an open breaker to half-open once its open duration has
+// elapsed. The caller must hold b.mu.
+func (b *circuitBreaker) refresh() {
+	if b.state == BreakerOpen && !b.now().Before(b.openedAt.Add(b.openDuration)) {
+		b.halfOpen()
+	}
+}
+
+// halfOpen starts a new round of trial calls. The caller must hold b.mu.
+func (b *circuitBreaker) halfOpen() {
+	b.state = BreakerHalfOpen
+	b.inFlight = 0
+	b.successes = 0
+}
+
+// open trips the breaker. The caller must hold b.mu.
+func (b *circuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.inFlight = 0
+	b.successes = 0
+}
+
+// allow reserves permission to send a request. Every successful call must be
+// followed by exactly one of onSuccess, onFailure or release.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.inFlight+b.successes < b.halfOpenCalls {
+			b.inFlight++
+			return true
+		}
+	}
+	return false
+}
+
+// canAttempt reports whether allow would currently succeed, without reserving a call
+func (b *circuitBreaker) canAttempt() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		return b.inFlight+b.successes < b.halfOpenCalls
+	}
+	return false
+}
+
+// onSuccess records a successful call
+func (b *circuitBreaker) onSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		b.failures = 0
+	case BreakerHalfOpen:
+		b.inFlight--
+		b.successes++
+		if b.successes >= b.halfOpenCalls {
+			b.state = BreakerClosed
+			b.failures = 0
+		}
+	}
+}
+
+// onFailure records a failed call that counts against the provider
+func (b *circuitBreaker) onFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.open()
+		}
+	case BreakerHalfOpen:
+		b.open()
+	}
+}
+
+// release gives back a reserved call whose outcome says nothing about the provider
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+// probeSucceeded lets a successful out-of-band health check cut an open period short
+func (b *circuitBreaker) probeSucceeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		b.halfOpen()
+	}
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	return b.state
+}
@@ -0,0 +1,166 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCircuitBreaker(config ProviderConfig) (*circuitBreaker, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	breaker := newCircuitBreaker(config)
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	breaker, _ := newTestCircuitBreaker(ProviderConfig{BreakerFailureThreshold: 2})
+
+	assert.True(t, breaker.allow())
+	breaker.onFailure()
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	assert.True(t, breaker.allow())
+	breaker.onFailure()
+	assert.Equal(t, BreakerOpen, breaker.State())
+	assert.False(t, breaker.allow())
+	assert.False(t, breaker.canAttempt())
+}
+
+func TestCircuitBreakerHalfOpenRecovery(t *testing.T) {
+	breaker, now := newTestCircuitBreaker(ProviderConfig{
+		BreakerFailureThreshold: 1,
+		BreakerOpenDuration:     time.Second,
+		BreakerHalfOpenCalls:    2,
+	})
+
+	assert.True(t, breaker.allow())
+	breaker.onFailure()
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// Half-open once the open duration elapses, with a limited number of trials
+	*now = now.Add(time.Second)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	assert.True(t, breaker.allow())
+	assert.True(t, breaker.allow())
+	assert.False(t, breaker.allow(), "Only two trial calls should be allowed")
+
+	breaker.onSuccess()
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	breaker.onSuccess()
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	breaker, now := newTestCircuitBreaker(ProviderConfig{
+		BreakerFailureThreshold: 1,
+		BreakerOpenDuration:     time.Second,
+	})
+
+	assert.True(t, breaker.allow())
+	breaker.onFailure()
+
+	*now = now.Add(time.Second)
+	assert.True(t, breaker.allow())
+	breaker.onFailure()
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// The open period restarts from the failed trial
+	*now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, BreakerOpen, breaker.State())
+}
+
+func TestCircuitBreakerReleaseFreesTrial(t *testing.T) {
+	breaker, now := newTestCircuitBreaker(ProviderConfig{
+		BreakerFailureThreshold: 1,
+		BreakerOpenDuration:     time.Second,
+	})
+
+	assert.True(t, breaker.allow())
+	breaker.onFailure()
+	*now = now.Add(time.Second)
+
+	assert.True(t, breaker.allow())
+	assert.False(t, breaker.allow())
+	breaker.release()
+	assert.True(t, breaker.allow())
+}
+
+func TestProviderManagerValidationErrorsDoNotTripBreaker(t *testing.T) {
+	pm := NewProviderManager()
+
+	mockProvider1 := new(MockProvider)
+	mockProvider1.On("Network").Return(NetworkSolana)
+	mockProvider2 := new(MockProvider)
+	mockProvider2.On("Network").Return(NetworkSolana)
+
+	for i, p := range []Provider{mockProvider1, mockProvider2} {
+		err := pm.RegisterProvider(p, ProviderConfig{
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 1,
+		})
+		assert.NoError(t, err)
+	}
+
+	var calls []Provider
+	invalidOp := func(p Provider) error {
+		calls = append(calls, p)
+		return NewValidationError("invalid address: %s", "bad")
+	}
+
+	for i := 0; i < 3; i++ {
+		err := pm.executeWithFallback(context.Background(), NetworkSolana, invalidOp)
+		assert.True(t, IsValidationError(err))
+		assert.Equal(t, "invalid address: bad", err.Error())
+	}
+
+	// Validation errors are returned without falling back or tripping the breaker
+	assert.Len(t, calls, 3)
+	for _, p := range calls {
+		assert.Same(t, mockProvider1, p)
+	}
+	status := pm.Status()[NetworkSolana]
+	assert.Equal(t, BreakerClosed, status[0].Breaker)
+	assert.Equal(t, ProviderStatusHealthy, status[0].Status)
+}
+
+func TestProviderManagerStatus(t *testing.T) {
+	pm := NewProviderManager()
+
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+
+	err := pm.RegisterProvider(mockProvider, ProviderConfig{
+		Priority:                1,
+		RequestsPerWindow:       100,
+		WindowDuration:          time.Minute,
+		MaxConsecutiveErrs:      3,
+		BreakerFailureThreshold: 2,
+		BreakerOpenDuration:     time.Minute,
+	})
+	assert.NoError(t, err)
+
+	failingOp := func(p Provider) error { return fmt.Errorf("simulated error") }
+	for i := 0; i < 2; i++ {
+		err = pm.executeWithFallback(context.Background(), NetworkSolana, failingOp)
+		assert.Error(t, err)
+	}
+
+	status := pm.Status()
+	assert.Len(t, status[NetworkSolana], 1)
+
+	snapshot := status[NetworkSolana][0]
+	assert.Equal(t, "solana-1", snapshot.Name)
+	assert.Equal(t, NetworkSolana, snapshot.Network)
+	assert.Equal(t, 1, snapshot.Priority)
+	assert.Equal(t, ProviderStatusDegraded, snapshot.Status)
+	assert.Equal(t, 2, snapshot.ConsecutiveErrors)
+	assert.Equal(t, 2, snapshot.RequestsInWindow)
+	assert.Equal(t, BreakerOpen, snapshot.Breaker)
+	assert.Equal(t, "open", snapshot.Breaker.String())
+}
@@ -0,0 +1,122 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ValidationError reports a request that was rejected before it reached the
+// network, such as an invalid address. It says nothing about the health of the
+// provider that returned it.
+type ValidationError struct {
+	Err error
+}
+
+// NewValidationError creates a ValidationError with a formatted message
+func NewValidationError(format string, args ...interface{}) error {
+	return &ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// IsValidationError reports whether err is or wraps a ValidationError
+func IsValidationError(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr)
+}
+
+// isProviderFault reports whether err should count against the provider that
+// returned it. Validation errors, failed simulations and errors caused by the
+// caller giving up are not the provider's fault and must not trip its breaker.
+func isProviderFault(ctx context.Context, err error) bool {
+	if IsValidationError(err) || IsSimulationError(err) {
+		return false
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	return true
+}
+
+// PreSendError reports a write that failed before anything was broadcast to
+// the network, so it is safe to retry it on another provider.
+type PreSendError struct {
+	Err error
+}
+
+// NewPreSendError creates a PreSendError with a formatted message
+func NewPreSendError(format string, args ...interface{}) error {
+	return &PreSendError{Err: fmt.Errorf(format, args...)}
+}
+
+func (e *PreSendError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PreSendError) Unwrap() error {
+	return e.Err
+}
+
+// IsPreSendError reports whether err is or wraps a PreSendError
+func IsPreSendError(err error) bool {
+	var preSendErr *PreSendError
+	return errors.As(err, &preSendErr)
+}
+
+// AmbiguousWriteError reports a write whose outcome is unknown: the
+// transaction may or may not have been broadcast. It is never retried on
+// another provider, as that could submit the same trade twice.
+type AmbiguousWriteError struct {
+	Err error
+}
+
+func (e *AmbiguousWriteError) Error() string {
+	return fmt.Sprintf("transaction outcome unknown: %v", e.Err)
+}
+
+func (e *AmbiguousWriteError) Unwrap() error {
+	return e.Err
+}
+
+// IsAmbiguousWriteError reports whether err is or wraps an AmbiguousWriteError
+func IsAmbiguousWriteError(err error) bool {
+	var ambiguousErr *AmbiguousWriteError
+	return errors.As(err, &ambiguousErr)
+}
+
+// Reasons a transaction failed in simulation, matched with errors.Is
+var (
+	ErrSlippageExceeded  = errors.New("slippage tolerance exceeded")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAccountFrozen     = errors.New("token account is frozen")
+)
+
+// SimulationError reports a transaction that failed when simulated, so it was
+// never broadcast. Err wraps one of the reasons above when the failure was
+// recognised. It is the transaction's fault, not the provider's.
+type SimulationError struct {
+	Err  error
+	Logs []string // Program logs of the simulation
+}
+
+func (e *SimulationError) Error() string {
+	return fmt.Sprintf("transaction simulation failed: %v", e.Err)
+}
+
+func (e *SimulationError) Unwrap() error {
+	return e.Err
+}
+
+// IsSimulationError reports whether err is or wraps a SimulationError
+func IsSimulationError(err error) bool {
+	var simulationErr *SimulationError
+	return errors.As(err, &simulationErr)
+}
@@ -0,0 +1,135 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultRecoveryThreshold is used when ProviderConfig.RecoveryThreshold is not set
+	defaultRecoveryThreshold = 2
+
+	// maxHealthCheckTimeout caps how long a single probe may take
+	maxHealthCheckTimeout = 10 * time.Second
+)
+
+// observe feeds the outcome of a request or probe into the provider status.
+// Failures step the provider down immediately (Healthy -> Degraded) and mark it
+// Unhealthy after MaxConsecutiveErrs. Successes only step it back up one level
+// after RecoveryThreshold consecutive successes, so a flapping provider does not
+// bounce straight back to Healthy. The caller must hold h.mu.
+func (h *ProviderHealth) observe(success bool, config ProviderConfig) {
+	if !success {
+		h.consecutiveOKs = 0
+		h.consecutiveErrs++
+		if h.consecutiveErrs >= config.MaxConsecutiveErrs {
+			h.status = ProviderStatusUnhealthy
+		} else if h.status == ProviderStatusHealthy {
+			h.status = ProviderStatusDegraded
+		}
+		return
+	}
+
+	h.consecutiveErrs = 0
+	if h.status == ProviderStatusHealthy {
+		return
+	}
+
+	threshold := config.RecoveryThreshold
+	if threshold <= 0 {
+		threshold = defaultRecoveryThreshold
+	}
+
+	h.consecutiveOKs++
+	if h.consecutiveOKs >= threshold {
+		h.consecutiveOKs = 0
+		h.status-- // statuses are ordered from Healthy to Unhealthy
+	}
+}
+
+// observeRequestError feeds a failed live request into the provider status.
+// Unlike observe it never marks the provider Unhealthy. The caller must hold h.mu.
+func (h *ProviderHealth) observeRequestError() {
+	h.consecutiveOKs = 0
+	h.consecutiveErrs++
+	if h.status == ProviderStatusHealthy {
+		h.status = ProviderStatusDegraded
+	}
+}
+
+// startHealthCheck launches the supervised probe loop for a provider entry
+func (pm *ProviderManager) startHealthCheck(entry providerEntry) {
+	if entry.config.HealthCheckPeriod <= 0 || entry.config.HealthCheckAddress == "" {
+		return
+	}
+
+	pm.wg.Add(1)
+	go func() {
+		defer pm.wg.Done()
+		pm.healthCheckLoop(pm.ctx, entry)
+	}()
+}
+
+// healthCheckLoop probes a provider every HealthCheckPeriod until ctx is cancelled
+func (pm *ProviderManager) healthCheckLoop(ctx context.Context, entry providerEntry) {
+	ticker := time.NewTicker(entry.config.HealthCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// A disabled provider is left alone until an operator enables it
+			entry.health.mu.RLock()
+			disabled := entry.health.disabled
+			entry.health.mu.RUnlock()
+			if disabled {
+				continue
+			}
+
+			err := pm.probe(ctx, entry)
+			if ctx.Err() != nil {
+				return
+			}
+
+			entry.health.mu.Lock()
+			entry.health.lastChecked = time.Now()
+			entry.health.observe(err == nil, entry.config)
+			entry.health.mu.Unlock()
+
+			if err == nil {
+				entry.breaker.probeSucceeded()
+			}
+		}
+	}
+}
+
+// probe performs a lightweight call against the provider's canary address
+func (pm *ProviderManager) probe(ctx context.Context, entry providerEntry) (err error) {
+	// A panicking provider must not take the manager down with it
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("health check panicked: %v", r)
+		}
+	}()
+
+	timeout := entry.config.HealthCheckPeriod
+	if timeout > maxHealthCheckTimeout {
+		timeout = maxHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	address := entry.config.HealthCheckAddress
+	if !entry.provider.IsValidAddress(address) {
+		return fmt.Errorf("invalid health check address: %s", address)
+	}
+
+	if _, err := entry.provider.GetBalance(ctx, address); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const testCanaryAddress = "canary"
+
+func providerStatus(pm *ProviderManager, network Network) ProviderStatus {
+	pm.mu.RLock()
+	entry := pm.providers[network][0]
+	pm.mu.RUnlock()
+
+	entry.health.mu.RLock()
+	defer entry.health.mu.RUnlock()
+	return entry.health.status
+}
+
+func TestProviderHealthObserveHysteresis(t *testing.T) {
+	config := ProviderConfig{MaxConsecutiveErrs: 3, RecoveryThreshold: 2}
+	health := &ProviderHealth{status: ProviderStatusHealthy}
+
+	// First failure degrades, third failure marks unhealthy
+	health.observe(false, config)
+	assert.Equal(t, ProviderStatusDegraded, health.status)
+	health.observe(false, config)
+	assert.Equal(t, ProviderStatusDegraded, health.status)
+	health.observe(false, config)
+	assert.Equal(t, ProviderStatusUnhealthy, health.status)
+
+	// A single success is not enough to recover
+	health.observe(true, config)
+	assert.Equal(t, ProviderStatusUnhealthy, health.status)
+	health.observe(true, config)
+	assert.Equal(t, ProviderStatusDegraded, health.status)
+
+	// A failure while recovering resets the success streak
+	health.observe(true, config)
+	health.observe(false, config)
+	health.observe(true, config)
+	assert.Equal(t, ProviderStatusDegraded, health.status)
+	health.observe(true, config)
+	assert.Equal(t, ProviderStatusHealthy, health.status)
+}
+
+func TestProviderManagerHealthCheckRevivesProvider(t *testing.T) {
+	pm := NewProviderManager()
+	defer pm.Close()
+
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	mockProvider.On("IsValidAddress", testCanaryAddress).Return(true)
+	mockProvider.On("GetBalance", mock.Anything, testCanaryAddress).Return(Amount{}, nil)
+
+	err := pm.RegisterProvider(mockProvider, ProviderConfig{
+		Priority:           1,
+		RequestsPerWindow:  100,
+		WindowDuration:     time.Minute,
+		HealthCheckPeriod:  10 * time.Millisecond,
+		HealthCheckAddress: testCanaryAddress,
+		MaxConsecutiveErrs: 1,
+		RecoveryThreshold:  1,
+	})
+	assert.NoError(t, err)
+
+	// Trip the provider with a failing request
+	err = pm.executeWithFallback(context.Background(), NetworkSolana, func(p Provider) error {
+		return fmt.Errorf("simulated error")
+	})
+	assert.Error(t, err)
+
+	// The health check should bring it back to healthy
+	assert.Eventually(t, func() bool {
+		return providerStatus(pm, NetworkSolana) == ProviderStatusHealthy
+	}, time.Second, 10*time.Millisecond)
+
+	provider, err := pm.getHealthyProvider(context.Background(), NetworkSolana)
+	assert.NoError(t, err)
+	assert.Same(t, mockProvider, provider)
+}
+
+func TestProviderManagerHealthCheckMarksUnhealthy(t *testing.T) {
+	pm := NewProviderManager()
+	defer pm.Close()
+
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	mockProvider.On("IsValidAddress", testCanaryAddress).Return(true)
+	mockProvider.On("GetBalance", mock.Anything, testCanaryAddress).Return(Amount{}, fmt.Errorf("rpc down"))
+
+	err := pm.RegisterProvider(mockProvider, ProviderConfig{
+		Priority:           1,
+		RequestsPerWindow:  100,
+		WindowDuration:     time.Minute,
+		HealthCheckPeriod:  10 * time.Millisecond,
+		HealthCheckAddress: testCanaryAddress,
+		MaxConsecutiveErrs: 2,
+	})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return providerStatus(pm, NetworkSolana) == ProviderStatusUnhealthy
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestProviderManagerClose(t *testing.T) {
+	pm := NewProviderManager()
+
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	mockProvider.On("IsValidAddress", testCanaryAddress).Return(true)
+	mockProvider.On("GetBalance", mock.Anything, testCanaryAddress).Return(Amount{}, nil)
+
+	err := pm.RegisterProvider(mockProvider, ProviderConfig{
+		Priority:           1,
+		RequestsPerWindow:  100,
+		WindowDuration:     time.Minute,
+		HealthCheckPeriod:  5 * time.Millisecond,
+		HealthCheckAddress: testCanaryAddress,
+		MaxConsecutiveErrs: 3,
+	})
+	assert.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, pm.Close())
+
+	// No probes should run once Close has returned
+	calls := len(mockProvider.Calls)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, calls, len(mockProvider.Calls))
+}
+
+func TestProviderManagerHealthCheckSkipsDisabledProvider(t *testing.T) {
+	pm := NewProviderManager()
+	defer pm.Close()
+
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	mockProvider.On("IsValidAddress", testCanaryAddress).Return(true)
+	mockProvider.On("GetBalance", mock.Anything, testCanaryAddress).Return(Amount{}, nil)
+
+	err := pm.RegisterProvider(mockProvider, ProviderConfig{
+		Name:               "primary",
+		Priority:           1,
+		RequestsPerWindow:  100,
+		WindowDuration:     time.Minute,
+		HealthCheckPeriod:  10 * time.Millisecond,
+		HealthCheckAddress: testCanaryAddress,
+		MaxConsecutiveErrs: 1,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, pm.DisableProvider(NetworkSolana, "primary"))
+
+	time.Sleep(50 * time.Millisecond)
+	mockProvider.AssertNotCalled(t, "GetBalance", mock.Anything, testCanaryAddress)
+
+	// Probes resume once it is enabled
+	assert.NoError(t, pm.EnableProvider(NetworkSolana, "primary"))
+	assert.Eventually(t, func() bool {
+		return !pm.Status()[NetworkSolana][0].LastChecked.IsZero()
+	}, time.Second, 5*time.Millisecond)
+}
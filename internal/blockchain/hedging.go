@@ -0,0 +1,221 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultHedgePercentile is the latency percentile used for adaptive delays
+	defaultHedgePercentile = 0.95
+
+	// defaultHedgeDelay is used by adaptive hedging until enough samples are recorded
+	defaultHedgeDelay = 200 * time.Millisecond
+
+	// defaultHedgeAttempts is the default number of providers asked in parallel
+	defaultHedgeAttempts = 2
+
+	// minAdaptiveSamples is the number of samples needed before the delay adapts
+	minAdaptiveSamples = 10
+)
+
+// errNoHedgeCandidates is returned when no provider could take the first attempt
+var errNoHedgeCandidates = errors.New("no provider available for hedged request")
+
+// HedgeConfig configures hedged reads for a network. A read that hasn't
+// answered within the hedge delay is also sent to the next available
+// provider; the first successful answer wins.
+type HedgeConfig struct {
+	Delay       time.Duration // Time to wait for a provider before also asking the next one
+	Adaptive    bool          // Derive the delay from the latency of the provider being hedged
+	Percentile  float64       // Latency percentile used as the adaptive delay (default 0.95)
+	MinDelay    time.Duration // Lower bound for the adaptive delay
+	MaxDelay    time.Duration // Upper bound for the adaptive delay
+	MaxAttempts int           // Maximum number of providers asked in parallel (default 2)
+}
+
+// SetHedging enables hedged reads for a network. A config with neither a
+// Delay nor Adaptive set disables hedging.
+func (pm *ProviderManager) SetHedging(network Network, config HedgeConfig) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if config.Delay <= 0 && !config.Adaptive {
+		delete(pm.hedging, network)
+		return
+	}
+	pm.hedging[network] = config
+}
+
+// hedgeConfig returns the hedging configuration of a network, if any
+func (pm *ProviderManager) hedgeConfig(network Network) (HedgeConfig, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	config, ok := pm.hedging[network]
+	return config, ok
+}
+
+// delayFor returns how long to wait on entry before hedging
+func (c HedgeConfig) delayFor(entry providerEntry) time.Duration {
+	delay := c.Delay
+	if c.Adaptive {
+		if delay <= 0 {
+			delay = defaultHedgeDelay
+		}
+
+		percentile := c.Percentile
+		if percentile <= 0 || percentile > 1 {
+			percentile = defaultHedgePercentile
+		}
+
+		if entry.latency.count() >= minAdaptiveSamples {
+			if d, ok := entry.latency.percentile(percentile); ok {
+				delay = d
+			}
+		}
+	}
+
+	if c.MinDelay > 0 && delay < c.MinDelay {
+		delay = c.MinDelay
+	}
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	return delay
+}
+
+// maxAttempts returns the number of providers that may be asked in parallel
+func (c HedgeConfig) maxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return defaultHedgeAttempts
+	}
+	return c.MaxAttempts
+}
+
+// hedgeResult is the outcome of a single hedged attempt
+type hedgeResult[T any] struct {
+	value T
+	err   error
+}
+
+// executeHedged runs a read with hedging when the network has a hedge
+// configuration and more than one provider; otherwise, or when no provider can
+// take the request right now, it behaves like executeWithFallback.
+func executeHedged[T any](ctx context.Context, pm *ProviderManager, network Network, op func(context.Context, Provider) (T, error)) (T, error) {
+	config, ok := pm.hedgeConfig(network)
+	providers := pm.orderedProviders(network)
+
+	if ok && len(providers) > 1 {
+		result, err := hedge(ctx, pm, providers, config, op)
+		if !errors.Is(err, errNoHedgeCandidates) {
+			return result, err
+		}
+	}
+
+	var result T
+	err := pm.executeWithFallback(ctx, network, func(provider Provider) error {
+		var err error
+		result, err = op(ctx, provider)
+		return err
+	})
+	return result, err
+}
+
+// hedge sends op to the providers in order, starting the next attempt when the
+// hedge delay elapses or the current attempt fails. The first success cancels
+// the remaining attempts.
+func hedge[T any](ctx context.Context, pm *ProviderManager, providers []providerEntry, config HedgeConfig, op func(context.Context, Provider) (T, error)) (T, error) {
+	var zero T
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult[T], len(providers))
+	next, inFlight := 0, 0
+
+	// launch starts an attempt on the next available provider. Each attempt
+	// settles its own health and breaker bookkeeping, so abandoned attempts
+	// never leak a half-open trial.
+	launch := func() (providerEntry, bool) {
+		for next < len(providers) {
+			entry := providers[next]
+			next++
+
+			if ok, _ := pm.reserve(entry); !ok {
+				continue
+			}
+
+			inFlight++
+			go func() {
+				start := time.Now()
+				value, err := op(ctx, entry.provider)
+				entry.inFlight.Add(-1)
+				switch {
+				case err == nil:
+					pm.recordSuccess(entry, time.Since(start))
+				case !isProviderFault(ctx, err):
+					entry.breaker.release()
+				default:
+					pm.recordError(entry)
+				}
+				results <- hedgeResult[T]{value: value, err: err}
+			}()
+			return entry, true
+		}
+		return providerEntry{}, false
+	}
+
+	first, ok := launch()
+	if !ok {
+		return zero, errNoHedgeCandidates
+	}
+
+	timer := time.NewTimer(config.delayFor(first))
+	defer timer.Stop()
+
+	var lastErr error
+	for inFlight > 0 {
+		select {
+		case <-ctx.Done():
+			return zero, fmt.Errorf("hedged request cancelled: %w", ctx.Err())
+
+		case result := <-results:
+			inFlight--
+			if result.err == nil {
+				return result.value, nil
+			}
+			if IsValidationError(result.err) {
+				return zero, result.err
+			}
+			lastErr = result.err
+
+			// Fall back to the next provider straight away
+			if entry, ok := launch(); ok {
+				resetTimer(timer, config.delayFor(entry))
+			}
+
+		case <-timer.C:
+			if inFlight < config.maxAttempts() {
+				if entry, ok := launch(); ok {
+					timer.Reset(config.delayFor(entry))
+				}
+			}
+		}
+	}
+
+	return zero, fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+// resetTimer stops t, drains a pending tick and restarts it with d
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,149 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newHedgedService(t *testing.T, config HedgeConfig) (Service, *MockProvider, *MockProvider) {
+	service := NewService()
+
+	mockProvider1 := new(MockProvider)
+	mockProvider1.On("Network").Return(NetworkSolana)
+	mockProvider2 := new(MockProvider)
+	mockProvider2.On("Network").Return(NetworkSolana)
+
+	for i, p := range []Provider{mockProvider1, mockProvider2} {
+		err := service.RegisterProviderWithConfig(p, ProviderConfig{
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 3,
+		})
+		assert.NoError(t, err)
+	}
+
+	service.SetHedging(NetworkSolana, config)
+	return service, mockProvider1, mockProvider2
+}
+
+func TestHedgedReadTakesFirstAnswer(t *testing.T) {
+	service, slowProvider, fastProvider := newHedgedService(t, HedgeConfig{Delay: 20 * time.Millisecond})
+
+	var slowCancelled atomic.Bool
+	slowProvider.On("GetBalance", mock.Anything, "test-address").Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+		select {
+		case <-ctx.Done():
+			slowCancelled.Store(true)
+		case <-time.After(time.Second):
+		}
+	}).Return(Amount{Value: big.NewInt(1)}, nil)
+	fastProvider.On("GetBalance", mock.Anything, "test-address").Return(Amount{Value: big.NewInt(2), Decimals: 9}, nil)
+
+	start := time.Now()
+	balance, err := service.GetBalance(context.Background(), NetworkSolana, "test-address")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), balance.Value.Int64())
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "Hedged request should not wait for the slow provider")
+
+	// The losing attempt is cancelled
+	assert.Eventually(t, slowCancelled.Load, time.Second, 5*time.Millisecond)
+}
+
+func TestHedgedReadDoesNotHedgeFastProvider(t *testing.T) {
+	service, primary, secondary := newHedgedService(t, HedgeConfig{Delay: 200 * time.Millisecond})
+
+	expectedTx := &Transaction{ID: "test-tx"}
+	primary.On("GetTransaction", mock.Anything, "test-tx").Return(expectedTx, nil)
+
+	tx, err := service.GetTransaction(context.Background(), NetworkSolana, "test-tx")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTx, tx)
+	secondary.AssertNotCalled(t, "GetTransaction", mock.Anything, mock.Anything)
+}
+
+func TestHedgedReadFallsBackOnError(t *testing.T) {
+	service, primary, secondary := newHedgedService(t, HedgeConfig{Delay: time.Second})
+
+	expected := []MemeCoin{{Symbol: "BONK"}}
+	req := TopMemeCoinsRequest{Limit: 10}
+	primary.On("GetTopMemeCoins", mock.Anything, req).Return(nil, fmt.Errorf("rpc down"))
+	secondary.On("GetTopMemeCoins", mock.Anything, req).Return(expected, nil)
+
+	start := time.Now()
+	coins, err := service.GetTopMemeCoins(context.Background(), NetworkSolana, req)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, coins)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "Failures should fall back without waiting for the hedge delay")
+}
+
+func TestHedgedReadAllProvidersFail(t *testing.T) {
+	service, primary, secondary := newHedgedService(t, HedgeConfig{Delay: 10 * time.Millisecond})
+
+	primary.On("GetBalance", mock.Anything, "test-address").Return(Amount{}, fmt.Errorf("rpc down"))
+	secondary.On("GetBalance", mock.Anything, "test-address").Return(Amount{}, fmt.Errorf("rpc down too"))
+
+	_, err := service.GetBalance(context.Background(), NetworkSolana, "test-address")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "all providers failed")
+}
+
+func TestHedgeConfigAdaptiveDelay(t *testing.T) {
+	config := HedgeConfig{
+		Adaptive:   true,
+		Percentile: 0.9,
+		MinDelay:   5 * time.Millisecond,
+		MaxDelay:   time.Second,
+	}
+	entry := providerEntry{latency: newLatencyStats()}
+
+	// Not enough samples yet
+	assert.Equal(t, defaultHedgeDelay, config.delayFor(entry))
+
+	for i := 1; i <= 10; i++ {
+		entry.latency.record(time.Duration(i) * 10 * time.Millisecond)
+	}
+	assert.Equal(t, 90*time.Millisecond, config.delayFor(entry))
+
+	// Delays are clamped to the configured bounds
+	config.MaxDelay = 50 * time.Millisecond
+	assert.Equal(t, 50*time.Millisecond, config.delayFor(entry))
+}
+
+func TestLatencyStats(t *testing.T) {
+	stats := newLatencyStats()
+
+	_, ok := stats.percentile(0.5)
+	assert.False(t, ok)
+
+	for i := 1; i <= 100; i++ {
+		stats.record(time.Duration(i) * time.Millisecond)
+	}
+
+	p50, ok := stats.percentile(0.5)
+	assert.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, p50)
+
+	p99, _ := stats.percentile(0.99)
+	assert.Equal(t, 99*time.Millisecond, p99)
+
+	avg, ok := stats.average()
+	assert.True(t, ok)
+	assert.Greater(t, avg, 50*time.Millisecond, "EWMA should favour recent samples")
+
+	// Only the most recent samples are kept
+	for i := 0; i < latencyWindow; i++ {
+		stats.record(time.Millisecond)
+	}
+	assert.Equal(t, latencyWindow, stats.count())
+	p99, _ = stats.percentile(0.99)
+	assert.Equal(t, time.Millisecond, p99)
+}
@@ -0,0 +1,85 @@
+package blockchain
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindow is the number of recent samples kept per provider
+	latencyWindow = 128
+
+	// latencyEWMAAlpha weights the most recent sample in the moving average
+	latencyEWMAAlpha = 0.2
+)
+
+// latencyStats records the latency of recent successful requests to a provider
+type latencyStats struct {
+	samples [latencyWindow]time.Duration
+	next    int
+	n       int
+	ewma    time.Duration
+	mu      sync.Mutex
+}
+
+func newLatencyStats() *latencyStats {
+	return &latencyStats{}
+}
+
+// record adds a latency sample
+func (s *latencyStats) record(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % latencyWindow
+	if s.n < latencyWindow {
+		s.n++
+	}
+
+	if s.n == 1 {
+		s.ewma = d
+	} else {
+		s.ewma = time.Duration(latencyEWMAAlpha*float64(d) + (1-latencyEWMAAlpha)*float64(s.ewma))
+	}
+}
+
+// percentile returns the p-th percentile (0 < p <= 1) of the recent samples
+func (s *latencyStats) percentile(p float64) (time.Duration, bool) {
+	s.mu.Lock()
+	sorted := make([]time.Duration, s.n)
+	copy(sorted, s.samples[:s.n])
+	s.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx], true
+}
+
+// average returns the exponentially weighted moving average latency
+func (s *latencyStats) average() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ewma, s.n > 0
+}
+
+// count returns the number of samples currently held
+func (s *latencyStats) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.n
+}
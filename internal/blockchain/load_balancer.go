@@ -0,0 +1,150 @@
+package blockchain
+
+import (
+	"sort"
+	"sync"
+)
+
+// BalancingStrategy decides the order in which a network's providers are tried
+type BalancingStrategy int
+
+const (
+	// StrategyPriority always tries providers in priority order
+	StrategyPriority BalancingStrategy = iota
+	// StrategyWeightedRoundRobin spreads requests in proportion to provider weights
+	StrategyWeightedRoundRobin
+	// StrategyLeastInFlight prefers the provider with the fewest requests in flight per unit of weight
+	StrategyLeastInFlight
+	// StrategyLatency prefers the provider with the lowest moving-average latency per unit of weight
+	StrategyLatency
+)
+
+// defaultProviderWeight is used when ProviderConfig.Weight is not set
+const defaultProviderWeight = 1
+
+func (s BalancingStrategy) String() string {
+	switch s {
+	case StrategyPriority:
+		return "priority"
+	case StrategyWeightedRoundRobin:
+		return "weighted-round-robin"
+	case StrategyLeastInFlight:
+		return "least-in-flight"
+	case StrategyLatency:
+		return "latency"
+	default:
+		return "unknown"
+	}
+}
+
+// weight returns the configured weight of an entry
+func (e providerEntry) weight() int {
+	if e.config.Weight <= 0 {
+		return defaultProviderWeight
+	}
+	return e.config.Weight
+}
+
+// balancer holds the per-network load balancing state
+type balancer struct {
+	strategy BalancingStrategy
+	current  map[*ProviderHealth]int // smooth weighted round-robin counters, keyed by each entry's unique health pointer
+	mu       sync.Mutex
+}
+
+func newBalancer(strategy BalancingStrategy) *balancer {
+	return &balancer{
+		strategy: strategy,
+		current:  make(map[*ProviderHealth]int),
+	}
+}
+
+// SetStrategy selects the load balancing strategy for a network
+func (pm *ProviderManager) SetStrategy(network Network, strategy BalancingStrategy) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.balancers[network] = newBalancer(strategy)
+}
+
+// orderedProviders returns the providers of a network in the order they should
+// be tried. Weighted round-robin moves its pick first and leaves the rest in
+// priority order; least-in-flight and latency sort every provider, ties
+// keeping priority order.
+func (pm *ProviderManager) orderedProviders(network Network) []providerEntry {
+	pm.mu.RLock()
+	providers := pm.providers[network]
+	b := pm.balancers[network]
+	pm.mu.RUnlock()
+
+	if b == nil || b.strategy == StrategyPriority || len(providers) < 2 {
+		return providers
+	}
+
+	ordered := make([]providerEntry, len(providers))
+	copy(ordered, providers)
+
+	switch b.strategy {
+	case StrategyWeightedRoundRobin:
+		if pick := b.nextWeighted(pm, ordered); pick > 0 {
+			chosen := ordered[pick]
+			copy(ordered[1:pick+1], ordered[:pick])
+			ordered[0] = chosen
+		}
+	case StrategyLeastInFlight:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return float64(ordered[i].inFlight.Load())/float64(ordered[i].weight()) <
+				float64(ordered[j].inFlight.Load())/float64(ordered[j].weight())
+		})
+	case StrategyLatency:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			scoreI, measuredI := latencyScore(ordered[i])
+			scoreJ, measuredJ := latencyScore(ordered[j])
+			if measuredI != measuredJ {
+				return measuredI
+			}
+			return scoreI < scoreJ
+		})
+	}
+
+	return ordered
+}
+
+// nextWeighted picks the next provider using smooth weighted round-robin over
+// the providers that can currently take a request, and returns its index.
+func (b *balancer) nextWeighted(pm *ProviderManager, providers []providerEntry) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	best, total := -1, 0
+	for i, entry := range providers {
+		if !pm.isProviderAvailable(entry) || !entry.breaker.canAttempt() {
+			continue
+		}
+
+		w := entry.weight()
+		total += w
+		b.current[entry.health] += w
+		if best < 0 || b.current[entry.health] > b.current[providers[best].health] {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return 0
+	}
+	b.current[providers[best].health] -= total
+	return best
+}
+
+// latencyScore ranks a provider by its average latency divided by its weight,
+// and reports whether it has samples. Samples are only taken from successful
+// requests, so providers without any rank after the measured ones: one that
+// always fails would otherwise stay first.
+func latencyScore(entry providerEntry) (float64, bool) {
+	avg, ok := entry.latency.average()
+	if !ok {
+		return 0, false
+	}
+	return float64(avg) / float64(entry.weight()), true
+}
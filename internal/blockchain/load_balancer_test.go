@@ -0,0 +1,141 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func registerWeightedProviders(t *testing.T, pm *ProviderManager, weights ...int) []*MockProvider {
+	var providers []*MockProvider
+	for i, weight := range weights {
+		mockProvider := new(MockProvider)
+		mockProvider.On("Network").Return(NetworkSolana)
+
+		err := pm.RegisterProvider(mockProvider, ProviderConfig{
+			Priority:           i + 1,
+			Weight:             weight,
+			RequestsPerWindow:  1000,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 3,
+		})
+		assert.NoError(t, err)
+		providers = append(providers, mockProvider)
+	}
+	return providers
+}
+
+func countCalls(t *testing.T, pm *ProviderManager, requests int) map[Provider]int {
+	counts := make(map[Provider]int)
+	for i := 0; i < requests; i++ {
+		err := pm.executeWithFallback(context.Background(), NetworkSolana, func(p Provider) error {
+			counts[p]++
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+	return counts
+}
+
+func TestStrategyPriorityIsDefault(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 5)
+
+	counts := countCalls(t, pm, 10)
+	assert.Equal(t, 10, counts[providers[0]], "Highest priority provider should take all traffic")
+	assert.Equal(t, 0, counts[providers[1]])
+}
+
+func TestStrategyWeightedRoundRobin(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 3)
+	pm.SetStrategy(NetworkSolana, StrategyWeightedRoundRobin)
+
+	counts := countCalls(t, pm, 40)
+	assert.Equal(t, 10, counts[providers[0]])
+	assert.Equal(t, 30, counts[providers[1]])
+}
+
+func TestStrategyWeightedRoundRobinSkipsOpenBreaker(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 1)
+	pm.SetStrategy(NetworkSolana, StrategyWeightedRoundRobin)
+
+	// Trip the second provider's breaker
+	pm.mu.RLock()
+	entry := pm.providers[NetworkSolana][1]
+	pm.mu.RUnlock()
+	for i := 0; i < 3; i++ {
+		entry.breaker.onFailure()
+	}
+
+	counts := countCalls(t, pm, 10)
+	assert.Equal(t, 10, counts[providers[0]])
+	assert.Equal(t, 0, counts[providers[1]])
+}
+
+func TestStrategyLeastInFlight(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 1)
+	pm.SetStrategy(NetworkSolana, StrategyLeastInFlight)
+
+	// Hold a request open on the first provider
+	pm.mu.RLock()
+	pm.providers[NetworkSolana][0].inFlight.Add(1)
+	pm.mu.RUnlock()
+
+	counts := countCalls(t, pm, 5)
+	assert.Equal(t, 5, counts[providers[1]], "Idle provider should be preferred")
+}
+
+func TestStrategyLeastInFlightRespectsWeight(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 4)
+	pm.SetStrategy(NetworkSolana, StrategyLeastInFlight)
+
+	pm.mu.RLock()
+	pm.providers[NetworkSolana][0].inFlight.Add(1)
+	pm.providers[NetworkSolana][1].inFlight.Add(2)
+	pm.mu.RUnlock()
+
+	// 2 in flight at weight 4 is lighter than 1 in flight at weight 1
+	counts := countCalls(t, pm, 1)
+	assert.Equal(t, 1, counts[providers[1]])
+}
+
+func TestStrategyLatency(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 1, 1)
+	pm.SetStrategy(NetworkSolana, StrategyLatency)
+
+	pm.mu.RLock()
+	entries := pm.providers[NetworkSolana]
+	pm.mu.RUnlock()
+	entries[0].latency.record(300 * time.Millisecond)
+	entries[1].latency.record(50 * time.Millisecond)
+	entries[2].latency.record(100 * time.Millisecond)
+
+	ordered := pm.orderedProviders(NetworkSolana)
+	assert.Same(t, providers[1], ordered[0].provider)
+	assert.Same(t, providers[2], ordered[1].provider)
+	assert.Same(t, providers[0], ordered[2].provider)
+}
+
+func TestStrategyLatencyRanksUnmeasuredProvidersLast(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerWeightedProviders(t, pm, 1, 1, 1)
+	pm.SetStrategy(NetworkSolana, StrategyLatency)
+
+	pm.mu.RLock()
+	pm.providers[NetworkSolana][2].latency.record(500 * time.Millisecond)
+	pm.mu.RUnlock()
+
+	// A provider that never succeeds has no samples, so it can't stay first;
+	// unmeasured providers keep their priority order
+	ordered := pm.orderedProviders(NetworkSolana)
+	assert.Same(t, providers[2], ordered[0].provider)
+	assert.Same(t, providers[0], ordered[1].provider)
+	assert.Same(t, providers[1], ordered[2].provider)
+}
@@ -0,0 +1,84 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrProviderNotFound is returned when no provider with the given name is registered
+var ErrProviderNotFound = errors.New("provider not found")
+
+// DrainProvider stops sending new requests to a provider while letting the
+// requests already in flight finish. Its snapshot reports Drained once none
+// are left, and health checks keep running so it is ready to be re-enabled.
+func (pm *ProviderManager) DrainProvider(network Network, name string) error {
+	return pm.updateHealth(network, name, func(h *ProviderHealth) {
+		h.draining = true
+	})
+}
+
+// DisableProvider takes a provider out of rotation and pauses its health
+// checks until it is re-enabled, e.g. when its endpoint is known to be down
+func (pm *ProviderManager) DisableProvider(network Network, name string) error {
+	return pm.updateHealth(network, name, func(h *ProviderHealth) {
+		h.disabled = true
+	})
+}
+
+// EnableProvider puts a drained or disabled provider back into rotation
+func (pm *ProviderManager) EnableProvider(network Network, name string) error {
+	return pm.updateHealth(network, name, func(h *ProviderHealth) {
+		h.disabled = false
+		h.draining = false
+	})
+}
+
+// SetProviderPriority changes the priority of a provider at runtime
+func (pm *ProviderManager) SetProviderPriority(network Network, name string, priority int) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	existing := pm.providers[network]
+	idx := findProvider(existing, name)
+	if idx < 0 {
+		return fmt.Errorf("%w: %s/%s", ErrProviderNotFound, network, name)
+	}
+
+	// Copy on write so that requests iterating the current slice are unaffected
+	providers := make([]providerEntry, len(existing))
+	copy(providers, existing)
+	providers[idx].config.Priority = priority
+	pm.providers[network] = providers
+	pm.sortProviders(network)
+
+	return nil
+}
+
+// updateHealth applies fn to the health of the named provider
+func (pm *ProviderManager) updateHealth(network Network, name string, fn func(*ProviderHealth)) error {
+	pm.mu.RLock()
+	providers := pm.providers[network]
+	pm.mu.RUnlock()
+
+	idx := findProvider(providers, name)
+	if idx < 0 {
+		return fmt.Errorf("%w: %s/%s", ErrProviderNotFound, network, name)
+	}
+
+	health := providers[idx].health
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	fn(health)
+	return nil
+}
+
+// findProvider returns the index of the named provider, or -1
+func findProvider(providers []providerEntry, name string) int {
+	for i, entry := range providers {
+		if entry.config.Name == name {
+			return i
+		}
+	}
+	return -1
+}
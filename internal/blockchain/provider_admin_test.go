@@ -0,0 +1,136 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func registerNamedProviders(t *testing.T, pm *ProviderManager, names ...string) []*MockProvider {
+	var providers []*MockProvider
+	for i, name := range names {
+		mockProvider := new(MockProvider)
+		mockProvider.On("Network").Return(NetworkSolana)
+
+		err := pm.RegisterProvider(mockProvider, ProviderConfig{
+			Name:               name,
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 3,
+		})
+		assert.NoError(t, err)
+		providers = append(providers, mockProvider)
+	}
+	return providers
+}
+
+func firstCalled(t *testing.T, pm *ProviderManager) Provider {
+	var called Provider
+	err := pm.executeWithFallback(context.Background(), NetworkSolana, func(p Provider) error {
+		called = p
+		return nil
+	})
+	assert.NoError(t, err)
+	return called
+}
+
+func TestProviderManagerRejectsDuplicateNames(t *testing.T) {
+	pm := NewProviderManager()
+	registerNamedProviders(t, pm, "primary")
+
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	err := pm.RegisterProvider(mockProvider, ProviderConfig{Name: "primary"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already registered")
+}
+
+func TestProviderManagerDisableAndEnable(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerNamedProviders(t, pm, "primary", "backup")
+
+	assert.NoError(t, pm.DisableProvider(NetworkSolana, "primary"))
+	assert.Same(t, providers[1], firstCalled(t, pm))
+	assert.True(t, pm.Status()[NetworkSolana][0].Disabled)
+
+	assert.NoError(t, pm.EnableProvider(NetworkSolana, "primary"))
+	assert.Same(t, providers[0], firstCalled(t, pm))
+	assert.False(t, pm.Status()[NetworkSolana][0].Disabled)
+}
+
+func TestProviderManagerDrain(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerNamedProviders(t, pm, "primary", "backup")
+
+	// Hold a request on the primary while it drains
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- pm.executeWithFallback(context.Background(), NetworkSolana, func(p Provider) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	assert.NoError(t, pm.DrainProvider(NetworkSolana, "primary"))
+	assert.Same(t, providers[1], firstCalled(t, pm))
+
+	snapshot := pm.Status()[NetworkSolana][0]
+	assert.True(t, snapshot.Draining)
+	assert.Equal(t, int64(1), snapshot.InFlight)
+	assert.False(t, snapshot.Drained, "a request is still in flight")
+
+	// The request in flight finishes, then the provider is drained
+	close(release)
+	assert.NoError(t, <-done)
+	snapshot = pm.Status()[NetworkSolana][0]
+	assert.Equal(t, int64(0), snapshot.InFlight)
+	assert.True(t, snapshot.Drained)
+
+	assert.NoError(t, pm.EnableProvider(NetworkSolana, "primary"))
+	assert.False(t, pm.Status()[NetworkSolana][0].Drained)
+}
+
+func TestProviderManagerSetPriority(t *testing.T) {
+	pm := NewProviderManager()
+	providers := registerNamedProviders(t, pm, "primary", "backup")
+
+	assert.NoError(t, pm.SetProviderPriority(NetworkSolana, "backup", 0))
+	assert.Same(t, providers[1], firstCalled(t, pm))
+
+	status := pm.Status()[NetworkSolana]
+	assert.Equal(t, "backup", status[0].Name)
+	assert.Equal(t, 0, status[0].Priority)
+}
+
+func TestProviderManagerAdminUnknownProvider(t *testing.T) {
+	pm := NewProviderManager()
+	registerNamedProviders(t, pm, "primary")
+
+	assert.ErrorIs(t, pm.DrainProvider(NetworkSolana, "missing"), ErrProviderNotFound)
+	assert.ErrorIs(t, pm.DisableProvider(NetworkSolana, "missing"), ErrProviderNotFound)
+	assert.ErrorIs(t, pm.EnableProvider(NetworkSolana, "missing"), ErrProviderNotFound)
+	assert.ErrorIs(t, pm.SetProviderPriority(NetworkSolana, "missing", 1), ErrProviderNotFound)
+}
+
+func TestProviderManagerStatusLatency(t *testing.T) {
+	pm := NewProviderManager()
+	registerNamedProviders(t, pm, "primary")
+
+	pm.mu.RLock()
+	entry := pm.providers[NetworkSolana][0]
+	pm.mu.RUnlock()
+	for i := 1; i <= 100; i++ {
+		entry.latency.record(time.Duration(i) * time.Millisecond)
+	}
+
+	snapshot := pm.Status()[NetworkSolana][0]
+	assert.Equal(t, 50*time.Millisecond, snapshot.LatencyP50)
+	assert.Equal(t, 95*time.Millisecond, snapshot.LatencyP95)
+	assert.Equal(t, 99*time.Millisecond, snapshot.LatencyP99)
+}
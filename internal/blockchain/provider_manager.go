@@ -2,8 +2,12 @@ package blockchain
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,51 +20,150 @@ const (
 	ProviderStatusUnhealthy
 )
 
-// ProviderHealth tracks the health and rate limit status of a provider
+func (s ProviderStatus) String() string {
+	switch s {
+	case ProviderStatusHealthy:
+		return "healthy"
+	case ProviderStatusDegraded:
+		return "degraded"
+	case ProviderStatusUnhealthy:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+// operationKind classifies an operation for fallback purposes
+type operationKind int
+
+const (
+	// operationRead has no side effects and can be retried on any provider
+	operationRead operationKind = iota
+	// operationWrite may broadcast a transaction and is only retried on
+	// another provider when it failed before anything was sent
+	operationWrite
+)
+
+// ProviderHealth tracks the health status of a provider
 type ProviderHealth struct {
-	status           ProviderStatus
-	lastChecked      time.Time
-	consecutiveErrs  int
-	rateLimitExpiry  time.Time
-	requestsInWindow int
-	mu               sync.RWMutex
+	status          ProviderStatus
+	lastChecked     time.Time
+	consecutiveErrs int
+	consecutiveOKs  int
+	draining        bool // set by an operator; no new requests until re-enabled, requests in flight finish
+	disabled        bool // set by an operator; out of rotation and not health checked until re-enabled
+	mu              sync.RWMutex
 }
 
 // ProviderConfig holds configuration for a provider
 type ProviderConfig struct {
+	Name               string        // Identifies the provider in status snapshots; defaults to "<network>-<n>"
 	Priority           int           // Lower number means higher priority
+	Weight             int           // Relative share of traffic for weighted strategies (default 1)
 	RequestsPerWindow  int           // Number of requests allowed in the time window
 	WindowDuration     time.Duration // Duration of the rate limiting window
 	HealthCheckPeriod  time.Duration // How often to check provider health
+	HealthCheckAddress string        // Canary address probed by the health check; empty disables the loop
 	MaxConsecutiveErrs int           // Number of consecutive errors before marking as unhealthy
+	RecoveryThreshold  int           // Number of consecutive successes before stepping up one status level
+
+	BreakerFailureThreshold int           // Consecutive request failures before the breaker opens; defaults to MaxConsecutiveErrs
+	BreakerOpenDuration     time.Duration // How long the breaker stays open before allowing trial requests
+	BreakerHalfOpenCalls    int           // Number of trial requests that must succeed to close the breaker
+}
+
+// ProviderSnapshot is a point-in-time view of a registered provider
+type ProviderSnapshot struct {
+	Name              string
+	Network           Network
+	Priority          int
+	Weight            int
+	Status            ProviderStatus
+	Draining          bool
+	Drained           bool // Draining with no requests left in flight, so it can be taken down
+	Disabled          bool
+	ConsecutiveErrors int
+	RequestsInWindow  int
+	InFlight          int64
+	LatencyP50        time.Duration
+	LatencyP95        time.Duration
+	LatencyP99        time.Duration
+	LastChecked       time.Time
+	Breaker           BreakerState
 }
 
 // providerEntry represents a provider and its associated metadata
 type providerEntry struct {
 	provider Provider
 	health   *ProviderHealth
+	limiter  *rateLimiter
+	breaker  *circuitBreaker
+	latency  *latencyStats
+	inFlight *atomic.Int64
 	config   ProviderConfig
 }
 
 // ProviderManager manages multiple providers per network with fallback and load balancing
 type ProviderManager struct {
 	providers map[Network][]providerEntry
+	hedging   map[Network]HedgeConfig
+	balancers map[Network]*balancer
 	mu        sync.RWMutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 // NewProviderManager creates a new provider manager
 func NewProviderManager() *ProviderManager {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ProviderManager{
 		providers: make(map[Network][]providerEntry),
+		hedging:   make(map[Network]HedgeConfig),
+		balancers: make(map[Network]*balancer),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 }
 
+// Close stops the background health checks, waits for them to exit and closes
+// the providers that hold connections
+func (pm *ProviderManager) Close() error {
+	pm.cancel()
+	pm.wg.Wait()
+
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var errs []error
+	for _, providers := range pm.providers {
+		for _, entry := range providers {
+			closer, ok := entry.provider.(io.Closer)
+			if !ok {
+				continue
+			}
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close provider %s: %w", entry.config.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // RegisterProvider registers a new provider with its configuration
 func (pm *ProviderManager) RegisterProvider(provider Provider, config ProviderConfig) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
 	network := provider.Network()
+	if config.Name == "" {
+		config.Name = fmt.Sprintf("%s-%d", network, len(pm.providers[network])+1)
+	}
+	if findProvider(pm.providers[network], config.Name) >= 0 {
+		return fmt.Errorf("provider %s already registered for network %s", config.Name, network)
+	}
+
 	health := &ProviderHealth{
 		status:      ProviderStatusHealthy,
 		lastChecked: time.Now(),
@@ -69,30 +172,37 @@ func (pm *ProviderManager) RegisterProvider(provider Provider, config ProviderCo
 	entry := providerEntry{
 		provider: provider,
 		health:   health,
+		limiter:  newRateLimiter(config.RequestsPerWindow, config.WindowDuration),
+		breaker:  newCircuitBreaker(config),
+		latency:  newLatencyStats(),
+		inFlight: new(atomic.Int64),
 		config:   config,
 	}
 
-	providers := pm.providers[network]
+	// Copy on write so that requests iterating the current slice are unaffected
+	existing := pm.providers[network]
+	providers := make([]providerEntry, len(existing), len(existing)+1)
+	copy(providers, existing)
 	pm.providers[network] = append(providers, entry)
 
 	// Sort providers by priority
 	pm.sortProviders(network)
 
+	// Start probing the provider in the background
+	pm.startHealthCheck(entry)
+
 	return nil
 }
 
 // getHealthyProvider returns the highest priority healthy provider for a network
 func (pm *ProviderManager) getHealthyProvider(_ context.Context, network Network) (Provider, error) {
-	pm.mu.RLock()
-	providers, exists := pm.providers[network]
-	pm.mu.RUnlock()
-
-	if !exists || len(providers) == 0 {
+	providers := pm.orderedProviders(network)
+	if len(providers) == 0 {
 		return nil, fmt.Errorf("no providers registered for network %s", network)
 	}
 
 	for _, entry := range providers {
-		if pm.isProviderAvailable(entry) {
+		if pm.isProviderAvailable(entry) && entry.breaker.canAttempt() && entry.limiter.available() {
 			return entry.provider, nil
 		}
 	}
@@ -100,88 +210,179 @@ func (pm *ProviderManager) getHealthyProvider(_ context.Context, network Network
 	return nil, fmt.Errorf("no healthy providers available for network %s", network)
 }
 
-// isProviderAvailable checks if a provider is healthy and not rate limited
+// isProviderAvailable checks if a provider is healthy enough to receive requests
+// and has not been taken out of rotation by an operator
 func (pm *ProviderManager) isProviderAvailable(entry providerEntry) bool {
 	entry.health.mu.RLock()
 	defer entry.health.mu.RUnlock()
 
-	now := time.Now()
-
-	// Check if rate limited
-	if now.Before(entry.health.rateLimitExpiry) {
+	if entry.health.disabled || entry.health.draining {
 		return false
 	}
+	return entry.health.status != ProviderStatusUnhealthy
+}
 
-	// Check if within rate limit window
-	if entry.health.requestsInWindow >= entry.config.RequestsPerWindow {
-		entry.health.rateLimitExpiry = now.Add(entry.config.WindowDuration)
-		return false
+// reserve claims a request on entry: the provider must be available, its
+// breaker must allow an attempt and its rate limit must have room. The request
+// is counted in flight before availability is checked, so that a draining
+// provider never reports no requests in flight while one is about to start.
+// When only the rate limit is exhausted, it returns how long until a slot
+// frees, and -1 otherwise.
+func (pm *ProviderManager) reserve(entry providerEntry) (bool, time.Duration) {
+	entry.inFlight.Add(1)
+	if !pm.isProviderAvailable(entry) || !entry.breaker.allow() {
+		entry.inFlight.Add(-1)
+		return false, -1
 	}
 
-	return entry.health.status == ProviderStatusHealthy
+	ok, wait := entry.limiter.tryAcquire()
+	if !ok {
+		entry.breaker.release()
+		entry.inFlight.Add(-1)
+		return false, wait
+	}
+	return true, 0
 }
 
-// recordSuccess records a successful request for a provider
-func (pm *ProviderManager) recordSuccess(entry providerEntry) {
+// recordSuccess records a successful request for a provider and how long it took
+func (pm *ProviderManager) recordSuccess(entry providerEntry, elapsed time.Duration) {
+	entry.breaker.onSuccess()
+	entry.latency.record(elapsed)
+
 	entry.health.mu.Lock()
 	defer entry.health.mu.Unlock()
 
-	entry.health.consecutiveErrs = 0
-	entry.health.requestsInWindow++
+	entry.health.observe(true, entry.config)
 }
 
-// recordError records a failed request for a provider
+// recordError records a failed request for a provider. Live traffic only
+// degrades the provider; cutting it off is left to the circuit breaker so it
+// can recover through half-open trials even without a health check.
 func (pm *ProviderManager) recordError(entry providerEntry) {
+	entry.breaker.onFailure()
+
 	entry.health.mu.Lock()
 	defer entry.health.mu.Unlock()
 
-	entry.health.consecutiveErrs++
-	if entry.health.consecutiveErrs >= entry.config.MaxConsecutiveErrs {
-		entry.health.status = ProviderStatusUnhealthy
-	}
+	entry.health.observeRequestError()
 }
 
-// sortProviders sorts providers by priority
-func (pm *ProviderManager) sortProviders(network Network) {
-	providers := pm.providers[network]
-	// Sort providers by priority (lower number means higher priority)
-	for i := 0; i < len(providers)-1; i++ {
-		for j := i + 1; j < len(providers); j++ {
-			if providers[i].config.Priority > providers[j].config.Priority {
-				providers[i], providers[j] = providers[j], providers[i]
+// Status returns a snapshot of every registered provider, grouped by network
+func (pm *ProviderManager) Status() map[Network][]ProviderSnapshot {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	status := make(map[Network][]ProviderSnapshot, len(pm.providers))
+	for network, providers := range pm.providers {
+		snapshots := make([]ProviderSnapshot, 0, len(providers))
+		for _, entry := range providers {
+			entry.health.mu.RLock()
+			snapshot := ProviderSnapshot{
+				Name:              entry.config.Name,
+				Network:           network,
+				Priority:          entry.config.Priority,
+				Weight:            entry.weight(),
+				Status:            entry.health.status,
+				Draining:          entry.health.draining,
+				Disabled:          entry.health.disabled,
+				ConsecutiveErrors: entry.health.consecutiveErrs,
+				LastChecked:       entry.health.lastChecked,
 			}
+			entry.health.mu.RUnlock()
+
+			// In flight is read after draining, so drained can't miss a request starting
+			snapshot.RequestsInWindow = entry.limiter.count()
+			snapshot.InFlight = entry.inFlight.Load()
+			snapshot.Drained = snapshot.Draining && snapshot.InFlight == 0
+			snapshot.LatencyP50, _ = entry.latency.percentile(0.50)
+			snapshot.LatencyP95, _ = entry.latency.percentile(0.95)
+			snapshot.LatencyP99, _ = entry.latency.percentile(0.99)
+			snapshot.Breaker = entry.breaker.State()
+			snapshots = append(snapshots, snapshot)
 		}
+		status[network] = snapshots
 	}
+
+	return status
 }
 
-// executeWithFallback executes an operation with automatic fallback
-func (pm *ProviderManager) executeWithFallback(_ context.Context, network Network, op func(Provider) error) error {
-	pm.mu.RLock()
+// sortProviders sorts providers by priority, keeping registration order for equal priorities
+func (pm *ProviderManager) sortProviders(network Network) {
 	providers := pm.providers[network]
-	pm.mu.RUnlock()
+	// Sort providers by priority (lower number means higher priority)
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].config.Priority < providers[j].config.Priority
+	})
+}
+
+// executeWithFallback executes a read operation with automatic fallback
+func (pm *ProviderManager) executeWithFallback(ctx context.Context, network Network, op func(Provider) error) error {
+	return pm.execute(ctx, network, operationRead, op)
+}
+
+// executeWrite executes a write operation, falling back to another provider
+// only when the failed attempt is known not to have broadcast anything
+func (pm *ProviderManager) executeWrite(ctx context.Context, network Network, op func(Provider) error) error {
+	return pm.execute(ctx, network, operationWrite, op)
+}
+
+// execute runs op against the available providers in order. When every
+// healthy provider is rate limited it waits for the first free slot, giving up
+// once ctx is done.
+func (pm *ProviderManager) execute(ctx context.Context, network Network, kind operationKind, op func(Provider) error) error {
+	providers := pm.orderedProviders(network)
 
 	if len(providers) == 0 {
 		return fmt.Errorf("no providers registered for network %s", network)
 	}
 
-	var lastErr error
-	for _, entry := range providers {
-		if !pm.isProviderAvailable(entry) {
-			continue
-		}
+	for {
+		var lastErr error
+		retryIn := time.Duration(-1)
+		for _, entry := range providers {
+			ok, wait := pm.reserve(entry)
+			if !ok {
+				if wait >= 0 && (retryIn < 0 || wait < retryIn) {
+					retryIn = wait
+				}
+				continue
+			}
+
+			start := time.Now()
+			err := op(entry.provider)
+			entry.inFlight.Add(-1)
+			if err == nil {
+				pm.recordSuccess(entry, time.Since(start))
+				return nil
+			}
 
-		err := op(entry.provider)
-		if err == nil {
-			pm.recordSuccess(entry)
-			return nil
+			// Errors that aren't the provider's fault would fail the same way on any other provider
+			if !isProviderFault(ctx, err) {
+				entry.breaker.release()
+				if kind == operationWrite && !IsValidationError(err) && !IsPreSendError(err) {
+					return &AmbiguousWriteError{Err: err}
+				}
+				return err
+			}
+
+			pm.recordError(entry)
+
+			// A write that may have reached the network must not be sent again
+			if kind == operationWrite && !IsPreSendError(err) {
+				return &AmbiguousWriteError{Err: err}
+			}
+			lastErr = err
 		}
 
-		pm.recordError(entry)
-		lastErr = err
-	}
+		if lastErr != nil {
+			return fmt.Errorf("all providers failed: %w", lastErr)
+		}
+		if retryIn < 0 {
+			return fmt.Errorf("no available providers for network %s", network)
+		}
 
-	if lastErr != nil {
-		return fmt.Errorf("all providers failed: %w", lastErr)
+		if err := waitFor(ctx, retryIn); err != nil {
+			return fmt.Errorf("no available providers for network %s: %w", network, err)
+		}
 	}
-	return fmt.Errorf("no available providers for network %s", network)
 }
@@ -207,7 +207,7 @@ func TestProviderTransactionsIntegration(t *testing.T) {
 			defer cancel()
 
 			// Get transactions
-			transactions, err := service.GetTransactions(ctx, tc.chainID, tc.tokenAddress, 10)
+			page, err := service.GetTransactions(ctx, tc.chainID, tc.tokenAddress, blockchain.TransactionsRequest{Limit: 10})
 
 			if tc.expectedError {
 				assert.Error(t, err, "Expected error for invalid token")
@@ -215,8 +215,8 @@ func TestProviderTransactionsIntegration(t *testing.T) {
 			}
 
 			require.NoError(t, err, "Failed to get transactions")
-			if transactions != nil && len(transactions) > 0 {
-				tx := transactions[0]
+			if page != nil && len(page.Transactions) > 0 {
+				tx := page.Transactions[0]
 				assert.Equal(t, tc.chainID, tx.Network, "Network mismatch")
 				assert.NotEmpty(t, tx.ID, "Transaction ID should not be empty")
 				assert.NotEmpty(t, tx.Signature, "Signature should not be empty")
@@ -267,12 +267,12 @@ func TestProviderFallbackIntegration(t *testing.T) {
 		// Make multiple requests to trigger fallback
 		for i := 0; i < 3; i++ {
 			// Get transactions
-			transactions, err := service.GetTransactions(ctx, blockchain.NetworkSolana, "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263", 10)
+			page, err := service.GetTransactions(ctx, blockchain.NetworkSolana, "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263", blockchain.TransactionsRequest{Limit: 10})
 			require.NoError(t, err, "Failed to get transactions after fallback")
 
 			// Some tokens might not have transactions yet, so we don't assert NotNil
-			if transactions != nil && len(transactions) > 0 {
-				tx := transactions[0]
+			if page != nil && len(page.Transactions) > 0 {
+				tx := page.Transactions[0]
 				assert.Equal(t, blockchain.NetworkSolana, tx.Network, "Network mismatch")
 				assert.NotEmpty(t, tx.ID, "Transaction ID should not be empty")
 				assert.NotEmpty(t, tx.Signature, "Signature should not be empty")
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a sliding-window limiter allowing at most limit requests in
+// any window of the given duration. A non-positive limit disables limiting.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+	events []time.Time // start times of requests still inside the window, oldest first
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// newRateLimiter creates a limiter from a provider configuration
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+// prune drops the requests that have left the window. The caller must hold l.mu.
+func (l *rateLimiter) prune(now time.Time) {
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(l.events) && !l.events[i].After(cutoff) {
+		i++
+	}
+	l.events = l.events[i:]
+}
+
+// tryAcquire reserves a request slot if one is free. When the window is full it
+// returns false and how long the caller has to wait for the next slot.
+func (l *rateLimiter) tryAcquire() (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+	if len(l.events) < l.limit {
+		l.events = append(l.events, now)
+		return true, 0
+	}
+
+	return false, l.events[0].Add(l.window).Sub(now)
+}
+
+// available reports whether a request could be made right now without reserving it
+func (l *rateLimiter) available() bool {
+	if l.limit <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(l.now())
+	return len(l.events) < l.limit
+}
+
+// count returns the number of requests made in the current window
+func (l *rateLimiter) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(l.now())
+	return len(l.events)
+}
+
+// waitFor blocks for d or until ctx is done, whichever comes first
+func waitFor(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package blockchain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRateLimiter(limit int, window time.Duration) (*rateLimiter, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter(limit, window)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	limiter, now := newTestRateLimiter(2, time.Second)
+
+	ok, _ := limiter.tryAcquire()
+	assert.True(t, ok)
+
+	*now = now.Add(400 * time.Millisecond)
+	ok, _ = limiter.tryAcquire()
+	assert.True(t, ok)
+	assert.Equal(t, 2, limiter.count())
+
+	// Window is full until the first request slides out
+	ok, wait := limiter.tryAcquire()
+	assert.False(t, ok)
+	assert.Equal(t, 600*time.Millisecond, wait)
+	assert.False(t, limiter.available())
+
+	*now = now.Add(600 * time.Millisecond)
+	assert.Equal(t, 1, limiter.count())
+	ok, _ = limiter.tryAcquire()
+	assert.True(t, ok)
+
+	// Both remaining requests expire after a full window
+	*now = now.Add(time.Second)
+	assert.Equal(t, 0, limiter.count())
+	assert.True(t, limiter.available())
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	limiter, _ := newTestRateLimiter(0, time.Second)
+
+	for i := 0; i < 10; i++ {
+		ok, _ := limiter.tryAcquire()
+		assert.True(t, ok)
+	}
+	assert.True(t, limiter.available())
+}
+
+func TestWaitForRespectsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := waitFor(ctx, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = waitFor(context.Background(), time.Millisecond)
+	assert.NoError(t, err)
+}
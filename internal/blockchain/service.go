@@ -2,17 +2,35 @@ package blockchain
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
 type service struct {
 	manager *ProviderManager
+	wallets WalletStore
+	builds  *ttlStore[*UnsignedTransaction] // Builds kept until they are submitted, to check the signed bytes against
+	quotes  *ttlStore[*Quote]               // Quotes shown to users, so that trades honour their terms
+	prices  PriceStore
 }
 
 // NewService creates a new blockchain service
 func NewService() Service {
 	return &service{
 		manager: NewProviderManager(),
+		builds:  newTTLStore[*UnsignedTransaction](pendingBuildTTL),
+		quotes:  newTTLStore[*Quote](quoteTTL),
+	}
+}
+
+// NewServiceWithWalletStore creates a new blockchain service that keeps the
+// keys of created wallets in store, so that transactions can be signed server-side
+func NewServiceWithWalletStore(store WalletStore) Service {
+	return &service{
+		manager: NewProviderManager(),
+		wallets: store,
+		builds:  newTTLStore[*UnsignedTransaction](pendingBuildTTL),
+		quotes:  newTTLStore[*Quote](quoteTTL),
 	}
 }
 
@@ -33,7 +51,53 @@ func (s *service) RegisterProviderWithConfig(provider Provider, config ProviderC
 	return s.manager.RegisterProvider(provider, config)
 }
 
-// CreateWallet creates a new wallet for the specified network
+// SetHedging enables hedged reads for a network
+func (s *service) SetHedging(network Network, config HedgeConfig) {
+	s.manager.SetHedging(network, config)
+}
+
+// SetStrategy selects the load balancing strategy for a network
+func (s *service) SetStrategy(network Network, strategy BalancingStrategy) {
+	s.manager.SetStrategy(network, strategy)
+}
+
+// SetPriceStore sets where the prices used to value portfolios are looked up
+func (s *service) SetPriceStore(store PriceStore) {
+	s.prices = store
+}
+
+// Close stops the background work of the registered providers
+func (s *service) Close() error {
+	return s.manager.Close()
+}
+
+// ProviderSnapshots returns the status of every registered provider
+func (s *service) ProviderSnapshots() map[Network][]ProviderSnapshot {
+	return s.manager.Status()
+}
+
+// DrainProvider stops sending new requests to a provider
+func (s *service) DrainProvider(network Network, name string) error {
+	return s.manager.DrainProvider(network, name)
+}
+
+// DisableProvider takes a provider out of rotation
+func (s *service) DisableProvider(network Network, name string) error {
+	return s.manager.DisableProvider(network, name)
+}
+
+// EnableProvider puts a provider back into rotation
+func (s *service) EnableProvider(network Network, name string) error {
+	return s.manager.EnableProvider(network, name)
+}
+
+// SetProviderPriority changes the priority of a provider
+func (s *service) SetProviderPriority(network Network, name string, priority int) error {
+	return s.manager.SetProviderPriority(network, name, priority)
+}
+
+// CreateWallet creates a new wallet for the specified network. With a wallet
+// store the private key is persisted and left out of the returned wallet.
 func (s *service) CreateWallet(ctx context.Context, network Network) (*Wallet, error) {
 	var wallet *Wallet
 	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
@@ -41,7 +105,26 @@ func (s *service) CreateWallet(ctx context.Context, network Network) (*Wallet, e
 		wallet, err = provider.CreateWallet(ctx)
 		return err
 	})
-	return wallet, err
+	if err != nil || s.wallets == nil {
+		return wallet, err
+	}
+
+	// Spending from the wallet requires its access token, which is only
+	// stored hashed and returned this once
+	token, hash, err := newAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	wallet.AccessTokenHash = hash
+	if err := s.wallets.SaveWallet(wallet); err != nil {
+		return nil, fmt.Errorf("failed to store wallet: %w", err)
+	}
+
+	public := *wallet
+	public.PrivateKey = ""
+	public.AccessToken = token
+	public.AccessTokenHash = ""
+	return &public, nil
 }
 
 // GetWallet retrieves a wallet by its address
@@ -57,55 +140,235 @@ func (s *service) GetWallet(ctx context.Context, network Network, address string
 
 // GetBalance retrieves the balance for a wallet
 func (s *service) GetBalance(ctx context.Context, network Network, address string) (Amount, error) {
-	var balance Amount
-	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
-		var err error
-		balance, err = provider.GetBalance(ctx, address)
-		return err
+	return executeHedged(ctx, s.manager, network, func(ctx context.Context, provider Provider) (Amount, error) {
+		return provider.GetBalance(ctx, address)
+	})
+}
+
+// GetPortfolio returns the token holdings of a wallet valued at the latest
+// known prices. Holdings of tokens without a price are listed but not valued.
+func (s *service) GetPortfolio(ctx context.Context, network Network, address string) (*Portfolio, error) {
+	balances, err := executeHedged(ctx, s.manager, network, func(ctx context.Context, provider Provider) ([]TokenBalance, error) {
+		return provider.GetTokenBalances(ctx, address)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]float64{}
+	if s.prices != nil && len(balances) > 0 {
+		mints := make([]string, 0, len(balances))
+		seen := make(map[string]bool, len(balances))
+		for _, balance := range balances {
+			if !seen[balance.Mint] {
+				seen[balance.Mint] = true
+				mints = append(mints, balance.Mint)
+			}
+		}
+		if prices, err = s.prices.GetTokenPrices(mints); err != nil {
+			return nil, fmt.Errorf("failed to get token prices: %w", err)
+		}
+	}
+
+	portfolio := &Portfolio{
+		Network:   network,
+		Address:   address,
+		Holdings:  make([]Holding, len(balances)),
+		Timestamp: time.Now().Unix(),
+	}
+	for i, balance := range balances {
+		holding := Holding{TokenBalance: balance}
+		if price, ok := prices[balance.Mint]; ok {
+			holding.Priced = true
+			holding.PriceUSD = price
+			holding.ValueUSD = balance.Amount.Float64() * price
+			portfolio.TotalValueUSD += holding.ValueUSD
+		}
+		portfolio.Holdings[i] = holding
+	}
+
+	return portfolio, nil
+}
+
+// Quote quotes a swap and keeps the quote, so that a buy or sell can execute
+// it by ID until it expires
+func (s *service) Quote(ctx context.Context, network Network, req QuoteRequest) (*Quote, error) {
+	swapQuote, err := executeHedged(ctx, s.manager, network, func(ctx context.Context, provider Provider) (*SwapQuote, error) {
+		return provider.Quote(ctx, req)
 	})
-	return balance, err
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &Quote{Network: network, SwapQuote: *swapQuote}
+	if quote.ID, quote.ExpiresAt, err = s.quotes.add(quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
 }
 
-// Buy executes a buy transaction
+// takeQuote claims the quote a trade executes. The quote must be unexpired,
+// for network and, when amount is set, for that amount. A trade that fails
+// releases it with releaseQuote.
+func (s *service) takeQuote(network Network, id string, amount Amount) (*Quote, error) {
+	quote, ok := s.quotes.take(id)
+	if !ok {
+		return nil, NewValidationError("unknown or expired quote: %s", id)
+	}
+	var err error
+	if quote.Network != network {
+		err = NewValidationError("unknown or expired quote: %s", id)
+	} else if amount.Value != nil && amount.Value.Sign() != 0 && amount.Value.Cmp(quote.AmountIn.Value) != 0 {
+		err = NewValidationError("amount %s does not match the quoted amount %s", amount.Value, quote.AmountIn.Value)
+	}
+	if err != nil {
+		s.releaseQuote(quote, err)
+		return nil, err
+	}
+	return quote, nil
+}
+
+// releaseQuote puts back a claimed quote whose trade failed before it was
+// broadcast, so that the trade can be retried on the same terms. A trade
+// that was, or may have been, broadcast keeps its quote.
+func (s *service) releaseQuote(quote *Quote, err error) {
+	if quote == nil || err == nil || IsAmbiguousWriteError(err) {
+		return
+	}
+	s.quotes.put(quote.ID, quote, quote.ExpiresAt)
+}
+
+// Buy executes a buy transaction, on the terms of req.QuoteID when set
 func (s *service) Buy(ctx context.Context, network Network, req BuyRequest) (*Transaction, error) {
+	var quote *Quote
+	if req.QuoteID != "" {
+		var err error
+		if quote, err = s.takeQuote(network, req.QuoteID, req.Amount); err != nil {
+			return nil, err
+		}
+		if req.TokenAddress != "" && req.TokenAddress != quote.OutputMint {
+			err := NewValidationError("quote %s does not buy %s", req.QuoteID, req.TokenAddress)
+			s.releaseQuote(quote, err)
+			return nil, err
+		}
+		req.TokenAddress = quote.OutputMint
+		req.Amount = quote.AmountIn
+		req.Quote = &quote.SwapQuote
+	}
+
 	var tx *Transaction
-	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
+	err := s.manager.executeWrite(ctx, network, func(provider Provider) error {
 		var err error
 		tx, err = provider.Buy(ctx, req)
 		return err
 	})
+	s.releaseQuote(quote, err)
 	return tx, err
 }
 
-// Sell executes a sell transaction
+// Sell executes a sell transaction, on the terms of req.QuoteID when set
 func (s *service) Sell(ctx context.Context, network Network, req SellRequest) (*Transaction, error) {
+	var quote *Quote
+	if req.QuoteID != "" {
+		var err error
+		if quote, err = s.takeQuote(network, req.QuoteID, req.Amount); err != nil {
+			return nil, err
+		}
+		if req.TokenAddress != "" && req.TokenAddress != quote.InputMint {
+			err := NewValidationError("quote %s does not sell %s", req.QuoteID, req.TokenAddress)
+			s.releaseQuote(quote, err)
+			return nil, err
+		}
+		req.TokenAddress = quote.InputMint
+		req.Amount = quote.AmountIn
+		req.Quote = &quote.SwapQuote
+	}
+
 	var tx *Transaction
-	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
+	err := s.manager.executeWrite(ctx, network, func(provider Provider) error {
 		var err error
 		tx, err = provider.Sell(ctx, req)
 		return err
 	})
+	s.releaseQuote(quote, err)
 	return tx, err
 }
 
-// GetTransaction retrieves a transaction by its ID
-func (s *service) GetTransaction(ctx context.Context, network Network, txID string) (*Transaction, error) {
+// Transfer sends SOL or tokens from a wallet to another address
+func (s *service) Transfer(ctx context.Context, network Network, req TransferRequest) (*Transaction, error) {
 	var tx *Transaction
-	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
+	err := s.manager.executeWrite(ctx, network, func(provider Provider) error {
 		var err error
-		tx, err = provider.GetTransaction(ctx, txID)
+		tx, err = provider.Transfer(ctx, req)
 		return err
 	})
 	return tx, err
 }
 
+// GetTransaction retrieves a transaction by its ID
+func (s *service) GetTransaction(ctx context.Context, network Network, txID string) (*Transaction, error) {
+	return executeHedged(ctx, s.manager, network, func(ctx context.Context, provider Provider) (*Transaction, error) {
+		return provider.GetTransaction(ctx, txID)
+	})
+}
+
 // GetTransactions retrieves transactions for a wallet
-func (s *service) GetTransactions(ctx context.Context, network Network, address string, limit int) ([]Transaction, error) {
-	var txs []Transaction
+func (s *service) GetTransactions(ctx context.Context, network Network, address string, req TransactionsRequest) (*TransactionPage, error) {
+	var page *TransactionPage
 	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
 		var err error
-		txs, err = provider.GetTransactions(ctx, address, limit)
+		page, err = provider.GetTransactions(ctx, address, req)
 		return err
 	})
-	return txs, err
+	return page, err
+}
+
+// BuildSwap builds an unsigned buy or sell transaction for the wallet to sign.
+// The build is kept so that the signed transaction can be checked against it.
+func (s *service) BuildSwap(ctx context.Context, network Network, req BuildSwapRequest) (*UnsignedTransaction, error) {
+	if req.Type != TransactionTypeBuy && req.Type != TransactionTypeSell {
+		return nil, NewValidationError("invalid transaction type: %s", req.Type)
+	}
+
+	var built *UnsignedTransaction
+	err := s.manager.executeWithFallback(ctx, network, func(provider Provider) error {
+		var err error
+		built, err = provider.BuildSwap(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	built.Network = network
+	if built.ID, built.ExpiresAt, err = s.builds.add(built); err != nil {
+		return nil, err
+	}
+	return built, nil
+}
+
+// SubmitTransaction broadcasts a client-signed transaction previously built by BuildSwap
+func (s *service) SubmitTransaction(ctx context.Context, network Network, req SubmitTransactionRequest) (*Transaction, error) {
+	built, ok := s.builds.get(req.ID)
+	if !ok || built.Network != network {
+		return nil, NewValidationError("unknown or expired transaction build: %s", req.ID)
+	}
+
+	var tx *Transaction
+	err := s.manager.executeWrite(ctx, network, func(provider Provider) error {
+		var err error
+		tx, err = provider.SubmitTransaction(ctx, built, req.Transaction)
+		return err
+	})
+	if err == nil {
+		s.builds.remove(req.ID)
+	}
+	return tx, err
+}
+
+// GetTopMemeCoins retrieves the top meme coins for a network
+func (s *service) GetTopMemeCoins(ctx context.Context, network Network, req TopMemeCoinsRequest) ([]MemeCoin, error) {
+	return executeHedged(ctx, s.manager, network, func(ctx context.Context, provider Provider) ([]MemeCoin, error) {
+		return provider.GetTopMemeCoins(ctx, req)
+	})
 }
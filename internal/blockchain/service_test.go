@@ -2,6 +2,9 @@ package blockchain
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/big"
 	"testing"
 	"time"
 
@@ -45,6 +48,22 @@ func (m *MockProvider) GetBalance(ctx context.Context, address string) (Amount,
 	return args.Get(0).(Amount), args.Error(1)
 }
 
+func (m *MockProvider) GetTokenBalances(ctx context.Context, address string) ([]TokenBalance, error) {
+	args := m.Called(ctx, address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]TokenBalance), args.Error(1)
+}
+
+func (m *MockProvider) Quote(ctx context.Context, req QuoteRequest) (*SwapQuote, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*SwapQuote), args.Error(1)
+}
+
 func (m *MockProvider) Buy(ctx context.Context, req BuyRequest) (*Transaction, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
@@ -61,6 +80,14 @@ func (m *MockProvider) Sell(ctx context.Context, req SellRequest) (*Transaction,
 	return args.Get(0).(*Transaction), args.Error(1)
 }
 
+func (m *MockProvider) Transfer(ctx context.Context, req TransferRequest) (*Transaction, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Transaction), args.Error(1)
+}
+
 func (m *MockProvider) GetTransaction(ctx context.Context, txID string) (*Transaction, error) {
 	args := m.Called(ctx, txID)
 	if args.Get(0) == nil {
@@ -69,12 +96,28 @@ func (m *MockProvider) GetTransaction(ctx context.Context, txID string) (*Transa
 	return args.Get(0).(*Transaction), args.Error(1)
 }
 
-func (m *MockProvider) GetTransactions(ctx context.Context, address string, limit int) ([]Transaction, error) {
-	args := m.Called(ctx, address, limit)
+func (m *MockProvider) GetTransactions(ctx context.Context, address string, req TransactionsRequest) (*TransactionPage, error) {
+	args := m.Called(ctx, address, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransactionPage), args.Error(1)
+}
+
+func (m *MockProvider) BuildSwap(ctx context.Context, req BuildSwapRequest) (*UnsignedTransaction, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UnsignedTransaction), args.Error(1)
+}
+
+func (m *MockProvider) SubmitTransaction(ctx context.Context, built *UnsignedTransaction, signedTx string) (*Transaction, error) {
+	args := m.Called(ctx, built, signedTx)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]Transaction), args.Error(1)
+	return args.Get(0).(*Transaction), args.Error(1)
 }
 
 func (m *MockProvider) GetTopMemeCoins(ctx context.Context, req TopMemeCoinsRequest) ([]MemeCoin, error) {
@@ -140,6 +183,86 @@ func TestCreateWallet(t *testing.T) {
 	assert.Equal(t, expectedWallet, wallet, "Should return expected wallet")
 }
 
+// memoryWalletStore is an in-memory WalletStore
+type memoryWalletStore struct {
+	wallets map[string]*Wallet
+}
+
+func (m *memoryWalletStore) SaveWallet(wallet *Wallet) error {
+	m.wallets[wallet.Address] = wallet
+	return nil
+}
+
+func (m *memoryWalletStore) GetWallet(network Network, address string) (*Wallet, error) {
+	wallet, ok := m.wallets[address]
+	if !ok {
+		return nil, fmt.Errorf("wallet not found")
+	}
+	return wallet, nil
+}
+
+func TestCreateWalletStoresPrivateKey(t *testing.T) {
+	store := &memoryWalletStore{wallets: make(map[string]*Wallet)}
+	service := NewServiceWithWalletStore(store)
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	mockProvider.On("CreateWallet", mock.Anything).Return(&Wallet{
+		ID:         "test-wallet",
+		Network:    NetworkSolana,
+		Address:    "test-address",
+		PublicKey:  "test-pubkey",
+		PrivateKey: "test-privkey",
+	}, nil)
+
+	assert.NoError(t, service.RegisterProvider(mockProvider))
+
+	wallet, err := service.CreateWallet(context.Background(), NetworkSolana)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-address", wallet.Address)
+	assert.Empty(t, wallet.PrivateKey, "Private key should stay on the server")
+
+	stored, err := store.GetWallet(NetworkSolana, "test-address")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-privkey", stored.PrivateKey)
+}
+
+func TestAuthorizeWallet(t *testing.T) {
+	store := &memoryWalletStore{wallets: make(map[string]*Wallet)}
+	service := NewServiceWithWalletStore(store)
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	mockProvider.On("CreateWallet", mock.Anything).Return(&Wallet{
+		Network:    NetworkSolana,
+		Address:    "test-address",
+		PrivateKey: "test-privkey",
+	}, nil)
+	assert.NoError(t, service.RegisterProvider(mockProvider))
+
+	wallet, err := service.CreateWallet(context.Background(), NetworkSolana)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, wallet.AccessToken)
+	assert.Empty(t, wallet.AccessTokenHash)
+
+	// Only the hash of the token is stored
+	stored, err := store.GetWallet(NetworkSolana, "test-address")
+	assert.NoError(t, err)
+	assert.Empty(t, stored.AccessToken)
+	assert.NotEqual(t, wallet.AccessToken, stored.AccessTokenHash)
+
+	ctx := context.Background()
+	assert.NoError(t, service.AuthorizeWallet(ctx, NetworkSolana, "test-address", wallet.AccessToken))
+	assert.ErrorIs(t, service.AuthorizeWallet(ctx, NetworkSolana, "test-address", ""), ErrUnauthorized)
+	assert.ErrorIs(t, service.AuthorizeWallet(ctx, NetworkSolana, "test-address", "wrong"), ErrUnauthorized)
+	assert.ErrorIs(t, service.AuthorizeWallet(ctx, NetworkSolana, "other-address", wallet.AccessToken), ErrUnauthorized)
+
+	// Wallets stored without a token can't be spent from
+	store.wallets["legacy-address"] = &Wallet{Network: NetworkSolana, Address: "legacy-address"}
+	assert.ErrorIs(t, service.AuthorizeWallet(ctx, NetworkSolana, "legacy-address", ""), ErrUnauthorized)
+
+	// Nor can any wallet without a store
+	assert.ErrorIs(t, NewService().AuthorizeWallet(ctx, NetworkSolana, "test-address", wallet.AccessToken), ErrUnauthorized)
+}
+
 func TestBuyTransaction(t *testing.T) {
 	service := NewService()
 	mockProvider := new(MockProvider)
@@ -212,6 +335,49 @@ func TestSellTransaction(t *testing.T) {
 	assert.Equal(t, expectedTx, tx, "Should return expected transaction")
 }
 
+func TestTransfer(t *testing.T) {
+	service := NewService()
+
+	mockProvider1 := new(MockProvider)
+	mockProvider1.On("Network").Return(NetworkSolana)
+	mockProvider2 := new(MockProvider)
+	mockProvider2.On("Network").Return(NetworkSolana)
+
+	for i, p := range []Provider{mockProvider1, mockProvider2} {
+		err := service.RegisterProviderWithConfig(p, ProviderConfig{
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 3,
+		})
+		assert.NoError(t, err)
+	}
+
+	transferReq := TransferRequest{
+		WalletAddress: "test-from",
+		ToAddress:     "test-to",
+		Amount:        Amount{Value: big.NewInt(1_000_000), Decimals: 9},
+	}
+	expectedTx := &Transaction{ID: "test-tx", Type: TransactionTypeTransfer}
+
+	// Transfers are writes: retried only when nothing was sent
+	mockProvider1.On("Transfer", mock.Anything, transferReq).Return(nil, NewPreSendError("failed to get recent blockhash"))
+	mockProvider2.On("Transfer", mock.Anything, transferReq).Return(expectedTx, nil)
+
+	tx, err := service.Transfer(context.Background(), NetworkSolana, transferReq)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTx, tx)
+
+	// An invalid recipient is rejected without trying another provider
+	invalidReq := TransferRequest{WalletAddress: "test-from", ToAddress: "invalid"}
+	mockProvider1.On("Transfer", mock.Anything, invalidReq).Return(nil, NewValidationError("invalid recipient address"))
+
+	tx, err = service.Transfer(context.Background(), NetworkSolana, invalidReq)
+	assert.Nil(t, tx)
+	assert.True(t, IsValidationError(err))
+	mockProvider2.AssertNotCalled(t, "Transfer", mock.Anything, invalidReq)
+}
+
 func TestProviderFallback(t *testing.T) {
 	service := NewService()
 
@@ -260,3 +426,350 @@ func TestProviderFallback(t *testing.T) {
 	mockProvider1.AssertExpectations(t)
 	mockProvider2.AssertExpectations(t)
 }
+
+func TestBuyFallsBackOnPreSendError(t *testing.T) {
+	service := NewService()
+
+	mockProvider1 := new(MockProvider)
+	mockProvider1.On("Network").Return(NetworkSolana)
+	mockProvider2 := new(MockProvider)
+	mockProvider2.On("Network").Return(NetworkSolana)
+
+	for i, p := range []Provider{mockProvider1, mockProvider2} {
+		err := service.RegisterProviderWithConfig(p, ProviderConfig{
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 3,
+		})
+		assert.NoError(t, err)
+	}
+
+	buyReq := BuyRequest{WalletAddress: "test-from", TokenAddress: "test-token"}
+	expectedTx := &Transaction{ID: "test-tx", Type: TransactionTypeBuy}
+
+	// Nothing was broadcast, so the second provider may try
+	mockProvider1.On("Buy", mock.Anything, buyReq).Return(nil, NewPreSendError("failed to get recent blockhash"))
+	mockProvider2.On("Buy", mock.Anything, buyReq).Return(expectedTx, nil)
+
+	tx, err := service.Buy(context.Background(), NetworkSolana, buyReq)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTx, tx)
+
+	mockProvider1.AssertExpectations(t)
+	mockProvider2.AssertExpectations(t)
+}
+
+func TestBuyDoesNotRetryAmbiguousFailure(t *testing.T) {
+	service := NewService()
+
+	mockProvider1 := new(MockProvider)
+	mockProvider1.On("Network").Return(NetworkSolana)
+	mockProvider2 := new(MockProvider)
+	mockProvider2.On("Network").Return(NetworkSolana)
+
+	for i, p := range []Provider{mockProvider1, mockProvider2} {
+		err := service.RegisterProviderWithConfig(p, ProviderConfig{
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 3,
+		})
+		assert.NoError(t, err)
+	}
+
+	sellReq := SellRequest{WalletAddress: "test-from", TokenAddress: "test-token"}
+
+	// A timeout after sending may or may not have landed the swap
+	mockProvider1.On("Sell", mock.Anything, sellReq).Return(nil, context.DeadlineExceeded)
+
+	tx, err := service.Sell(context.Background(), NetworkSolana, sellReq)
+	assert.Nil(t, tx)
+	assert.True(t, IsAmbiguousWriteError(err))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	mockProvider1.AssertExpectations(t)
+	mockProvider2.AssertNotCalled(t, "Sell", mock.Anything, mock.Anything)
+}
+
+func TestBuyDoesNotRetryFailedSimulation(t *testing.T) {
+	service := NewService()
+
+	mockProvider1 := new(MockProvider)
+	mockProvider1.On("Network").Return(NetworkSolana)
+	mockProvider2 := new(MockProvider)
+	mockProvider2.On("Network").Return(NetworkSolana)
+
+	for i, p := range []Provider{mockProvider1, mockProvider2} {
+		err := service.RegisterProviderWithConfig(p, ProviderConfig{
+			Priority:           i + 1,
+			RequestsPerWindow:  100,
+			WindowDuration:     time.Minute,
+			MaxConsecutiveErrs: 1,
+		})
+		assert.NoError(t, err)
+	}
+
+	buyReq := BuyRequest{WalletAddress: "test-from", TokenAddress: "test-token"}
+	failedTx := &Transaction{ID: "test-tx", Type: TransactionTypeBuy, Status: TransactionStatusFailed}
+
+	// The swap would fail the same way on any provider
+	simulationErr := &SimulationError{Err: fmt.Errorf("%w: custom program error 0x1e", ErrSlippageExceeded)}
+	mockProvider1.On("Buy", mock.Anything, buyReq).Return(failedTx, &PreSendError{Err: simulationErr})
+
+	tx, err := service.Buy(context.Background(), NetworkSolana, buyReq)
+	assert.Equal(t, failedTx, tx)
+	assert.True(t, IsSimulationError(err))
+	assert.ErrorIs(t, err, ErrSlippageExceeded)
+	assert.False(t, IsAmbiguousWriteError(err))
+
+	mockProvider1.AssertExpectations(t)
+	mockProvider2.AssertNotCalled(t, "Buy", mock.Anything, mock.Anything)
+
+	// Nor does it count against the provider
+	for _, snapshot := range service.ProviderSnapshots()[NetworkSolana] {
+		assert.Zero(t, snapshot.ConsecutiveErrors, snapshot.Name)
+	}
+}
+
+func TestBuyCancelledMidSendIsAmbiguous(t *testing.T) {
+	service := NewService()
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+
+	err := service.RegisterProvider(mockProvider)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buyReq := BuyRequest{WalletAddress: "test-from", TokenAddress: "test-token"}
+	mockProvider.On("Buy", mock.Anything, buyReq).Run(func(mock.Arguments) { cancel() }).Return(nil, context.Canceled)
+
+	tx, err := service.Buy(ctx, NetworkSolana, buyReq)
+	assert.Nil(t, tx)
+	assert.True(t, IsAmbiguousWriteError(err))
+}
+
+func TestBuildAndSubmitTransaction(t *testing.T) {
+	service := NewService()
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	assert.NoError(t, service.RegisterProvider(mockProvider))
+
+	buildReq := BuildSwapRequest{Type: TransactionTypeBuy, WalletAddress: "test-from", TokenAddress: "test-token"}
+	mockProvider.On("BuildSwap", mock.Anything, buildReq).Return(&UnsignedTransaction{
+		Type:          TransactionTypeBuy,
+		WalletAddress: "test-from",
+		TokenAddress:  "test-token",
+		Transaction:   "unsigned",
+	}, nil)
+
+	built, err := service.BuildSwap(context.Background(), NetworkSolana, buildReq)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, built.ID)
+	assert.Equal(t, NetworkSolana, built.Network)
+	assert.Greater(t, built.ExpiresAt, time.Now().Unix())
+
+	expectedTx := &Transaction{ID: "test-signature", Status: TransactionStatusPending}
+	mockProvider.On("SubmitTransaction", mock.Anything, built, "signed").Return(expectedTx, nil).Once()
+
+	tx, err := service.SubmitTransaction(context.Background(), NetworkSolana, SubmitTransactionRequest{ID: built.ID, Transaction: "signed"})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTx, tx)
+
+	// A build can only be submitted once
+	_, err = service.SubmitTransaction(context.Background(), NetworkSolana, SubmitTransactionRequest{ID: built.ID, Transaction: "signed"})
+	assert.True(t, IsValidationError(err))
+	mockProvider.AssertExpectations(t)
+}
+
+func TestSubmitTransactionValidation(t *testing.T) {
+	svc := NewService().(*service)
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	assert.NoError(t, svc.RegisterProvider(mockProvider))
+
+	// Unknown build
+	_, err := svc.SubmitTransaction(context.Background(), NetworkSolana, SubmitTransactionRequest{ID: "unknown", Transaction: "signed"})
+	assert.True(t, IsValidationError(err))
+
+	// Unsupported transaction type
+	_, err = svc.BuildSwap(context.Background(), NetworkSolana, BuildSwapRequest{Type: "transfer"})
+	assert.True(t, IsValidationError(err))
+
+	// A tampered transaction is rejected by the provider and the build stays available
+	buildReq := BuildSwapRequest{Type: TransactionTypeSell, WalletAddress: "test-from", TokenAddress: "test-token"}
+	mockProvider.On("BuildSwap", mock.Anything, buildReq).Return(&UnsignedTransaction{Transaction: "unsigned"}, nil)
+	built, err := svc.BuildSwap(context.Background(), NetworkSolana, buildReq)
+	assert.NoError(t, err)
+
+	mockProvider.On("SubmitTransaction", mock.Anything, built, "tampered").
+		Return(nil, NewValidationError("signed transaction does not match the built transaction"))
+	_, err = svc.SubmitTransaction(context.Background(), NetworkSolana, SubmitTransactionRequest{ID: built.ID, Transaction: "tampered"})
+	assert.True(t, IsValidationError(err))
+
+	_, ok := svc.builds.get(built.ID)
+	assert.True(t, ok)
+}
+
+// memoryPriceStore is an in-memory PriceStore
+type memoryPriceStore map[string]float64
+
+func (m memoryPriceStore) GetTokenPrices(addresses []string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	for _, address := range addresses {
+		if price, ok := m[address]; ok {
+			prices[address] = price
+		}
+	}
+	return prices, nil
+}
+
+func TestGetPortfolio(t *testing.T) {
+	service := NewService()
+	service.SetPriceStore(memoryPriceStore{"bonk": 0.00002, "wif": 2.5})
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	assert.NoError(t, service.RegisterProvider(mockProvider))
+
+	mockProvider.On("GetTokenBalances", mock.Anything, "test-address").Return([]TokenBalance{
+		{Account: "a", Mint: "bonk", Amount: Amount{Value: big.NewInt(150_000_000_000), Decimals: 5}},
+		{Account: "b", Mint: "wif", Amount: Amount{Value: big.NewInt(4_000_000), Decimals: 6}},
+		{Account: "c", Mint: "unknown", Amount: Amount{Value: big.NewInt(1), Decimals: 0}},
+	}, nil)
+
+	portfolio, err := service.GetPortfolio(context.Background(), NetworkSolana, "test-address")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-address", portfolio.Address)
+	assert.Len(t, portfolio.Holdings, 3)
+
+	assert.True(t, portfolio.Holdings[0].Priced)
+	assert.InDelta(t, 30, portfolio.Holdings[0].ValueUSD, 1e-9)
+	assert.InDelta(t, 10, portfolio.Holdings[1].ValueUSD, 1e-9)
+	assert.False(t, portfolio.Holdings[2].Priced)
+	assert.Zero(t, portfolio.Holdings[2].ValueUSD)
+	assert.InDelta(t, 40, portfolio.TotalValueUSD, 1e-9)
+}
+
+func TestBuyExecutesQuote(t *testing.T) {
+	service := NewService()
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	assert.NoError(t, service.RegisterProvider(mockProvider))
+
+	quoteReq := QuoteRequest{InputMint: "sol", OutputMint: "test-token", Amount: Amount{Value: big.NewInt(1000)}, SlippageBps: 50}
+	swapQuote := &SwapQuote{
+		InputMint:        "sol",
+		OutputMint:       "test-token",
+		AmountIn:         Amount{Value: big.NewInt(1000), Decimals: 9},
+		MinimumAmountOut: Amount{Value: big.NewInt(495), Decimals: 6},
+		Pool:             "test-pool",
+	}
+	mockProvider.On("Quote", mock.Anything, quoteReq).Return(swapQuote, nil)
+
+	quote, err := service.Quote(context.Background(), NetworkSolana, quoteReq)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, quote.ID)
+	assert.Equal(t, NetworkSolana, quote.Network)
+	assert.Equal(t, *swapQuote, quote.SwapQuote)
+	assert.Greater(t, quote.ExpiresAt, time.Now().Unix())
+
+	// The token and amount are taken from the quote
+	expectedReq := BuyRequest{
+		WalletAddress: "test-from",
+		TokenAddress:  "test-token",
+		Amount:        swapQuote.AmountIn,
+		QuoteID:       quote.ID,
+		Quote:         swapQuote,
+	}
+	expectedTx := &Transaction{ID: "test-tx"}
+	mockProvider.On("Buy", mock.Anything, expectedReq).Return(expectedTx, nil).Once()
+
+	tx, err := service.Buy(context.Background(), NetworkSolana, BuyRequest{WalletAddress: "test-from", QuoteID: quote.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTx, tx)
+
+	// A quote can only be executed once
+	_, err = service.Buy(context.Background(), NetworkSolana, BuyRequest{WalletAddress: "test-from", QuoteID: quote.ID})
+	assert.True(t, IsValidationError(err))
+	mockProvider.AssertExpectations(t)
+}
+
+func TestQuoteIsKeptUntilTradeIsBroadcast(t *testing.T) {
+	svc := NewService().(*service)
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	assert.NoError(t, svc.RegisterProvider(mockProvider))
+
+	mockProvider.On("Quote", mock.Anything, mock.Anything).Return(&SwapQuote{
+		InputMint:  "sol",
+		OutputMint: "test-token",
+		AmountIn:   Amount{Value: big.NewInt(1000), Decimals: 9},
+	}, nil)
+	quote, err := svc.Quote(context.Background(), NetworkSolana, QuoteRequest{})
+	assert.NoError(t, err)
+
+	// A trade that doesn't match the quote doesn't use it up
+	_, err = svc.Buy(context.Background(), NetworkSolana, BuyRequest{QuoteID: quote.ID, TokenAddress: "other-token"})
+	assert.True(t, IsValidationError(err))
+	_, ok := svc.quotes.get(quote.ID)
+	assert.True(t, ok)
+
+	// Nor does one that failed before it was sent, e.g. in simulation
+	mockProvider.On("Buy", mock.Anything, mock.Anything).Return(nil, &PreSendError{Err: &SimulationError{Err: ErrSlippageExceeded}}).Once()
+	_, err = svc.Buy(context.Background(), NetworkSolana, BuyRequest{QuoteID: quote.ID})
+	assert.True(t, IsSimulationError(err))
+	_, ok = svc.quotes.get(quote.ID)
+	assert.True(t, ok)
+
+	// A trade that may have been broadcast does
+	mockProvider.On("Buy", mock.Anything, mock.Anything).Return(nil, errors.New("connection reset")).Once()
+	_, err = svc.Buy(context.Background(), NetworkSolana, BuyRequest{QuoteID: quote.ID})
+	assert.True(t, IsAmbiguousWriteError(err))
+	_, ok = svc.quotes.get(quote.ID)
+	assert.False(t, ok)
+}
+
+func TestQuoteMustMatchTrade(t *testing.T) {
+	service := NewService()
+	mockProvider := new(MockProvider)
+	mockProvider.On("Network").Return(NetworkSolana)
+	assert.NoError(t, service.RegisterProvider(mockProvider))
+
+	mockProvider.On("Quote", mock.Anything, mock.Anything).Return(&SwapQuote{
+		InputMint:  "sol",
+		OutputMint: "test-token",
+		AmountIn:   Amount{Value: big.NewInt(1000), Decimals: 9},
+	}, nil)
+
+	tests := []struct {
+		name  string
+		trade func(quoteID string) error
+	}{
+		{"different amount", func(quoteID string) error {
+			_, err := service.Buy(context.Background(), NetworkSolana, BuyRequest{QuoteID: quoteID, Amount: Amount{Value: big.NewInt(2000)}})
+			return err
+		}},
+		{"different token", func(quoteID string) error {
+			_, err := service.Buy(context.Background(), NetworkSolana, BuyRequest{QuoteID: quoteID, TokenAddress: "other-token"})
+			return err
+		}},
+		{"sell of a buy quote", func(quoteID string) error {
+			_, err := service.Sell(context.Background(), NetworkSolana, SellRequest{QuoteID: quoteID, TokenAddress: "test-token"})
+			return err
+		}},
+		{"unknown quote", func(string) error {
+			_, err := service.Buy(context.Background(), NetworkSolana, BuyRequest{QuoteID: "unknown"})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := service.Quote(context.Background(), NetworkSolana, QuoteRequest{})
+			assert.NoError(t, err)
+			assert.True(t, IsValidationError(tt.trade(quote.ID)))
+		})
+	}
+	mockProvider.AssertNotCalled(t, "Buy", mock.Anything, mock.Anything)
+	mockProvider.AssertNotCalled(t, "Sell", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,31 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrSignerUnavailable is returned when a transaction needs a signature the server cannot produce
+var ErrSignerUnavailable = errors.New("no signer available")
+
+// Signer signs transaction messages on behalf of a wallet. Implementations may
+// hold keys in process, delegate to an external key management service, or
+// return ErrSignerUnavailable so that transactions are signed client-side.
+type Signer interface {
+	// Sign returns the signature of message by the key of the wallet at address
+	Sign(ctx context.Context, network Network, address string, message []byte) ([]byte, error)
+}
+
+// SignerFunc adapts a function to the Signer interface
+type SignerFunc func(ctx context.Context, network Network, address string, message []byte) ([]byte, error)
+
+// Sign calls f
+func (f SignerFunc) Sign(ctx context.Context, network Network, address string, message []byte) ([]byte, error) {
+	return f(ctx, network, address, message)
+}
+
+// WalletStore persists wallets, including their private keys
+type WalletStore interface {
+	SaveWallet(wallet *Wallet) error
+	GetWallet(network Network, address string) (*Wallet, error)
+}
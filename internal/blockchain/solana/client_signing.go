@@ -0,0 +1,143 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"meme-trader/internal/blockchain"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// BuildSwap builds an unsigned buy or sell transaction for the wallet to sign client-side
+func (p *Provider) BuildSwap(ctx context.Context, req blockchain.BuildSwapRequest) (*blockchain.UnsignedTransaction, error) {
+	if !p.IsValidAddress(req.WalletAddress) {
+		return nil, blockchain.NewValidationError("invalid wallet address")
+	}
+	token, err := solana.PublicKeyFromBase58(req.TokenAddress)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid token address")
+	}
+
+	// Buying spends SOL for the token, selling the other way around
+	inputMint, outputMint := solana.SolMint, token
+	if req.Type == blockchain.TransactionTypeSell {
+		inputMint, outputMint = token, solana.SolMint
+	}
+
+	swap, err := p.buildSwap(ctx, &SwapRequest{
+		FromAddress:      req.WalletAddress,
+		ToAddress:        req.TokenAddress,
+		TokenAddress:     req.TokenAddress,
+		Amount:           req.Amount,
+		MinimumAmountOut: req.MinimumAmountOut,
+		SlippageBps:      req.SlippageBps,
+		ComputeUnitPrice: req.PriorityFee,
+		Type:             req.Type,
+	}, nil, inputMint, outputMint)
+	if err != nil {
+		return nil, err
+	}
+	// Every route quotes the swaps it builds, so this would fail on any provider
+	if swap.quote == nil {
+		return nil, blockchain.NewValidationError("swap for %s was built without a quote", req.TokenAddress)
+	}
+
+	// Leave an empty slot for every required signature, as wallets expect
+	signers := swap.tx.Message.Signers()
+	swap.tx.Signatures = make([]solana.Signature, len(signers))
+
+	encoded, err := swap.tx.ToBase64()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	addresses := make([]string, len(signers))
+	for i, signer := range signers {
+		addresses[i] = signer.String()
+	}
+
+	return &blockchain.UnsignedTransaction{
+		Type:                 req.Type,
+		WalletAddress:        req.WalletAddress,
+		TokenAddress:         req.TokenAddress,
+		Amount:               req.Amount,
+		Transaction:          encoded,
+		Signers:              addresses,
+		BlockHash:            swap.blockhash.String(),
+		LastValidBlockHeight: swap.lastValidBlockHeight,
+		Quote:                *swap.quote,
+	}, nil
+}
+
+// SubmitTransaction checks that a client-signed transaction is the one that
+// was built, then broadcasts it
+func (p *Provider) SubmitTransaction(ctx context.Context, built *blockchain.UnsignedTransaction, signedTx string) (*blockchain.Transaction, error) {
+	var expected solana.Transaction
+	if err := expected.UnmarshalBase64(built.Transaction); err != nil {
+		return nil, blockchain.NewPreSendError("failed to decode built transaction: %w", err)
+	}
+
+	var signed solana.Transaction
+	if err := signed.UnmarshalBase64(signedTx); err != nil {
+		return nil, blockchain.NewValidationError("invalid signed transaction: %w", err)
+	}
+
+	if err := verifySignedTransaction(&expected, &signed); err != nil {
+		return nil, err
+	}
+
+	sig, err := p.rpcClient.SendTransaction(ctx, &signed)
+	if err != nil {
+		return nil, classifySendError(err)
+	}
+
+	now := time.Now().Unix()
+	record := &blockchain.Transaction{
+		ID:            sig.String(),
+		Network:       p.network,
+		Type:          built.Type,
+		Status:        blockchain.TransactionStatusPending,
+		FromAddress:   built.WalletAddress,
+		ToAddress:     built.TokenAddress,
+		Amount:        built.Amount,
+		TokenAddress:  built.TokenAddress,
+		Route:         built.Quote.Route,
+		Signature:     sig.String(),
+		BlockHash:     built.BlockHash,
+		CreatedAt:     now,
+		LastUpdatedAt: now,
+	}
+	p.tracker.track(record, &signed, built.LastValidBlockHeight)
+	return record, nil
+}
+
+// verifySignedTransaction checks that signed carries exactly the message that
+// was built, so the payer and instructions are unchanged, and that every
+// required signer signed it
+func verifySignedTransaction(expected, signed *solana.Transaction) error {
+	want, err := expected.Message.MarshalBinary()
+	if err != nil {
+		return blockchain.NewPreSendError("failed to encode built message: %w", err)
+	}
+
+	got, err := signed.Message.MarshalBinary()
+	if err != nil {
+		return blockchain.NewValidationError("invalid signed message: %w", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		return blockchain.NewValidationError("signed transaction does not match the built transaction")
+	}
+
+	if required := len(signed.Message.Signers()); len(signed.Signatures) != required {
+		return blockchain.NewValidationError("expected %d signatures, got %d", required, len(signed.Signatures))
+	}
+
+	if err := signed.VerifySignatures(); err != nil {
+		return blockchain.NewValidationError("invalid signature: %w", err)
+	}
+
+	return nil
+}
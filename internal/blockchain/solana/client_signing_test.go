@@ -0,0 +1,370 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSwapRPCServer stubs the RPC calls made while quoting, building and
+// sending a swap through the SOL-USDC pool for owner, whose recent priority
+// fees have a 75th percentile of 8,000 micro-lamports. Swaps consume 60,000
+// compute units in simulation.
+func newSwapRPCServer(t *testing.T, owner solana.PublicKey, sent *[]solana.Transaction) string {
+	return newRPCServer(t, swapRPCHandler(t, owner, sent)).URL
+}
+
+// swapRPCHandler answers the RPC calls stubbed by newSwapRPCServer
+func swapRPCHandler(t *testing.T, owner solana.PublicKey, sent *[]solana.Transaction) func(req rpcRequest) interface{} {
+	return func(req rpcRequest) interface{} {
+		switch req.Method {
+		case "getProgramAccounts":
+			return []interface{}{
+				map[string]interface{}{
+					"pubkey":  solUSDCAmmID.String(),
+					"account": accountResult(raydiumAmmV4ProgramID, solUSDCAmmInfo()),
+				},
+			}
+
+		case "getAccountInfo":
+			var account string
+			require.NoError(t, json.Unmarshal(req.Params[0], &account))
+
+			var value interface{}
+			switch account {
+			case solUSDCAmmID.String():
+				value = accountResult(raydiumAmmV4ProgramID, solUSDCAmmInfo())
+			case solUSDCMarketID.String():
+				value = accountResult(serumProgramID, solUSDCMarket())
+			}
+			return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": value}
+
+		case "getMultipleAccounts":
+			return solUSDCPoolAccounts(t, req)
+
+		case "getRecentPrioritizationFees":
+			return []interface{}{
+				map[string]interface{}{"slot": 1, "prioritizationFee": 0},
+				map[string]interface{}{"slot": 2, "prioritizationFee": 5_000},
+				map[string]interface{}{"slot": 3, "prioritizationFee": 20_000},
+				map[string]interface{}{"slot": 4, "prioritizationFee": 8_000},
+			}
+
+		case "getLatestBlockhash":
+			return map[string]interface{}{
+				"context": map[string]interface{}{"slot": 1},
+				"value": map[string]interface{}{
+					"blockhash":            "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N",
+					"lastValidBlockHeight": 3090,
+				},
+			}
+
+		case "simulateTransaction":
+			simulatedTransaction(t, req)
+			return map[string]interface{}{
+				"context": map[string]interface{}{"slot": 1},
+				"value":   map[string]interface{}{"err": nil, "logs": []string{}, "unitsConsumed": 60_000},
+			}
+
+		case "sendTransaction":
+			var encoded string
+			require.NoError(t, json.Unmarshal(req.Params[0], &encoded))
+
+			var tx solana.Transaction
+			require.NoError(t, tx.UnmarshalBase64(encoded))
+			*sent = append(*sent, tx)
+			return tx.Signatures[0].String()
+		}
+
+		t.Errorf("unexpected RPC method %s", req.Method)
+		return nil
+	}
+}
+
+// raydiumSwapInstruction returns the Raydium swap instruction of tx
+func raydiumSwapInstruction(t *testing.T, tx *solana.Transaction) *solana.CompiledInstruction {
+	for i, instruction := range tx.Message.Instructions {
+		if tx.Message.AccountKeys[instruction.ProgramIDIndex].Equals(raydiumAmmV4ProgramID) {
+			return &tx.Message.Instructions[i]
+		}
+	}
+	require.Fail(t, "no Raydium swap instruction")
+	return nil
+}
+
+// assertWrapsSOL checks that tx funds the wrapped SOL account of owner with
+// lamports before its swap, and closes it to owner afterwards
+func assertWrapsSOL(t *testing.T, tx *solana.Transaction, owner solana.PublicKey, lamports uint64) {
+	wrapped, _, err := solana.FindAssociatedTokenAddress(owner, solana.SolMint)
+	require.NoError(t, err)
+
+	instructions := tx.Message.Instructions
+	accounts := func(i int) []solana.PublicKey {
+		metas, err := instructions[i].ResolveInstructionAccounts(&tx.Message)
+		require.NoError(t, err)
+		keys := make([]solana.PublicKey, len(metas))
+		for j, meta := range metas {
+			keys[j] = meta.PublicKey
+		}
+		return keys
+	}
+
+	// System transfer (2) of lamports, then SyncNative (17), before the swap
+	transfer, sync, last := 3, 4, len(instructions)-1
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(instructions[transfer].Data))
+	assert.Equal(t, lamports, binary.LittleEndian.Uint64(instructions[transfer].Data[4:]))
+	assert.Equal(t, []solana.PublicKey{owner, wrapped}, accounts(transfer))
+	assert.Equal(t, []byte{17}, []byte(instructions[sync].Data))
+	assert.Equal(t, []solana.PublicKey{wrapped}, accounts(sync))
+
+	// CloseAccount (9) returns everything to the wallet
+	assert.Equal(t, []byte{9}, []byte(instructions[last].Data))
+	assert.Equal(t, []solana.PublicKey{wrapped, owner, owner}, accounts(last))
+}
+
+// newTestProvider creates a provider for rpcEndpoint with no WebSocket, whose
+// sent transactions are only checked once an hour
+func newTestProvider(t *testing.T, rpcEndpoint string, store blockchain.TransactionStore) *Provider {
+	provider, err := NewProviderWithOptions(ProviderOptions{
+		RPCEndpoint:              rpcEndpoint,
+		WSEndpoint:               "ws://127.0.0.1:1",
+		LazyWS:                   true,
+		Transactions:             store,
+		ConfirmationPollInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func TestBuildSwapAndSubmit(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	var sent []solana.Transaction
+
+	provider := newTestProvider(t, newSwapRPCServer(t, owner.PublicKey(), &sent), nil)
+
+	req := blockchain.BuildSwapRequest{
+		Type:             blockchain.TransactionTypeBuy,
+		WalletAddress:    owner.PublicKey().String(),
+		TokenAddress:     usdcMint.String(),
+		Amount:           blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+		MinimumAmountOut: blockchain.Amount{Value: newBigInt(150_000_000), Decimals: 6},
+	}
+	built, err := provider.BuildSwap(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{owner.PublicKey().String()}, built.Signers)
+	assert.Equal(t, "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N", built.BlockHash)
+	assert.Equal(t, uint64(3090), built.LastValidBlockHeight)
+	assert.Equal(t, solana.SolMint.String(), built.Quote.InputMint)
+	assert.Equal(t, usdcMint.String(), built.Quote.OutputMint)
+	assert.Equal(t, solUSDCAmmID.String(), built.Quote.Pool)
+	assert.Equal(t, int64(149_475_897), built.Quote.ExpectedAmountOut.Value.Int64())
+	assert.Equal(t, int64(150_000_000), built.Quote.MinimumAmountOut.Value.Int64())
+	assert.Zero(t, built.Quote.SlippageBps, "the minimum is above the expected amount")
+
+	// Compute budget, the wrapped SOL account created and funded, the USDC
+	// account created, the swap, then the wrapped SOL account closed
+	var tx solana.Transaction
+	require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+	require.Len(t, tx.Signatures, 1)
+	assert.Equal(t, solana.Signature{}, tx.Signatures[0])
+	assert.Equal(t, owner.PublicKey(), tx.Message.AccountKeys[0])
+
+	programs := make([]solana.PublicKey, len(tx.Message.Instructions))
+	for i, instruction := range tx.Message.Instructions {
+		programs[i] = tx.Message.AccountKeys[instruction.ProgramIDIndex]
+	}
+	assert.Equal(t, []solana.PublicKey{
+		solana.ComputeBudget,
+		solana.ComputeBudget,
+		solana.SPLAssociatedTokenAccountProgramID,
+		solana.SystemProgramID,
+		solana.TokenProgramID,
+		solana.SPLAssociatedTokenAccountProgramID,
+		raydiumAmmV4ProgramID,
+		solana.TokenProgramID,
+	}, programs)
+	assert.Equal(t, []byte{ataCreateIdempotent}, []byte(tx.Message.Instructions[2].Data))
+	assert.Equal(t, []byte{ataCreateIdempotent}, []byte(tx.Message.Instructions[5].Data))
+	assert.Equal(t, encodeSwapBaseIn(1_000_000_000, 150_000_000), []byte(raydiumSwapInstruction(t, &tx).Data))
+	assertWrapsSOL(t, &tx, owner.PublicKey(), 1_000_000_000)
+
+	// Sign as the wallet would, filling the empty slot, and submit
+	tx.Signatures = nil
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(owner.PublicKey()) {
+			return &owner
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	signed, err := tx.ToBase64()
+	require.NoError(t, err)
+
+	record, err := provider.SubmitTransaction(context.Background(), built, signed)
+	require.NoError(t, err)
+	assert.Equal(t, tx.Signatures[0].String(), record.Signature)
+	assert.Equal(t, blockchain.TransactionTypeBuy, record.Type)
+	assert.Equal(t, blockchain.TransactionStatusPending, record.Status)
+	require.Len(t, sent, 1)
+	assert.Equal(t, tx.Signatures, sent[0].Signatures)
+}
+
+func TestSubmitTransactionRejectsMismatch(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	var sent []solana.Transaction
+
+	provider := newTestProvider(t, newSwapRPCServer(t, owner.PublicKey(), &sent), nil)
+
+	built, err := provider.BuildSwap(context.Background(), blockchain.BuildSwapRequest{
+		Type:          blockchain.TransactionTypeBuy,
+		WalletAddress: owner.PublicKey().String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000)},
+	})
+	require.NoError(t, err)
+
+	sign := func(tx *solana.Transaction, key solana.PrivateKey) string {
+		tx.Signatures = nil
+		_, err := tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &key })
+		require.NoError(t, err)
+		encoded, err := tx.ToBase64()
+		require.NoError(t, err)
+		return encoded
+	}
+
+	tests := []struct {
+		name   string
+		signed func() string
+	}{
+		{"unsigned", func() string { return built.Transaction }},
+		{"not base64", func() string { return "not a transaction" }},
+		{"signed by another key", func() string {
+			var tx solana.Transaction
+			require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+			return sign(&tx, solana.NewWallet().PrivateKey)
+		}},
+		{"instructions changed", func() string {
+			var tx solana.Transaction
+			require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+			swap := raydiumSwapInstruction(t, &tx)
+			swap.Data = encodeSwapBaseIn(2_000_000_000, 0)
+			return sign(&tx, owner)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := provider.SubmitTransaction(context.Background(), built, tt.signed())
+			assert.True(t, blockchain.IsValidationError(err), "got %v", err)
+		})
+	}
+	assert.Empty(t, sent)
+}
+
+func TestBuildSwapDerivesMinimumFromSlippage(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	var sent []solana.Transaction
+
+	provider := newTestProvider(t, newSwapRPCServer(t, owner.PublicKey(), &sent), nil)
+
+	req := blockchain.BuildSwapRequest{
+		Type:          blockchain.TransactionTypeBuy,
+		WalletAddress: owner.PublicKey().String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+	}
+
+	tests := []struct {
+		name        string
+		slippageBps uint16
+		minimumOut  uint64
+	}{
+		{"default tolerance", 0, 147_981_138},
+		{"half a percent", 50, 148_728_517},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req.SlippageBps = tt.slippageBps
+			built, err := provider.BuildSwap(context.Background(), req)
+			require.NoError(t, err)
+
+			assert.Equal(t, int64(tt.minimumOut), built.Quote.MinimumAmountOut.Value.Int64())
+
+			var tx solana.Transaction
+			require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+			swap := raydiumSwapInstruction(t, &tx)
+			assert.Equal(t, encodeSwapBaseIn(1_000_000_000, tt.minimumOut), []byte(swap.Data))
+		})
+	}
+}
+
+func TestBuildSwapUnwrapsSOL(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	var sent []solana.Transaction
+	client := NewRaydiumClient(rpc.New(newSwapRPCServer(t, owner, &sent)), false)
+
+	wrapped, _, err := solana.FindAssociatedTokenAddress(owner, solana.SolMint)
+	require.NoError(t, err)
+	usdcAccount, _, err := solana.FindAssociatedTokenAddress(owner, usdcMint)
+	require.NoError(t, err)
+
+	programs := func(tx *solana.Transaction) []solana.PublicKey {
+		programs := make([]solana.PublicKey, len(tx.Message.Instructions))
+		for i, instruction := range tx.Message.Instructions {
+			programs[i] = tx.Message.AccountKeys[instruction.ProgramIDIndex]
+		}
+		return programs
+	}
+
+	// A sell receives wrapped SOL, unwrapped by closing the account
+	sell, err := client.buildSwapTransaction(context.Background(), SwapRequest{
+		FromAddress:      owner.String(),
+		TokenAddress:     usdcMint.String(),
+		Type:             blockchain.TransactionTypeSell,
+		Amount:           blockchain.Amount{Value: newBigInt(150_000_000), Decimals: 6},
+		MinimumAmountOut: blockchain.Amount{Value: newBigInt(1), Decimals: solDecimals},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []solana.PublicKey{
+		solana.ComputeBudget,
+		solana.ComputeBudget,
+		solana.SPLAssociatedTokenAccountProgramID,
+		raydiumAmmV4ProgramID,
+		solana.TokenProgramID,
+	}, programs(sell.tx))
+
+	swap, err := raydiumSwapInstruction(t, sell.tx).ResolveInstructionAccounts(&sell.tx.Message)
+	require.NoError(t, err)
+	assert.Equal(t, usdcAccount, swap[15].PublicKey, "source")
+	assert.Equal(t, wrapped, swap[16].PublicKey, "destination")
+
+	closed, err := sell.tx.Message.Instructions[4].ResolveInstructionAccounts(&sell.tx.Message)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{9}, []byte(sell.tx.Message.Instructions[4].Data))
+	assert.Equal(t, wrapped, closed[0].PublicKey)
+	assert.Equal(t, owner, closed[1].PublicKey)
+
+	// An exact output buy wraps the most it may spend, and gets the rest back
+	buy, err := client.buildSwapTransaction(context.Background(), SwapRequest{
+		FromAddress:     owner.String(),
+		TokenAddress:    usdcMint.String(),
+		Type:            blockchain.TransactionTypeBuy,
+		Mode:            SwapBaseOut,
+		Amount:          blockchain.Amount{Value: newBigInt(100_000_000), Decimals: 6},
+		MaximumAmountIn: blockchain.Amount{Value: newBigInt(700_000_000), Decimals: solDecimals},
+	})
+	require.NoError(t, err)
+	assertWrapsSOL(t, buy.tx, owner, 700_000_000)
+	assert.Empty(t, sent)
+}
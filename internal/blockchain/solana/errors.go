@@ -0,0 +1,21 @@
+package solana
+
+import (
+	"errors"
+	"fmt"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// classifySendError wraps a SendTransaction failure. A JSON-RPC error means
+// the node answered and rejected the transaction (e.g. failed preflight), so
+// nothing was broadcast. Anything else, such as a timeout or a dropped
+// connection, leaves the outcome unknown.
+func classifySendError(err error) error {
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		return blockchain.NewPreSendError("transaction rejected: %w", err)
+	}
+	return fmt.Errorf("failed to send transaction: %w", err)
+}
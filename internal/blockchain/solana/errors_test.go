@@ -0,0 +1,22 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifySendError(t *testing.T) {
+	// The node rejected the transaction, so nothing was broadcast
+	rejected := classifySendError(fmt.Errorf("send: %w", &jsonrpc.RPCError{Code: -32002, Message: "Transaction simulation failed"}))
+	assert.True(t, blockchain.IsPreSendError(rejected))
+
+	// A transport failure leaves the outcome unknown
+	timedOut := classifySendError(context.DeadlineExceeded)
+	assert.False(t, blockchain.IsPreSendError(timedOut))
+	assert.ErrorIs(t, timedOut, context.DeadlineExceeded)
+}
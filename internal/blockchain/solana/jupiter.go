@@ -0,0 +1,248 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"meme-trader/internal/blockchain"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// JupiterClient requests quotes and swap transactions from the Jupiter v6 swap API
+type JupiterClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewJupiterClient creates a client for the Jupiter v6 API at baseURL, e.g.
+// https://quote-api.jup.ag/v6
+func NewJupiterClient(baseURL string, httpClient *http.Client) *JupiterClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &JupiterClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// jupiterQuote is the part of a Jupiter quote response read when comparing
+// routes. The full response is kept in SwapQuote.RouteData, as the swap
+// endpoint expects it back unchanged.
+type jupiterQuote struct {
+	InputMint            string `json:"inputMint"`
+	InAmount             string `json:"inAmount"`
+	OutputMint           string `json:"outputMint"`
+	OutAmount            string `json:"outAmount"`
+	OtherAmountThreshold string `json:"otherAmountThreshold"`
+	SlippageBps          uint16 `json:"slippageBps"`
+	PriceImpactPct       string `json:"priceImpactPct"`
+	RoutePlan            []struct {
+		SwapInfo struct {
+			AmmKey    string `json:"ammKey"`
+			FeeAmount string `json:"feeAmount"`
+			FeeMint   string `json:"feeMint"`
+		} `json:"swapInfo"`
+	} `json:"routePlan"`
+}
+
+// jupiterSwapResponse is the response of the Jupiter swap endpoint
+type jupiterSwapResponse struct {
+	SwapTransaction      string `json:"swapTransaction"`
+	LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
+}
+
+// Quote requests the best route for swapping exactly amount of inputMint for
+// outputMint. The amounts of the quote have no decimals set.
+func (c *JupiterClient) Quote(ctx context.Context, inputMint, outputMint solana.PublicKey, amount uint64, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	query := url.Values{}
+	query.Set("inputMint", inputMint.String())
+	query.Set("outputMint", outputMint.String())
+	query.Set("amount", strconv.FormatUint(amount, 10))
+	query.Set("slippageBps", strconv.FormatUint(uint64(slippageBps), 10))
+	query.Set("swapMode", "ExactIn")
+
+	body, err := c.do(ctx, http.MethodGet, "/quote?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Jupiter quote: %w", err)
+	}
+
+	return decodeJupiterQuote(body)
+}
+
+// decodeJupiterQuote converts a Jupiter quote response into a swap quote
+func decodeJupiterQuote(body []byte) (*blockchain.SwapQuote, error) {
+	var resp jupiterQuote
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode Jupiter quote: %w", err)
+	}
+
+	amounts := make([]*big.Int, 3)
+	for i, value := range []string{resp.InAmount, resp.OutAmount, resp.OtherAmountThreshold} {
+		amount, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q in Jupiter quote", value)
+		}
+		amounts[i] = amount
+	}
+	if len(resp.RoutePlan) == 0 || amounts[1].Sign() == 0 {
+		return nil, fmt.Errorf("no Jupiter route for %s to %s", resp.InputMint, resp.OutputMint)
+	}
+
+	// The price impact is a decimal fraction, e.g. "0.0012" for 12 bps
+	priceImpact, ok := new(big.Rat).SetString(resp.PriceImpactPct)
+	if !ok {
+		return nil, fmt.Errorf("invalid price impact %q in Jupiter quote", resp.PriceImpactPct)
+	}
+	priceImpact.Mul(priceImpact, big.NewRat(basisPoints, 1))
+	priceImpactBps := new(big.Int).Quo(priceImpact.Num(), priceImpact.Denom())
+
+	// Only fees paid in the input token are comparable with a direct swap's
+	fee := new(big.Int)
+	pools := make([]string, len(resp.RoutePlan))
+	for i, step := range resp.RoutePlan {
+		pools[i] = step.SwapInfo.AmmKey
+		if step.SwapInfo.FeeMint != resp.InputMint {
+			continue
+		}
+		if stepFee, ok := new(big.Int).SetString(step.SwapInfo.FeeAmount, 10); ok {
+			fee.Add(fee, stepFee)
+		}
+	}
+
+	return &blockchain.SwapQuote{
+		InputMint:         resp.InputMint,
+		OutputMint:        resp.OutputMint,
+		AmountIn:          blockchain.Amount{Value: amounts[0]},
+		ExpectedAmountOut: blockchain.Amount{Value: amounts[1]},
+		MinimumAmountOut:  blockchain.Amount{Value: amounts[2]},
+		Fee:               blockchain.Amount{Value: fee},
+		PriceImpactBps:    priceImpactBps.Int64(),
+		SlippageBps:       resp.SlippageBps,
+		Pool:              strings.Join(pools, ","),
+		Route:             jupiterRoute,
+		RouteData:         body,
+	}, nil
+}
+
+// buildSwapTransaction requests the unsigned transaction executing quote for
+// owner, paying computeUnitPrice micro-lamports per compute unit. Jupiter
+// builds it, so it is checked to be paid and signed by owner alone before it
+// is used.
+func (c *JupiterClient) buildSwapTransaction(ctx context.Context, quote *blockchain.SwapQuote, owner solana.PublicKey, computeUnitPrice uint64) (*swapTransaction, error) {
+	if len(quote.RouteData) == 0 {
+		return nil, blockchain.NewValidationError("quote has no Jupiter route")
+	}
+
+	// Jupiter adds the compute budget instructions itself
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"quoteResponse":                 json.RawMessage(quote.RouteData),
+		"userPublicKey":                 owner.String(),
+		"wrapAndUnwrapSol":              true,
+		"dynamicComputeUnitLimit":       true,
+		"computeUnitPriceMicroLamports": computeUnitPrice,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Jupiter swap request: %w", err)
+	}
+
+	body, err := c.do(ctx, http.MethodPost, "/swap", reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Jupiter swap transaction: %w", err)
+	}
+
+	var resp jupiterSwapResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode Jupiter swap response: %w", err)
+	}
+
+	var tx solana.Transaction
+	if err := tx.UnmarshalBase64(resp.SwapTransaction); err != nil {
+		return nil, fmt.Errorf("failed to decode Jupiter swap transaction: %w", err)
+	}
+
+	signers := tx.Message.Signers()
+	if len(signers) != 1 || !signers[0].Equals(owner) {
+		return nil, fmt.Errorf("swap transaction must be signed by %s alone, got signers %v", owner, signers)
+	}
+
+	inputMint, err := solana.PublicKeyFromBase58(quote.InputMint)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid quote input mint: %w", err)
+	}
+	outputMint, err := solana.PublicKeyFromBase58(quote.OutputMint)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid quote output mint: %w", err)
+	}
+
+	return &swapTransaction{
+		tx:                   &tx,
+		quote:                quote,
+		inputMint:            inputMint,
+		outputMint:           outputMint,
+		blockhash:            tx.Message.RecentBlockhash,
+		lastValidBlockHeight: resp.LastValidBlockHeight,
+	}, nil
+}
+
+// setJupiterSlippage sets the slippage tolerance of a Jupiter quote, which the
+// swap program enforces as a minimum amount out
+func setJupiterSlippage(quote *blockchain.SwapQuote, slippageBps uint16) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(quote.RouteData, &raw); err != nil {
+		return fmt.Errorf("failed to decode Jupiter quote: %w", err)
+	}
+
+	// The program computes the minimum as outAmount * (10000 - slippageBps) / 10000
+	minimum := new(big.Int).Mul(quote.ExpectedAmountOut.Value, big.NewInt(int64(basisPoints-int(slippageBps))))
+	minimum.Quo(minimum, big.NewInt(basisPoints))
+
+	raw["slippageBps"] = json.RawMessage(strconv.FormatUint(uint64(slippageBps), 10))
+	raw["otherAmountThreshold"] = json.RawMessage(strconv.Quote(minimum.String()))
+	routeData, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to encode Jupiter quote: %w", err)
+	}
+
+	quote.RouteData = routeData
+	quote.SlippageBps = slippageBps
+	quote.MinimumAmountOut.Value = minimum
+	return nil
+}
+
+// do sends a request to the Jupiter API and returns the body of a 200 response
+func (c *JupiterClient) do(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	// Jupiter explains rejected requests, e.g. tokens with no route, in the body
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+
+	return respBody, nil
+}
@@ -0,0 +1,279 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jupiterStub is a stand-in for the Jupiter v6 API, quoting 1 SOL for
+// outAmount USDC over two hops and answering swaps with swapTransaction
+type jupiterStub struct {
+	outAmount       string
+	swapTransaction string
+	status          int // Status of every response when set
+
+	quotes []url.Values
+	swaps  []map[string]json.RawMessage
+}
+
+// jupiterQuoteResponse is a quote response as returned by the Jupiter API
+func jupiterQuoteResponse(outAmount, threshold string, slippageBps int) map[string]interface{} {
+	hop := func(ammKey, inputMint, outputMint, feeAmount, feeMint string) map[string]interface{} {
+		return map[string]interface{}{
+			"swapInfo": map[string]interface{}{
+				"ammKey":     ammKey,
+				"label":      "Orca",
+				"inputMint":  inputMint,
+				"outputMint": outputMint,
+				"feeAmount":  feeAmount,
+				"feeMint":    feeMint,
+			},
+			"percent": 100,
+		}
+	}
+
+	return map[string]interface{}{
+		"inputMint":            solana.SolMint.String(),
+		"inAmount":             "1000000000",
+		"outputMint":           usdcMint.String(),
+		"outAmount":            outAmount,
+		"otherAmountThreshold": threshold,
+		"swapMode":             "ExactIn",
+		"slippageBps":          slippageBps,
+		"priceImpactPct":       "0.0012",
+		"routePlan": []interface{}{
+			hop("pool1", solana.SolMint.String(), bonkMint, "1000", solana.SolMint.String()),
+			hop("pool2", bonkMint, usdcMint.String(), "50", usdcMint.String()),
+		},
+		"contextSlot": 1,
+	}
+}
+
+// bonkMint is the intermediate token of the stubbed two-hop route
+const bonkMint = "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+
+func newJupiterServer(t *testing.T, stub *jupiterStub) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stub.status != 0 {
+			w.WriteHeader(stub.status)
+			w.Write([]byte(`{"error":"Could not find any route"}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v6/quote":
+			stub.quotes = append(stub.quotes, r.URL.Query())
+			json.NewEncoder(w).Encode(jupiterQuoteResponse(stub.outAmount, stub.outAmount, 100))
+		case "/v6/swap":
+			assert.Equal(t, http.MethodPost, r.Method)
+			var body map[string]json.RawMessage
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			stub.swaps = append(stub.swaps, body)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"swapTransaction":      stub.swapTransaction,
+				"lastValidBlockHeight": 4000,
+			})
+		default:
+			t.Errorf("unexpected Jupiter request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// jupiterSwapTransaction encodes an unsigned v0 transaction paid by payer, as
+// the Jupiter swap endpoint returns it
+func jupiterSwapTransaction(t *testing.T, payer, from solana.PublicKey) string {
+	tx := newTransferTransaction(t, payer, from, solana.NewWallet().PublicKey())
+	tx.Message.SetVersion(solana.MessageVersionV0)
+	tx.Message.RecentBlockhash = solana.MustHashFromBase58("EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N")
+	tx.Signatures = make([]solana.Signature, len(tx.Message.Signers()))
+
+	encoded, err := tx.ToBase64()
+	require.NoError(t, err)
+	return encoded
+}
+
+// newJupiterTestProvider creates a provider swapping through the stubbed
+// SOL-USDC pool and Jupiter API, signing with the wallets in store
+func newJupiterTestProvider(t *testing.T, owner solana.PublicKey, sent *[]solana.Transaction, jupiterURL string, store memoryWalletStore) *Provider {
+	handle := swapRPCHandler(t, owner, sent)
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		if req.Method == "getTokenSupply" {
+			return map[string]interface{}{
+				"context": map[string]interface{}{"slot": 1},
+				"value":   map[string]interface{}{"amount": "1000000000000", "decimals": 6, "uiAmountString": "1000000"},
+			}
+		}
+		return handle(req)
+	})
+
+	provider, err := NewProviderWithOptions(ProviderOptions{
+		RPCEndpoint:              server.URL,
+		WSEndpoint:               "ws://127.0.0.1:1",
+		LazyWS:                   true,
+		Signer:                   NewWalletSigner(store),
+		JupiterEndpoint:          jupiterURL + "/v6",
+		ConfirmationPollInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func TestJupiterClientQuote(t *testing.T) {
+	stub := &jupiterStub{outAmount: "150000000"}
+	server := newJupiterServer(t, stub)
+
+	client := NewJupiterClient(server.URL+"/v6/", nil)
+	quote, err := client.Quote(context.Background(), solana.SolMint, usdcMint, 1_000_000_000, 50)
+	require.NoError(t, err)
+
+	require.Len(t, stub.quotes, 1)
+	assert.Equal(t, solana.SolMint.String(), stub.quotes[0].Get("inputMint"))
+	assert.Equal(t, usdcMint.String(), stub.quotes[0].Get("outputMint"))
+	assert.Equal(t, "1000000000", stub.quotes[0].Get("amount"))
+	assert.Equal(t, "50", stub.quotes[0].Get("slippageBps"))
+
+	assert.Equal(t, solana.SolMint.String(), quote.InputMint)
+	assert.Equal(t, usdcMint.String(), quote.OutputMint)
+	assert.Equal(t, int64(1_000_000_000), quote.AmountIn.Value.Int64())
+	assert.Equal(t, int64(150_000_000), quote.ExpectedAmountOut.Value.Int64())
+	// Only the first hop's fee is paid in SOL
+	assert.Equal(t, int64(1000), quote.Fee.Value.Int64())
+	assert.Equal(t, int64(12), quote.PriceImpactBps)
+	assert.Equal(t, "pool1,pool2", quote.Pool)
+	assert.Equal(t, jupiterRoute, quote.Route)
+	assert.NotEmpty(t, quote.RouteData)
+
+	stub.status = http.StatusBadRequest
+	_, err = client.Quote(context.Background(), solana.SolMint, usdcMint, 1_000_000_000, 50)
+	assert.ErrorContains(t, err, "Could not find any route")
+}
+
+func TestJupiterClientBuildSwapTransaction(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	stub := &jupiterStub{outAmount: "150000000", swapTransaction: jupiterSwapTransaction(t, owner, owner)}
+	server := newJupiterServer(t, stub)
+
+	client := NewJupiterClient(server.URL+"/v6", nil)
+	quote, err := client.Quote(context.Background(), solana.SolMint, usdcMint, 1_000_000_000, 50)
+	require.NoError(t, err)
+
+	swap, err := client.buildSwapTransaction(context.Background(), quote, owner, 5_000)
+	require.NoError(t, err)
+
+	require.Len(t, stub.swaps, 1)
+	assert.JSONEq(t, string(quote.RouteData), string(stub.swaps[0]["quoteResponse"]))
+	assert.JSONEq(t, `"`+owner.String()+`"`, string(stub.swaps[0]["userPublicKey"]))
+	assert.JSONEq(t, `5000`, string(stub.swaps[0]["computeUnitPriceMicroLamports"]))
+	assert.Equal(t, "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N", swap.blockhash.String())
+	assert.Equal(t, uint64(4000), swap.lastValidBlockHeight)
+	assert.Equal(t, quote, swap.quote)
+
+	// A transaction needing another signature is refused
+	stub.swapTransaction = jupiterSwapTransaction(t, owner, solana.NewWallet().PublicKey())
+	_, err = client.buildSwapTransaction(context.Background(), quote, owner, 5_000)
+	assert.ErrorContains(t, err, "signed by "+owner.String()+" alone")
+
+	_, err = client.buildSwapTransaction(context.Background(), &blockchain.SwapQuote{Route: jupiterRoute}, owner, 5_000)
+	assert.True(t, blockchain.IsValidationError(err))
+}
+
+func TestProviderQuotePicksBestRoute(t *testing.T) {
+	// Raydium quotes 149,475,897 USDC for 1 SOL
+	tests := []struct {
+		name      string
+		outAmount string
+		status    int
+		route     string
+	}{
+		{name: "jupiter pays more", outAmount: "150000000", route: jupiterRoute},
+		{name: "raydium pays more", outAmount: "149000000", route: raydiumRoute},
+		{name: "jupiter has no route", status: http.StatusBadRequest, route: raydiumRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []solana.Transaction
+			server := newJupiterServer(t, &jupiterStub{outAmount: tt.outAmount, status: tt.status})
+			provider := newJupiterTestProvider(t, solana.NewWallet().PublicKey(), &sent, server.URL, memoryWalletStore{})
+
+			quote, err := provider.Quote(context.Background(), blockchain.QuoteRequest{
+				InputMint:  solana.SolMint.String(),
+				OutputMint: usdcMint.String(),
+				Amount:     blockchain.Amount{Value: newBigInt(1_000_000_000)},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.route, quote.Route)
+			assert.Equal(t, uint8(solDecimals), quote.AmountIn.Decimals)
+			assert.Equal(t, uint8(6), quote.ExpectedAmountOut.Decimals)
+			assert.Equal(t, uint8(6), quote.MinimumAmountOut.Decimals)
+		})
+	}
+}
+
+func TestProviderBuyThroughJupiter(t *testing.T) {
+	store := memoryWalletStore{}
+	owner := storeKey(store)
+
+	var sent []solana.Transaction
+	stub := &jupiterStub{outAmount: "150000000", swapTransaction: jupiterSwapTransaction(t, owner.PublicKey(), owner.PublicKey())}
+	server := newJupiterServer(t, stub)
+	provider := newJupiterTestProvider(t, owner.PublicKey(), &sent, server.URL, store)
+
+	tx, err := provider.Buy(context.Background(), blockchain.BuyRequest{
+		WalletAddress: owner.PublicKey().String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+		MaxPrice:      blockchain.Amount{Value: newBigInt(148_500_000), Decimals: 6},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, jupiterRoute, tx.Route)
+	assert.Equal(t, "EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N", tx.BlockHash)
+	require.Len(t, sent, 1)
+	assert.Equal(t, tx.Signature, sent[0].Signatures[0].String())
+	assert.NoError(t, sent[0].VerifySignatures())
+
+	// The caller's minimum is sent to Jupiter as a 1% tolerance
+	require.Len(t, stub.swaps, 1)
+	var quote jupiterQuote
+	require.NoError(t, json.Unmarshal(stub.swaps[0]["quoteResponse"], &quote))
+	assert.Equal(t, uint16(100), quote.SlippageBps)
+	assert.Equal(t, "148500000", quote.OtherAmountThreshold)
+}
+
+func TestProviderBuyFallsBackToRaydium(t *testing.T) {
+	store := memoryWalletStore{}
+	owner := storeKey(store)
+
+	var sent []solana.Transaction
+	stub := &jupiterStub{outAmount: "150000000"}
+	server := newJupiterServer(t, stub)
+	provider := newJupiterTestProvider(t, owner.PublicKey(), &sent, server.URL, store)
+
+	// Jupiter can't guarantee more than it quoted, Raydium fails on-chain instead
+	tx, err := provider.Buy(context.Background(), blockchain.BuyRequest{
+		WalletAddress: owner.PublicKey().String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+		MaxPrice:      blockchain.Amount{Value: newBigInt(151_000_000), Decimals: 6},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, raydiumRoute, tx.Route)
+	assert.Empty(t, stub.swaps)
+	require.Len(t, sent, 1)
+}
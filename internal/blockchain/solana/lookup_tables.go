@@ -0,0 +1,159 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// maxTransactionSize is the largest serialized transaction the network accepts
+	maxTransactionSize = 1232
+
+	// lookupTableTTL is how long the addresses of a lookup table are cached.
+	// Tables only grow while active, so a stale copy is still correct; it is
+	// refreshed to pick up extensions and deactivations.
+	lookupTableTTL = 10 * time.Minute
+)
+
+// addressLookupTableProgramID owns the address lookup tables
+var addressLookupTableProgramID = solana.MustPublicKeyFromBase58("AddressLookupTab1e1111111111111111111111111")
+
+// lookupTables resolves the address lookup tables swaps load their accounts
+// from, and caches their addresses
+type lookupTables struct {
+	rpcClient *rpc.Client
+	addresses []solana.PublicKey
+
+	mu        sync.Mutex
+	tables    map[solana.PublicKey]solana.PublicKeySlice // Active tables only
+	fetchedAt time.Time
+}
+
+func newLookupTables(rpcClient *rpc.Client, addresses []solana.PublicKey) *lookupTables {
+	return &lookupTables{rpcClient: rpcClient, addresses: addresses}
+}
+
+// resolve returns the addresses held by each active table, fetching them
+// with a single getMultipleAccounts call once the cache expires
+func (l *lookupTables) resolve(ctx context.Context) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+	if l == nil || len(l.addresses) == 0 {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.tables != nil && time.Since(l.fetchedAt) < lookupTableTTL {
+		return l.tables, nil
+	}
+
+	result, err := l.rpcClient.GetMultipleAccounts(ctx, l.addresses...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lookup tables: %w", err)
+	}
+
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice, len(l.addresses))
+	for i, account := range result.Value {
+		// Swaps are only smaller with a table, so closed tables are skipped
+		address := l.addresses[i]
+		if account == nil || !account.Owner.Equals(addressLookupTableProgramID) {
+			continue
+		}
+		state, err := addresslookuptable.DecodeAddressLookupTableState(account.Data.GetBinary())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode lookup table %s: %w", address, err)
+		}
+		// A deactivated table can't be loaded from once it is closed
+		if state.IsActive() {
+			tables[address] = state.Addresses
+		}
+	}
+
+	l.tables = tables
+	l.fetchedAt = time.Now()
+	return tables, nil
+}
+
+// newSwapTransaction compiles instructions into a transaction paid by payer.
+// Accounts held by the lookup tables are loaded from them in a v0
+// transaction; the transaction is legacy when no table holds any of them.
+// Transactions too large to be sent are rejected.
+func newSwapTransaction(ctx context.Context, lookups *lookupTables, instructions []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey) (*solana.Transaction, error) {
+	tables, err := lookups.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []solana.TransactionOption
+	if used := usedLookupTables(tables, instructions); len(used) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(used))
+	}
+	return newTransaction(instructions, blockhash, payer, opts...)
+}
+
+// newTransaction compiles instructions into a transaction paid by payer,
+// rejecting transactions too large to be sent
+func newTransaction(instructions []solana.Instruction, blockhash solana.Hash, payer solana.PublicKey, opts ...solana.TransactionOption) (*solana.Transaction, error) {
+	opts = append([]solana.TransactionOption{solana.TransactionPayer(payer)}, opts...)
+	tx, err := solana.NewTransaction(instructions, blockhash, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	size, err := transactionSize(tx)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxTransactionSize {
+		return nil, fmt.Errorf("transaction is %d bytes, over the %d byte limit", size, maxTransactionSize)
+	}
+	return tx, nil
+}
+
+// usedLookupTables returns the tables holding accounts of instructions that
+// can be loaded from a table: neither signers nor programs
+func usedLookupTables(tables map[solana.PublicKey]solana.PublicKeySlice, instructions []solana.Instruction) map[solana.PublicKey]solana.PublicKeySlice {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	programs := make(map[solana.PublicKey]bool, len(instructions))
+	for _, instruction := range instructions {
+		programs[instruction.ProgramID()] = true
+	}
+	loadable := make(map[solana.PublicKey]bool)
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			if !account.IsSigner && !programs[account.PublicKey] {
+				loadable[account.PublicKey] = true
+			}
+		}
+	}
+
+	used := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for table, addresses := range tables {
+		for _, address := range addresses {
+			if loadable[address] {
+				used[table] = addresses
+				break
+			}
+		}
+	}
+	return used
+}
+
+// transactionSize returns the serialized size of tx once signed
+func transactionSize(tx *solana.Transaction) (int, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	signatures := int(tx.Message.Header.NumRequiredSignatures)
+	// Signature count (a one byte compact-u16 below 128), then the signatures
+	return 1 + signatures*solana.SignatureLength + len(message), nil
+}
@@ -0,0 +1,250 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	solUSDCLookupTable = solana.MustPublicKeyFromBase58("8QM63b1EtQpVS2qihKmJLgTAEV9WtErFo1EPEa4yZvnN")
+	deactivatedTable   = solana.MustPublicKeyFromBase58("CsL1zR9zJpRDbvZvDagTZ2WwyvKrJvK78kC5vUCMmRYA")
+	closedLookupTable  = solana.MustPublicKeyFromBase58("2nQUTfJzJqgjBUvuJQf9cRVsXU14358Y6tgSAAFuJXwK")
+	activeTableSlot    = uint64(math.MaxUint64) // Deactivation slot of an active table
+)
+
+// lookupTableData is the account data of a lookup table holding addresses
+func lookupTableData(deactivationSlot uint64, addresses ...solana.PublicKey) []byte {
+	data := make([]byte, 56, 56+32*len(addresses))
+	binary.LittleEndian.PutUint32(data[0:], 1)
+	binary.LittleEndian.PutUint64(data[4:], deactivationSlot)
+	for _, address := range addresses {
+		data = append(data, address[:]...)
+	}
+	return data
+}
+
+// solUSDCTableAddresses are the accounts of swaps through the SOL-USDC pool
+// that don't depend on the wallet
+func solUSDCTableAddresses() []solana.PublicKey {
+	return []solana.PublicKey{
+		solana.TokenProgramID, solana.SystemProgramID, solana.SolMint, usdcMint,
+		solUSDCAmmID, solUSDCAuthority, solUSDCOpenOrders, solUSDCTargetOrders,
+		solUSDCBaseVault, solUSDCQuoteVault, serumProgramID, solUSDCMarketID,
+		solUSDCMarketBids, solUSDCMarketAsks, solUSDCMarketEventQueue,
+		solUSDCMarketBaseVault, solUSDCMarketQuoteVault, solUSDCVaultSigner,
+	}
+}
+
+// lookupTableRPCHandler answers getMultipleAccounts for the lookup tables,
+// and passes other calls to next
+func lookupTableRPCHandler(t *testing.T, next func(req rpcRequest) interface{}) func(req rpcRequest) interface{} {
+	tables := map[string][]byte{
+		solUSDCLookupTable.String(): lookupTableData(activeTableSlot, solUSDCTableAddresses()...),
+		deactivatedTable.String():   lookupTableData(100, solUSDCAmmID),
+	}
+
+	return func(req rpcRequest) interface{} {
+		if req.Method != "getMultipleAccounts" {
+			return next(req)
+		}
+		var accounts []string
+		require.NoError(t, json.Unmarshal(req.Params[0], &accounts))
+		if _, ok := tables[accounts[0]]; !ok && accounts[0] != closedLookupTable.String() {
+			return next(req)
+		}
+
+		values := make([]interface{}, len(accounts))
+		for i, account := range accounts {
+			if data, ok := tables[account]; ok {
+				values[i] = accountResult(addressLookupTableProgramID, data)
+			}
+		}
+		return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": values}
+	}
+}
+
+// solUSDCSwapInstructions are the instructions of a swap of 1 SOL for USDC
+func solUSDCSwapInstructions(t *testing.T, owner solana.PublicKey) []solana.Instruction {
+	client := NewRaydiumClient(nil, false)
+	source, createSource, err := tokenAccount(owner, solana.SolMint)
+	require.NoError(t, err)
+	destination, createDestination, err := tokenAccount(owner, usdcMint)
+	require.NoError(t, err)
+
+	instructions := computeBudgetInstructions(defaultSwapComputeUnits, defaultComputeUnitPrice)
+	return append(instructions,
+		createSource,
+		createDestination,
+		client.buildSwapInstruction(solUSDCPoolKeys(t), source, destination, owner, encodeSwapBaseIn(1_000_000_000, 0)),
+	)
+}
+
+// encodedSize returns the size of tx once signed by key and serialized
+func encodedSize(t *testing.T, tx *solana.Transaction, key solana.PrivateKey) int {
+	_, err := tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &key })
+	require.NoError(t, err)
+	encoded, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return len(encoded)
+}
+
+func TestLookupTablesResolve(t *testing.T) {
+	var calls int
+	handle := lookupTableRPCHandler(t, nil)
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		calls++
+		return handle(req)
+	})
+	client := rpc.New(server.URL)
+
+	// Deactivated and closed tables can't be loaded from
+	lookups := newLookupTables(client, []solana.PublicKey{solUSDCLookupTable, deactivatedTable, closedLookupTable})
+	tables, err := lookups.resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[solana.PublicKey]solana.PublicKeySlice{
+		solUSDCLookupTable: solUSDCTableAddresses(),
+	}, tables)
+
+	// Tables are cached
+	_, err = lookups.resolve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// Without tables nothing is fetched
+	tables, err = newLookupTables(client, nil).resolve(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, tables)
+	assert.Equal(t, 1, calls)
+}
+
+func TestNewSwapTransaction(t *testing.T) {
+	server := newRPCServer(t, lookupTableRPCHandler(t, nil))
+	client := rpc.New(server.URL)
+	owner := solana.NewWallet().PrivateKey
+	blockhash := solana.MustHashFromBase58("EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N")
+
+	legacy, err := newSwapTransaction(context.Background(), nil, solUSDCSwapInstructions(t, owner.PublicKey()), blockhash, owner.PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, solana.MessageVersionLegacy, legacy.Message.GetVersion())
+	legacySize := encodedSize(t, legacy, owner)
+
+	// A table holding none of the accounts isn't needed
+	unused := newLookupTables(client, []solana.PublicKey{deactivatedTable})
+	tx, err := newSwapTransaction(context.Background(), unused, solUSDCSwapInstructions(t, owner.PublicKey()), blockhash, owner.PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, solana.MessageVersionLegacy, tx.Message.GetVersion())
+
+	lookups := newLookupTables(client, []solana.PublicKey{solUSDCLookupTable})
+	tx, err = newSwapTransaction(context.Background(), lookups, solUSDCSwapInstructions(t, owner.PublicKey()), blockhash, owner.PublicKey())
+	require.NoError(t, err)
+	assert.Equal(t, solana.MessageVersionV0, tx.Message.GetVersion())
+	require.Len(t, tx.Message.AddressTableLookups, 1)
+	assert.Equal(t, solUSDCLookupTable, tx.Message.AddressTableLookups[0].AccountKey)
+
+	// The signer, its token accounts and the invoked programs stay in the message
+	assert.Equal(t, owner.PublicKey(), tx.Message.AccountKeys[0])
+	assert.Len(t, tx.Message.AccountKeys, 6)
+
+	size, err := transactionSize(tx)
+	require.NoError(t, err)
+	assert.Equal(t, size, encodedSize(t, tx, owner))
+	assert.Less(t, size, legacySize)
+
+	// Decoded as it is sent, the transaction loads the same accounts
+	encoded, err := tx.ToBase64()
+	require.NoError(t, err)
+	var decoded solana.Transaction
+	require.NoError(t, decoded.UnmarshalBase64(encoded))
+	assert.Equal(t, solana.MessageVersionV0, decoded.Message.GetVersion())
+	assert.NoError(t, decoded.VerifySignatures())
+	require.NoError(t, decoded.Message.SetAddressTables(map[solana.PublicKey]solana.PublicKeySlice{
+		solUSDCLookupTable: solUSDCTableAddresses(),
+	}))
+	require.NoError(t, decoded.Message.ResolveLookups())
+
+	swap := decoded.Message.Instructions[len(decoded.Message.Instructions)-1]
+	accounts, err := swap.ResolveInstructionAccounts(&decoded.Message)
+	require.NoError(t, err)
+	want := solUSDCSwapInstructions(t, owner.PublicKey())[4].Accounts()
+	require.Len(t, accounts, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].PublicKey, accounts[i].PublicKey, "account %d", i)
+		if want[i].IsWritable {
+			assert.True(t, accounts[i].IsWritable, "account %d", i)
+		}
+	}
+}
+
+func TestNewSwapTransactionSizeLimit(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	blockhash := solana.MustHashFromBase58("EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N")
+
+	// 40 accounts take 1,280 bytes in a legacy message, one byte each from a table
+	accounts := solana.AccountMetaSlice{solana.Meta(owner.PublicKey()).WRITE().SIGNER()}
+	addresses := make([]solana.PublicKey, 40)
+	for i := range addresses {
+		addresses[i] = solana.NewWallet().PublicKey()
+		accounts = append(accounts, solana.Meta(addresses[i]).WRITE())
+	}
+	instructions := []solana.Instruction{solana.NewInstruction(raydiumAmmV4ProgramID, accounts, []byte{9})}
+
+	_, err := newSwapTransaction(context.Background(), nil, instructions, blockhash, owner.PublicKey())
+	assert.ErrorContains(t, err, "over the 1232 byte limit")
+
+	table := solana.NewWallet().PublicKey()
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		require.Equal(t, "getMultipleAccounts", req.Method)
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value":   []interface{}{accountResult(addressLookupTableProgramID, lookupTableData(activeTableSlot, addresses...))},
+		}
+	})
+
+	tx, err := newSwapTransaction(context.Background(), newLookupTables(rpc.New(server.URL), []solana.PublicKey{table}), instructions, blockhash, owner.PublicKey())
+	require.NoError(t, err)
+	size := encodedSize(t, tx, owner)
+	assert.LessOrEqual(t, size, maxTransactionSize)
+	assert.Len(t, tx.Message.AddressTableLookups[0].WritableIndexes, 40)
+}
+
+func TestBuildSwapWithLookupTables(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	var sent []solana.Transaction
+	server := newRPCServer(t, lookupTableRPCHandler(t, swapRPCHandler(t, owner, &sent)))
+
+	provider, err := NewProviderWithOptions(ProviderOptions{
+		RPCEndpoint:  server.URL,
+		WSEndpoint:   "ws://127.0.0.1:1",
+		LazyWS:       true,
+		LookupTables: []string{solUSDCLookupTable.String()},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { provider.Close() })
+
+	built, err := provider.BuildSwap(context.Background(), blockchain.BuildSwapRequest{
+		Type:          blockchain.TransactionTypeBuy,
+		WalletAddress: owner.String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+	})
+	require.NoError(t, err)
+
+	var tx solana.Transaction
+	require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+	assert.Equal(t, solana.MessageVersionV0, tx.Message.GetVersion())
+	require.Len(t, tx.Message.AddressTableLookups, 1)
+	assert.Equal(t, solUSDCLookupTable, tx.Message.AddressTableLookups[0].AccountKey)
+	assert.NotContains(t, tx.Message.AccountKeys, solUSDCAmmID)
+
+	_, err = NewProviderWithOptions(ProviderOptions{RPCEndpoint: server.URL, LazyWS: true, LookupTables: []string{"not a key"}})
+	assert.ErrorContains(t, err, "invalid lookup table")
+}
@@ -0,0 +1,141 @@
+package solana
+
+import (
+	"fmt"
+	"meme-trader/internal/blockchain"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// defaultRPCTimeout bounds each RPC call when no HTTP client is supplied
+	defaultRPCTimeout = 30 * time.Second
+
+	// defaultConfirmationPollInterval is how often sent transactions are checked
+	defaultConfirmationPollInterval = 2 * time.Second
+
+	// Ports used by solana-test-validator; its WebSocket listens one port above RPC
+	localRPCPort = "8899"
+	localWSPort  = "8900"
+)
+
+// ProviderOptions configures the endpoints and transport of a Solana provider
+type ProviderOptions struct {
+	RPCEndpoint string             // JSON-RPC URL; defaults to the public mainnet or devnet endpoint
+	WSEndpoint  string             // WebSocket URL; derived from RPCEndpoint when empty
+	IsDevnet    bool               // Selects the devnet defaults and program IDs
+	Commitment  rpc.CommitmentType // Commitment used for reads (default finalized)
+	HTTPClient  *http.Client       // Client used for RPC calls; overrides Timeout
+	Timeout     time.Duration      // Timeout of each RPC call when HTTPClient is nil (default 30s)
+	Headers     map[string]string  // Extra headers sent with every RPC and WebSocket request, e.g. API keys
+	LazyWS      bool               // Connect the WebSocket on first use instead of in the constructor
+	Signer      blockchain.Signer  // Signs transactions before broadcast; trades fail without one
+
+	PriorityFeePercentile int    // Percentile of recent prioritization fees paid by swaps (default 75)
+	MaxComputeUnitPrice   uint64 // Cap of estimated priority fees, in micro-lamports per compute unit (default 200,000)
+
+	// LookupTables are the addresses of the address lookup tables swaps load
+	// accounts from, in v0 transactions. Swaps are legacy transactions when
+	// none of their accounts are in a table.
+	LookupTables []string
+
+	// JupiterEndpoint is the Jupiter v6 swap API URL, e.g. https://quote-api.jup.ag/v6.
+	// Swaps take the better of the Raydium and Jupiter routes when it is set,
+	// and only use Raydium otherwise.
+	JupiterEndpoint string
+
+	Transactions             blockchain.TransactionStore // Saves sent transactions on every status change
+	ConfirmationPollInterval time.Duration               // How often sent transactions are checked (default 2s)
+}
+
+// withDefaults fills in the unset options
+func (o ProviderOptions) withDefaults() (ProviderOptions, error) {
+	if o.RPCEndpoint == "" {
+		o.RPCEndpoint = mainnetRPCEndpoint
+		if o.IsDevnet {
+			o.RPCEndpoint = devnetRPCEndpoint
+		}
+	}
+
+	if o.WSEndpoint == "" {
+		wsEndpoint, err := wsEndpointFor(o.RPCEndpoint)
+		if err != nil {
+			return o, err
+		}
+		o.WSEndpoint = wsEndpoint
+	}
+
+	switch o.Commitment {
+	case "":
+		o.Commitment = rpc.CommitmentFinalized
+	case rpc.CommitmentProcessed, rpc.CommitmentConfirmed, rpc.CommitmentFinalized:
+	default:
+		return o, fmt.Errorf("invalid commitment %q: must be processed, confirmed or finalized", o.Commitment)
+	}
+
+	if o.PriorityFeePercentile == 0 {
+		o.PriorityFeePercentile = defaultPriorityFeePercentile
+	}
+	if o.PriorityFeePercentile < 0 || o.PriorityFeePercentile > 100 {
+		return o, fmt.Errorf("invalid priority fee percentile %d: must be between 1 and 100", o.PriorityFeePercentile)
+	}
+
+	if o.MaxComputeUnitPrice == 0 {
+		o.MaxComputeUnitPrice = defaultMaxComputeUnitPrice
+	}
+
+	if o.ConfirmationPollInterval <= 0 {
+		o.ConfirmationPollInterval = defaultConfirmationPollInterval
+	}
+
+	if o.HTTPClient == nil {
+		timeout := o.Timeout
+		if timeout <= 0 {
+			timeout = defaultRPCTimeout
+		}
+		o.HTTPClient = &http.Client{Timeout: timeout}
+	}
+
+	return o, nil
+}
+
+// wsHeader returns the custom headers in the form expected by the WebSocket dialer
+func (o ProviderOptions) wsHeader() http.Header {
+	if len(o.Headers) == 0 {
+		return nil
+	}
+
+	header := make(http.Header, len(o.Headers))
+	for k, v := range o.Headers {
+		header.Set(k, v)
+	}
+	return header
+}
+
+// wsEndpointFor derives the WebSocket URL from an RPC URL by switching the
+// scheme, and the port as well for a local test validator
+func wsEndpointFor(rpcEndpoint string) (string, error) {
+	u, err := url.Parse(rpcEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid RPC endpoint %q: %w", rpcEndpoint, err)
+	}
+
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	default:
+		return "", fmt.Errorf("invalid RPC endpoint %q: unsupported scheme %q", rpcEndpoint, u.Scheme)
+	}
+
+	if u.Port() == localRPCPort {
+		u.Host = net.JoinHostPort(u.Hostname(), localWSPort)
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,100 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSEndpointFor(t *testing.T) {
+	tests := []struct {
+		rpc  string
+		want string
+	}{
+		{"https://api.mainnet-beta.solana.com", "wss://api.mainnet-beta.solana.com"},
+		{"https://rpc.example.com/v1?api-key=secret", "wss://rpc.example.com/v1?api-key=secret"},
+		{"http://127.0.0.1:8899", "ws://127.0.0.1:8900"},
+		{"http://localhost:9000", "ws://localhost:9000"},
+	}
+
+	for _, tt := range tests {
+		got, err := wsEndpointFor(tt.rpc)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.rpc)
+	}
+
+	_, err := wsEndpointFor("ftp://example.com")
+	assert.Error(t, err)
+}
+
+func TestProviderOptionsDefaults(t *testing.T) {
+	opts, err := ProviderOptions{IsDevnet: true}.withDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, devnetRPCEndpoint, opts.RPCEndpoint)
+	assert.Equal(t, "wss://api.devnet.solana.com", opts.WSEndpoint)
+	assert.Equal(t, rpc.CommitmentFinalized, opts.Commitment)
+	assert.Equal(t, defaultRPCTimeout, opts.HTTPClient.Timeout)
+
+	opts, err = ProviderOptions{}.withDefaults()
+	require.NoError(t, err)
+	assert.Equal(t, mainnetRPCEndpoint, opts.RPCEndpoint)
+
+	_, err = ProviderOptions{Commitment: "finalised"}.withDefaults()
+	assert.ErrorContains(t, err, `invalid commitment "finalised"`)
+}
+
+func TestNewProviderWithOptionsUsesEndpoint(t *testing.T) {
+	var gotAuth string
+	var gotParams []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		gotAuth = r.Header.Get("Authorization")
+		gotParams = req.Params
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":{"context":{"slot":1},"value":1500000000}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProviderWithOptions(ProviderOptions{
+		RPCEndpoint: server.URL,
+		Commitment:  rpc.CommitmentConfirmed,
+		Headers:     map[string]string{"Authorization": "Bearer secret"},
+		LazyWS:      true,
+	})
+	require.NoError(t, err)
+	defer provider.Close()
+
+	balance, err := provider.GetBalance(context.Background(), "11111111111111111111111111111111")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500000000), balance.Value.Int64())
+	assert.Equal(t, "Bearer secret", gotAuth)
+	require.Len(t, gotParams, 2)
+	assert.JSONEq(t, `{"commitment":"confirmed"}`, string(gotParams[1]))
+}
+
+func TestNewProviderWithOptionsConnectsWebSocket(t *testing.T) {
+	// A plain HTTP server refuses the WebSocket upgrade
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := NewProviderWithOptions(ProviderOptions{RPCEndpoint: server.URL})
+	assert.Error(t, err)
+
+	provider, err := NewProviderWithOptions(ProviderOptions{RPCEndpoint: server.URL, LazyWS: true})
+	require.NoError(t, err)
+	defer provider.Close()
+
+	_, err = provider.ws(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,69 @@
+package solana
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// defaultPriorityFeePercentile is the percentile of recent fees paid by swaps
+	defaultPriorityFeePercentile = 75
+
+	// defaultMaxComputeUnitPrice caps estimated priority fees during fee
+	// spikes, in micro-lamports per compute unit: 40,000 lamports (0.00004 SOL)
+	// for the default swap compute limit
+	defaultMaxComputeUnitPrice = 200_000
+)
+
+// priorityFees prices compute units from the prioritization fees recently
+// paid by transactions writing the same accounts
+type priorityFees struct {
+	rpcClient  *rpc.Client
+	percentile int
+	maxPrice   uint64
+}
+
+// computeUnitPrice returns override when set. Otherwise it returns the
+// percentile of the fees recently paid to write accounts, between the default
+// price and the maximum, or the default price when recent fees can't be read.
+func (f *priorityFees) computeUnitPrice(ctx context.Context, override uint64, accounts ...solana.PublicKey) uint64 {
+	if override > 0 {
+		return override
+	}
+	if f == nil {
+		return defaultComputeUnitPrice
+	}
+
+	// Fees are best effort: a swap is still worth sending at the default price
+	fees, err := f.rpcClient.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil || len(fees) == 0 {
+		return defaultComputeUnitPrice
+	}
+
+	price := feePercentile(fees, f.percentile)
+	if price < defaultComputeUnitPrice {
+		return defaultComputeUnitPrice
+	}
+	if price > f.maxPrice {
+		return f.maxPrice
+	}
+	return price
+}
+
+// feePercentile returns the nearest-rank percentile of the fees
+func feePercentile(fees []rpc.PriorizationFeeResult, percentile int) uint64 {
+	prices := make([]uint64, len(fees))
+	for i, fee := range fees {
+		prices[i] = fee.PrioritizationFee
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	rank := (percentile*len(prices) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return prices[rank-1]
+}
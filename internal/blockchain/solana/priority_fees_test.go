@@ -0,0 +1,101 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeUnitPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		fees     []uint64
+		override uint64
+		want     uint64
+	}{
+		{name: "75th percentile", fees: []uint64{0, 5_000, 20_000, 8_000}, want: 8_000},
+		{name: "below the default", fees: []uint64{0, 0, 10}, want: defaultComputeUnitPrice},
+		{name: "capped", fees: []uint64{900_000, 1_000_000}, want: 50_000},
+		{name: "no recent fees", want: defaultComputeUnitPrice},
+		{name: "override", fees: []uint64{5_000}, override: 123, want: 123},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accounts [][]string
+			server := newRPCServer(t, func(req rpcRequest) interface{} {
+				require.Equal(t, "getRecentPrioritizationFees", req.Method)
+				var keys []string
+				require.NoError(t, json.Unmarshal(req.Params[0], &keys))
+				accounts = append(accounts, keys)
+
+				result := []interface{}{}
+				for i, fee := range tt.fees {
+					result = append(result, map[string]interface{}{"slot": i, "prioritizationFee": fee})
+				}
+				return result
+			})
+
+			fees := &priorityFees{rpcClient: rpc.New(server.URL), percentile: 75, maxPrice: 50_000}
+			price := fees.computeUnitPrice(context.Background(), tt.override, solUSDCAmmID)
+			assert.Equal(t, tt.want, price)
+
+			if tt.override != 0 {
+				assert.Empty(t, accounts, "an override needs no estimate")
+				return
+			}
+			assert.Equal(t, [][]string{{solUSDCAmmID.String()}}, accounts)
+		})
+	}
+
+	// Fees that can't be read fall back to the default price
+	fees := &priorityFees{rpcClient: rpc.New("http://127.0.0.1:1"), percentile: 75, maxPrice: 50_000}
+	assert.Equal(t, uint64(defaultComputeUnitPrice), fees.computeUnitPrice(context.Background(), 0))
+
+	var none *priorityFees
+	assert.Equal(t, uint64(defaultComputeUnitPrice), none.computeUnitPrice(context.Background(), 0))
+}
+
+func TestBuildSwapPriorityFee(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	var sent []solana.Transaction
+	provider := newTestProvider(t, newSwapRPCServer(t, owner, &sent), nil)
+
+	tests := []struct {
+		name        string
+		priorityFee uint64
+		want        uint64
+	}{
+		{name: "estimated", want: 8_000},
+		{name: "override", priorityFee: 30_000, want: 30_000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			built, err := provider.BuildSwap(context.Background(), blockchain.BuildSwapRequest{
+				Type:          blockchain.TransactionTypeBuy,
+				WalletAddress: owner.String(),
+				TokenAddress:  usdcMint.String(),
+				Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+				PriorityFee:   tt.priorityFee,
+			})
+			require.NoError(t, err)
+
+			var tx solana.Transaction
+			require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+
+			// SetComputeUnitPrice is instruction 3 of the compute budget program
+			price := tx.Message.Instructions[1]
+			require.Len(t, price.Data, 9)
+			assert.Equal(t, byte(3), price.Data[0])
+			assert.Equal(t, tt.want, binary.LittleEndian.Uint64(price.Data[1:]))
+		})
+	}
+}
@@ -5,59 +5,150 @@ import (
 	"fmt"
 	"math/big"
 	"meme-trader/internal/blockchain"
-	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
 	"github.com/gagliardetto/solana-go/rpc/ws"
 )
 
 const (
-	// Solana mainnet RPC endpoints (use your preferred endpoint)
+	// Public Solana RPC endpoints, used when no endpoint is configured
 	mainnetRPCEndpoint = "https://api.mainnet-beta.solana.com"
-	mainnetWSEndpoint  = "wss://api.mainnet-beta.solana.com"
-
-	// Solana devnet RPC endpoints (for testing)
-	devnetRPCEndpoint = "https://api.devnet.solana.com"
-	devnetWSEndpoint  = "wss://api.devnet.solana.com"
+	devnetRPCEndpoint  = "https://api.devnet.solana.com"
 
 	// Default decimals for SOL
 	solDecimals = 9
+
+	// Every transaction of a history page costs a getTransaction call, so
+	// pages are kept small
+	defaultTransactionsPageSize = 20
+	maxTransactionsPageSize     = 100
+
+	// transactionFetchConcurrency bounds the getTransaction calls made at once
+	transactionFetchConcurrency = 8
 )
 
 type Provider struct {
 	rpcClient     *rpc.Client
-	wsClient      *ws.Client
 	raydiumClient *RaydiumClient
+	jupiterClient *JupiterClient // Nil when swaps only use Raydium
+	pumpFunClient *PumpFunClient
+	fees          *priorityFees
+	signer        blockchain.Signer
 	network       blockchain.Network
 	isDevnet      bool
+	commitment    rpc.CommitmentType
+	tracker       *confirmationTracker
+
+	wsEndpoint string
+	wsOptions  *ws.Options
+	wsClient   *ws.Client
+	wsMu       sync.Mutex
 }
 
-// NewProvider creates a new Solana provider
+// NewProvider creates a new Solana provider for the public mainnet or devnet endpoint
 func NewProvider(isDevnet bool) (*Provider, error) {
-	rpcEndpoint := mainnetRPCEndpoint
-	wsEndpoint := mainnetWSEndpoint
-	if isDevnet {
-		rpcEndpoint = devnetRPCEndpoint
-		wsEndpoint = devnetWSEndpoint
-	}
+	return NewProviderWithOptions(ProviderOptions{IsDevnet: isDevnet})
+}
 
-	rpcClient := rpc.New(rpcEndpoint)
-	wsClient, err := ws.Connect(context.Background(), wsEndpoint)
+// NewProviderWithOptions creates a new Solana provider with custom endpoints and transport
+func NewProviderWithOptions(opts ProviderOptions) (*Provider, error) {
+	opts, err := opts.withDefaults()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to websocket: %w", err)
+		return nil, err
 	}
 
-	raydiumClient := NewRaydiumClient(rpcClient, isDevnet)
+	rpcClient := rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(opts.RPCEndpoint, &jsonrpc.RPCClientOpts{
+		HTTPClient:    opts.HTTPClient,
+		CustomHeaders: opts.Headers,
+	}))
+
+	fees := &priorityFees{
+		rpcClient:  rpcClient,
+		percentile: opts.PriorityFeePercentile,
+		maxPrice:   opts.MaxComputeUnitPrice,
+	}
 
-	return &Provider{
+	tables := make([]solana.PublicKey, len(opts.LookupTables))
+	for i, table := range opts.LookupTables {
+		if tables[i], err = solana.PublicKeyFromBase58(table); err != nil {
+			return nil, fmt.Errorf("invalid lookup table %q: %w", table, err)
+		}
+	}
+	lookups := newLookupTables(rpcClient, tables)
+
+	raydiumClient := NewRaydiumClient(rpcClient, opts.IsDevnet)
+	raydiumClient.fees = fees
+	raydiumClient.lookups = lookups
+
+	pumpFunClient := NewPumpFunClient(rpcClient)
+	pumpFunClient.fees = fees
+	pumpFunClient.lookups = lookups
+
+	p := &Provider{
 		rpcClient:     rpcClient,
-		wsClient:      wsClient,
 		raydiumClient: raydiumClient,
+		pumpFunClient: pumpFunClient,
+		fees:          fees,
+		signer:        opts.Signer,
 		network:       blockchain.NetworkSolana,
-		isDevnet:      isDevnet,
-	}, nil
+		isDevnet:      opts.IsDevnet,
+		commitment:    opts.Commitment,
+		wsEndpoint:    opts.WSEndpoint,
+		wsOptions: &ws.Options{
+			HttpHeader:       opts.wsHeader(),
+			HandshakeTimeout: opts.HTTPClient.Timeout,
+		},
+	}
+
+	if opts.JupiterEndpoint != "" {
+		p.jupiterClient = NewJupiterClient(opts.JupiterEndpoint, opts.HTTPClient)
+	}
+
+	p.tracker = newConfirmationTracker(rpcClient, p.ws, opts.Transactions, opts.ConfirmationPollInterval)
+
+	if !opts.LazyWS {
+		if _, err := p.ws(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// ws returns the WebSocket client, connecting on first use
+func (p *Provider) ws(ctx context.Context) (*ws.Client, error) {
+	p.wsMu.Lock()
+	defer p.wsMu.Unlock()
+
+	if p.wsClient != nil {
+		return p.wsClient, nil
+	}
+
+	wsClient, err := ws.ConnectWithOptions(ctx, p.wsEndpoint, p.wsOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to websocket: %w", err)
+	}
+	p.wsClient = wsClient
+	return wsClient, nil
+}
+
+// Close stops tracking sent transactions and closes the WebSocket connection,
+// if any, and the RPC client
+func (p *Provider) Close() error {
+	p.tracker.stop()
+
+	p.wsMu.Lock()
+	if p.wsClient != nil {
+		p.wsClient.Close()
+		p.wsClient = nil
+	}
+	p.wsMu.Unlock()
+
+	return p.rpcClient.Close()
 }
 
 func (p *Provider) Network() blockchain.Network {
@@ -91,7 +182,7 @@ func (p *Provider) CreateWallet(ctx context.Context) (*blockchain.Wallet, error)
 
 func (p *Provider) GetWallet(ctx context.Context, address string) (*blockchain.Wallet, error) {
 	if !p.IsValidAddress(address) {
-		return nil, fmt.Errorf("invalid Solana address: %s", address)
+		return nil, blockchain.NewValidationError("invalid Solana address: %s", address)
 	}
 
 	// In a real implementation, you would fetch this from your database
@@ -109,13 +200,13 @@ func (p *Provider) GetWallet(ctx context.Context, address string) (*blockchain.W
 func (p *Provider) GetBalance(ctx context.Context, address string) (blockchain.Amount, error) {
 	pubKey, err := solana.PublicKeyFromBase58(address)
 	if err != nil {
-		return blockchain.Amount{}, fmt.Errorf("invalid address: %w", err)
+		return blockchain.Amount{}, blockchain.NewValidationError("invalid address: %w", err)
 	}
 
 	balance, err := p.rpcClient.GetBalance(
 		ctx,
 		pubKey,
-		rpc.CommitmentFinalized,
+		p.commitment,
 	)
 	if err != nil {
 		return blockchain.Amount{}, fmt.Errorf("failed to get balance: %w", err)
@@ -127,110 +218,195 @@ func (p *Provider) GetBalance(ctx context.Context, address string) (blockchain.A
 	}, nil
 }
 
+// Quote quotes swapping an exact amount of one token for another
+func (p *Provider) Quote(ctx context.Context, req blockchain.QuoteRequest) (*blockchain.SwapQuote, error) {
+	inputMint, err := solana.PublicKeyFromBase58(req.InputMint)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid input mint: %w", err)
+	}
+	outputMint, err := solana.PublicKeyFromBase58(req.OutputMint)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid output mint: %w", err)
+	}
+
+	return p.bestQuote(ctx, inputMint, outputMint, req.Amount, blockchain.Amount{}, req.SlippageBps)
+}
+
 func (p *Provider) Buy(ctx context.Context, req blockchain.BuyRequest) (*blockchain.Transaction, error) {
 	if !p.IsValidAddress(req.WalletAddress) {
-		return nil, fmt.Errorf("invalid wallet address")
+		return nil, blockchain.NewValidationError("invalid wallet address")
 	}
-	if !p.IsValidAddress(req.TokenAddress) {
-		return nil, fmt.Errorf("invalid token address")
+	token, err := solana.PublicKeyFromBase58(req.TokenAddress)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid token address")
 	}
 
-	// Use Raydium to execute the swap
 	swapReq := SwapRequest{
 		FromAddress:      req.WalletAddress,
 		ToAddress:        req.TokenAddress,
 		TokenAddress:     req.TokenAddress,
 		Amount:           req.Amount,
 		MinimumAmountOut: req.MaxPrice,
+		SlippageBps:      req.SlippageBps,
+		ComputeUnitPrice: req.PriorityFee,
 		Type:             blockchain.TransactionTypeBuy,
 		Timestamp:        time.Now().Unix(),
 	}
 
-	return p.raydiumClient.SwapTokens(ctx, swapReq)
+	// Without a quote the swap takes the route with the best output
+	return p.swap(ctx, swapReq, req.Quote, solana.SolMint, token)
 }
 
 func (p *Provider) Sell(ctx context.Context, req blockchain.SellRequest) (*blockchain.Transaction, error) {
 	if !p.IsValidAddress(req.WalletAddress) {
-		return nil, fmt.Errorf("invalid wallet address")
+		return nil, blockchain.NewValidationError("invalid wallet address")
 	}
-	if !p.IsValidAddress(req.TokenAddress) {
-		return nil, fmt.Errorf("invalid token address")
+	token, err := solana.PublicKeyFromBase58(req.TokenAddress)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid token address")
 	}
 
-	// Use Raydium to execute the swap
 	swapReq := SwapRequest{
 		FromAddress:      req.WalletAddress,
 		ToAddress:        req.TokenAddress,
 		TokenAddress:     req.TokenAddress,
 		Amount:           req.Amount,
 		MinimumAmountOut: req.MinPrice,
+		SlippageBps:      req.SlippageBps,
+		ComputeUnitPrice: req.PriorityFee,
 		Type:             blockchain.TransactionTypeSell,
 		Timestamp:        time.Now().Unix(),
 	}
 
-	return p.raydiumClient.SwapTokens(ctx, swapReq)
+	// Without a quote the swap takes the route with the best output
+	return p.swap(ctx, swapReq, req.Quote, token, solana.SolMint)
 }
 
 func (p *Provider) GetTransaction(ctx context.Context, txID string) (*blockchain.Transaction, error) {
 	signature, err := solana.SignatureFromBase58(txID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid transaction ID: %w", err)
+		return nil, blockchain.NewValidationError("invalid transaction ID: %w", err)
+	}
+
+	return p.getTransaction(ctx, signature, solana.PublicKey{})
+}
+
+// GetTransactions returns a page of the history of address, newest first.
+// Transactions that can't be fetched or decoded are reported in the page
+// rather than failing it.
+func (p *Provider) GetTransactions(ctx context.Context, address string, req blockchain.TransactionsRequest) (*blockchain.TransactionPage, error) {
+	pubKey, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid address: %w", err)
 	}
 
-	opts := &rpc.GetTransactionOpts{
-		Commitment: rpc.CommitmentFinalized,
+	opts := &rpc.GetSignaturesForAddressOpts{
+		Commitment: p.getTransactionOpts().Commitment,
 	}
+	if req.Before != "" {
+		if opts.Before, err = solana.SignatureFromBase58(req.Before); err != nil {
+			return nil, blockchain.NewValidationError("invalid before signature: %w", err)
+		}
+	}
+	if req.Until != "" {
+		if opts.Until, err = solana.SignatureFromBase58(req.Until); err != nil {
+			return nil, blockchain.NewValidationError("invalid until signature: %w", err)
+		}
+	}
+
+	limit := req.Limit
+	switch {
+	case limit <= 0:
+		limit = defaultTransactionsPageSize
+	case limit > maxTransactionsPageSize:
+		limit = maxTransactionsPageSize
+	}
+	opts.Limit = &limit
 
-	tx, err := p.rpcClient.GetTransaction(ctx, signature, opts)
+	signatures, err := p.rpcClient.GetSignaturesForAddressWithOpts(ctx, pubKey, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transaction: %w", err)
+		return nil, fmt.Errorf("failed to get signatures: %w", err)
 	}
 
-	// Convert Solana transaction to our generic Transaction type
-	// This is a simplified conversion
-	status := blockchain.TransactionStatusConfirmed
-	if tx.Meta != nil && tx.Meta.Err != nil {
-		status = blockchain.TransactionStatusFailed
+	page := p.fetchTransactions(ctx, signatures, pubKey)
+	if len(signatures) == limit {
+		page.NextBefore = signatures[len(signatures)-1].Signature.String()
 	}
+	return page, nil
+}
 
-	return &blockchain.Transaction{
-		ID:            txID,
-		Network:       p.network,
-		Status:        status,
-		BlockHash:     strconv.FormatUint(uint64(tx.Slot), 10), // Use slot as block hash since we don't have access to the actual block hash
-		BlockNumber:   uint64(tx.Slot),
-		Signature:     txID,
-		Timestamp:     time.Now().Unix(), // You should get this from the block
-		CreatedAt:     time.Now().Unix(),
-		LastUpdatedAt: time.Now().Unix(),
-	}, nil
+// fetchTransactions fetches and decodes the transactions of signatures as
+// seen by wallet, a few at a time, keeping their order
+func (p *Provider) fetchTransactions(ctx context.Context, signatures []*rpc.TransactionSignature, wallet solana.PublicKey) *blockchain.TransactionPage {
+	transactions := make([]*blockchain.Transaction, len(signatures))
+	errs := make([]error, len(signatures))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, transactionFetchConcurrency)
+	for i, sig := range signatures {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, signature solana.Signature) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			transactions[i], errs[i] = p.getTransaction(ctx, signature, wallet)
+		}(i, sig.Signature)
+	}
+	wg.Wait()
+
+	page := &blockchain.TransactionPage{
+		Transactions: make([]blockchain.Transaction, 0, len(signatures)),
+	}
+	for i, sig := range signatures {
+		if errs[i] != nil {
+			page.Failed = append(page.Failed, blockchain.FailedTransaction{
+				Signature: sig.Signature.String(),
+				Error:     errs[i].Error(),
+			})
+			continue
+		}
+		page.Transactions = append(page.Transactions, *transactions[i])
+	}
+	return page
 }
 
-func (p *Provider) GetTransactions(ctx context.Context, address string, limit int) ([]blockchain.Transaction, error) {
-	pubKey, err := solana.PublicKeyFromBase58(address)
+// getTransaction fetches and decodes a transaction as seen by wallet, or by
+// its fee payer when wallet is empty
+func (p *Provider) getTransaction(ctx context.Context, signature solana.Signature, wallet solana.PublicKey) (*blockchain.Transaction, error) {
+	result, err := p.rpcClient.GetTransaction(ctx, signature, p.getTransactionOpts())
 	if err != nil {
-		return nil, fmt.Errorf("invalid address: %w", err)
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
 	}
 
-	signatures, err := p.rpcClient.GetSignaturesForAddress(ctx, pubKey)
+	tx, err := decodeTransaction(signature.String(), result, wallet)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get signatures: %w", err)
+		return nil, err
 	}
 
-	var transactions []blockchain.Transaction
-	for i, sig := range signatures {
-		if i >= limit {
-			break
-		}
+	// Transactions read at the finalized commitment can't be rolled back
+	if tx.Status == blockchain.TransactionStatusConfirmed && p.commitment == rpc.CommitmentFinalized {
+		tx.Status = blockchain.TransactionStatusFinalized
+	}
+	return tx, nil
+}
 
-		tx, err := p.GetTransaction(ctx, sig.Signature.String())
-		if err != nil {
-			continue // Skip failed transactions
-		}
-		transactions = append(transactions, *tx)
+// getTransactionOpts returns the options of getTransaction calls: binary
+// encoding, so that the transaction can be decoded, and versioned transactions
+func (p *Provider) getTransactionOpts() *rpc.GetTransactionOpts {
+	// getTransaction does not accept the processed commitment
+	commitment := p.commitment
+	if commitment == rpc.CommitmentProcessed {
+		commitment = rpc.CommitmentConfirmed
 	}
 
-	return transactions, nil
+	maxVersion := uint64(0)
+	return &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     commitment,
+		MaxSupportedTransactionVersion: &maxVersion,
+	}
 }
 
 func (p *Provider) GetTopMemeCoins(ctx context.Context, req blockchain.TopMemeCoinsRequest) ([]blockchain.MemeCoin, error) {
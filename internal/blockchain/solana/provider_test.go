@@ -7,22 +7,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 )
 
-// MockRaydiumClient is a mock implementation of the Raydium client
-type MockRaydiumClient struct {
-	mock.Mock
-}
-
-func (m *MockRaydiumClient) SwapTokens(ctx context.Context, req SwapRequest) (*blockchain.Transaction, error) {
-	args := m.Called(ctx, req)
-	if args.Get(0) == nil {
-		return nil, args.Error(1)
-	}
-	return args.Get(0).(*blockchain.Transaction), args.Error(1)
-}
-
 func TestNewProvider(t *testing.T) {
 	provider, err := NewProvider(true)
 	assert.NoError(t, err)
@@ -173,12 +159,12 @@ func TestGetTransactions(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test valid address
-	txs, err := provider.GetTransactions(context.Background(), "11111111111111111111111111111111", 10)
+	page, err := provider.GetTransactions(context.Background(), "11111111111111111111111111111111", blockchain.TransactionsRequest{Limit: 10})
 	assert.NoError(t, err)
-	assert.NotNil(t, txs)
+	assert.NotNil(t, page)
 
 	// Test invalid address
-	txs, err = provider.GetTransactions(context.Background(), "invalid", 10)
+	page, err = provider.GetTransactions(context.Background(), "invalid", blockchain.TransactionsRequest{Limit: 10})
 	assert.Error(t, err)
-	assert.Nil(t, txs)
+	assert.Nil(t, page)
 }
@@ -0,0 +1,359 @@
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// pumpFunTokenDecimals is the decimals of every token launched on Pump.fun
+const pumpFunTokenDecimals = 6
+
+// Offsets into the Pump.fun BondingCurve account, after the discriminator
+const (
+	curveVirtualTokenReservesOffset = 8
+	curveVirtualSolReservesOffset   = 16
+	curveRealTokenReservesOffset    = 24
+	curveRealSolReservesOffset      = 32
+	curveCompleteOffset             = 48
+	bondingCurveSize                = 49
+)
+
+// Offsets into the Pump.fun Global account, after the discriminator
+const (
+	globalFeeRecipientOffset   = 41
+	globalFeeBasisPointsOffset = 105
+	pumpFunGlobalSize          = 113
+)
+
+var (
+	// Anchor discriminators of the Pump.fun accounts and instructions
+	bondingCurveDiscriminator = []byte{23, 183, 248, 55, 96, 216, 172, 96}
+	pumpFunBuyDiscriminator   = []byte{102, 6, 61, 18, 1, 218, 235, 234}
+	pumpFunSellDiscriminator  = []byte{51, 230, 133, 164, 1, 127, 131, 173}
+
+	pumpFunGlobal         = mustFindProgramAddress([]byte("global"))
+	pumpFunEventAuthority = mustFindProgramAddress([]byte("__event_authority"))
+)
+
+// PumpFunClient trades tokens on their Pump.fun bonding curve, until the
+// curve completes and the token graduates to Raydium
+type PumpFunClient struct {
+	rpcClient *rpc.Client
+	fees      *priorityFees // Default priority fee when nil
+	lookups   *lookupTables // Swaps are legacy transactions when nil
+}
+
+// NewPumpFunClient creates a new Pump.fun client
+func NewPumpFunClient(rpcClient *rpc.Client) *PumpFunClient {
+	return &PumpFunClient{rpcClient: rpcClient}
+}
+
+// bondingCurveState is the trading state of a Pump.fun bonding curve. The
+// curve prices trades with the constant product of its virtual reserves, and
+// can sell at most its real token reserves.
+type bondingCurveState struct {
+	mint                 solana.PublicKey
+	address              solana.PublicKey
+	virtualTokenReserves *big.Int
+	virtualSolReserves   *big.Int
+	realTokenReserves    *big.Int
+	realSolReserves      *big.Int
+	complete             bool
+	feeRecipient         solana.PublicKey
+	feeBasisPoints       *big.Int
+}
+
+// bondingCurveAddress derives the bonding curve account of mint
+func bondingCurveAddress(mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{[]byte("bonding-curve"), mint.Bytes()}, pumpFunProgramID)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive bonding curve of %s: %w", mint, err)
+	}
+	return address, nil
+}
+
+// mustFindProgramAddress derives a Pump.fun account from constant seeds
+func mustFindProgramAddress(seed []byte) solana.PublicKey {
+	address, _, err := solana.FindProgramAddress([][]byte{seed}, pumpFunProgramID)
+	if err != nil {
+		panic(err)
+	}
+	return address
+}
+
+// fetchCurve reads the bonding curve of mint and the program's global state
+// in a single getMultipleAccounts call. It returns nil when mint was not
+// launched on Pump.fun.
+func (c *PumpFunClient) fetchCurve(ctx context.Context, mint solana.PublicKey) (*bondingCurveState, error) {
+	address, err := bondingCurveAddress(mint)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.rpcClient.GetMultipleAccounts(ctx, address, pumpFunGlobal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bonding curve accounts: %w", err)
+	}
+	if len(result.Value) != 2 {
+		return nil, fmt.Errorf("expected 2 bonding curve accounts, got %d", len(result.Value))
+	}
+
+	curve, global := result.Value[0], result.Value[1]
+	if curve == nil || curve.Data == nil || !curve.Owner.Equals(pumpFunProgramID) {
+		return nil, nil
+	}
+	if global == nil || global.Data == nil {
+		return nil, fmt.Errorf("pump.fun global account %s not found", pumpFunGlobal)
+	}
+	return decodeBondingCurve(mint, address, curve.Data.GetBinary(), global.Data.GetBinary())
+}
+
+// decodeBondingCurve decodes a bonding curve account and the global account
+// holding the trading fee
+func decodeBondingCurve(mint, address solana.PublicKey, curve, global []byte) (*bondingCurveState, error) {
+	if len(curve) < bondingCurveSize || !bytes.Equal(curve[:8], bondingCurveDiscriminator) {
+		return nil, fmt.Errorf("account %s is not a bonding curve", address)
+	}
+	if len(global) < pumpFunGlobalSize {
+		return nil, fmt.Errorf("invalid pump.fun global account size: %d", len(global))
+	}
+
+	state := &bondingCurveState{
+		mint:                 mint,
+		address:              address,
+		virtualTokenReserves: readUint64(curve, curveVirtualTokenReservesOffset),
+		virtualSolReserves:   readUint64(curve, curveVirtualSolReservesOffset),
+		realTokenReserves:    readUint64(curve, curveRealTokenReservesOffset),
+		realSolReserves:      readUint64(curve, curveRealSolReservesOffset),
+		complete:             curve[curveCompleteOffset] != 0,
+		feeRecipient:         solana.PublicKeyFromBytes(global[globalFeeRecipientOffset : globalFeeRecipientOffset+32]),
+		feeBasisPoints:       readUint64(global, globalFeeBasisPointsOffset),
+	}
+	if state.feeBasisPoints.Cmp(big.NewInt(basisPoints)) >= 0 {
+		return nil, fmt.Errorf("invalid pump.fun fee of %s basis points", state.feeBasisPoints)
+	}
+	if !state.complete && (state.virtualTokenReserves.Sign() == 0 || state.virtualSolReserves.Sign() == 0) {
+		return nil, fmt.Errorf("bonding curve %s has no liquidity", address)
+	}
+	return state, nil
+}
+
+// Quote quotes swapping exactly amount of inputMint for outputMint on the
+// bonding curve of the token. It returns nil when the token is not trading on
+// a bonding curve, either because it was not launched on Pump.fun or because
+// its curve completed and it now trades on Raydium.
+func (c *PumpFunClient) Quote(ctx context.Context, inputMint, outputMint solana.PublicKey, amount blockchain.Amount, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	token := outputMint
+	if !inputMint.Equals(solana.SolMint) {
+		token = inputMint
+		if !outputMint.Equals(solana.SolMint) {
+			return nil, nil
+		}
+	}
+
+	state, err := c.fetchCurve(ctx, token)
+	if err != nil || state == nil || state.complete {
+		return nil, err
+	}
+
+	if slippageBps == 0 {
+		slippageBps = defaultSlippageBps
+	}
+	return state.quoteExactIn(inputMint, amount.Value, slippageBps)
+}
+
+// quoteExactIn quotes a trade spending exactly amountIn of inputMint. Buys pay
+// the fee on top of the SOL going into the curve, sells out of the SOL coming
+// out of it, so the fee of both is in SOL.
+func (s *bondingCurveState) quoteExactIn(inputMint solana.PublicKey, amountIn *big.Int, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return nil, blockchain.NewValidationError("amount must be greater than zero")
+	}
+	if slippageBps > basisPoints {
+		return nil, blockchain.NewValidationError("slippage must be at most %d basis points", basisPoints)
+	}
+
+	bps := big.NewInt(basisPoints)
+	quote := &blockchain.SwapQuote{
+		InputMint:   inputMint.String(),
+		AmountIn:    blockchain.Amount{Value: new(big.Int).Set(amountIn), Decimals: solDecimals},
+		SlippageBps: slippageBps,
+		Pool:        s.address.String(),
+		Route:       pumpFunRoute,
+	}
+
+	var amountOut, priceImpact *big.Int
+	var outDecimals uint8
+	switch {
+	case inputMint.Equals(solana.SolMint):
+		quote.OutputMint = s.mint.String()
+		outDecimals = pumpFunTokenDecimals
+
+		// amountIn = solIn + solIn * fee / 10000
+		solIn := new(big.Int).Mul(amountIn, bps)
+		solIn.Quo(solIn, new(big.Int).Add(bps, s.feeBasisPoints))
+		quote.Fee = blockchain.Amount{Value: new(big.Int).Sub(amountIn, solIn), Decimals: solDecimals}
+
+		// tokensOut = virtualTokens - (virtualSol * virtualTokens / (virtualSol + solIn) + 1)
+		reserveAfter := new(big.Int).Add(s.virtualSolReserves, solIn)
+		remaining := new(big.Int).Mul(s.virtualSolReserves, s.virtualTokenReserves)
+		remaining.Quo(remaining, reserveAfter).Add(remaining, big.NewInt(1))
+		amountOut = new(big.Int).Sub(s.virtualTokenReserves, remaining)
+		if amountOut.Cmp(s.realTokenReserves) > 0 {
+			amountOut.Set(s.realTokenReserves)
+		}
+
+		priceImpact = new(big.Int).Mul(solIn, bps)
+		priceImpact.Quo(priceImpact, reserveAfter)
+
+	case inputMint.Equals(s.mint):
+		quote.OutputMint = solana.SolMint.String()
+		quote.AmountIn.Decimals = pumpFunTokenDecimals
+		outDecimals = solDecimals
+
+		// solOut = virtualSol * tokensIn / (virtualTokens + tokensIn)
+		reserveAfter := new(big.Int).Add(s.virtualTokenReserves, amountIn)
+		solOut := new(big.Int).Mul(s.virtualSolReserves, amountIn)
+		solOut.Quo(solOut, reserveAfter)
+		if solOut.Cmp(s.realSolReserves) > 0 {
+			solOut.Set(s.realSolReserves)
+		}
+
+		// fee = ceil(solOut * fee / 10000)
+		fee := new(big.Int).Mul(solOut, s.feeBasisPoints)
+		fee.Add(fee, big.NewInt(basisPoints-1)).Quo(fee, bps)
+		quote.Fee = blockchain.Amount{Value: fee, Decimals: solDecimals}
+		amountOut = new(big.Int).Sub(solOut, fee)
+
+		priceImpact = new(big.Int).Mul(amountIn, bps)
+		priceImpact.Quo(priceImpact, reserveAfter)
+
+	default:
+		return nil, blockchain.NewValidationError("bonding curve %s does not trade %s", s.address, inputMint)
+	}
+
+	if amountOut.Sign() <= 0 {
+		return nil, blockchain.NewValidationError("amount is too small to receive any %s", quote.OutputMint)
+	}
+
+	minimumAmountOut := new(big.Int).Mul(amountOut, big.NewInt(int64(basisPoints-int(slippageBps))))
+	minimumAmountOut.Quo(minimumAmountOut, bps)
+
+	quote.ExpectedAmountOut = blockchain.Amount{Value: amountOut, Decimals: outDecimals}
+	quote.MinimumAmountOut = blockchain.Amount{Value: minimumAmountOut, Decimals: outDecimals}
+	quote.PriceImpactBps = priceImpact.Int64()
+	return quote, nil
+}
+
+// buildSwapTransaction builds the unsigned bonding curve trade of req, quoted
+// by quote, buying the token when inputMint is SOL and selling it otherwise.
+// Buys are priced in tokens, so a buy receives exactly the minimum amount out
+// and spends at most the amount.
+func (c *PumpFunClient) buildSwapTransaction(ctx context.Context, req SwapRequest, quote *blockchain.SwapQuote, inputMint, outputMint solana.PublicKey) (*swapTransaction, error) {
+	owner, err := solana.PublicKeyFromBase58(req.FromAddress)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid from address: %w", err)
+	}
+
+	amount, err := amountToUint64(req.Amount)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid amount: %w", err)
+	}
+	minimumAmountOut, err := amountToUint64(req.MinimumAmountOut)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid minimum amount out: %w", err)
+	}
+
+	isBuy := inputMint.Equals(solana.SolMint)
+	mint := outputMint
+	if !isBuy {
+		mint = inputMint
+	}
+
+	// The curve may have completed since the quote
+	state, err := c.fetchCurve(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.complete {
+		return nil, blockchain.NewValidationError("%s is no longer trading on its bonding curve", mint)
+	}
+
+	userAccount, createUserAccount, err := tokenAccount(owner, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token account: %w", err)
+	}
+
+	// The priority fee follows what recent trades paid to write the curve
+	price := c.fees.computeUnitPrice(ctx, req.ComputeUnitPrice, state.address)
+	instructions := computeBudgetInstructions(defaultSwapComputeUnits, price)
+	if isBuy {
+		if minimumAmountOut == 0 {
+			return nil, blockchain.NewValidationError("minimum amount out is required to buy on a bonding curve")
+		}
+		instructions = append(instructions,
+			createUserAccount,
+			state.buildTradeInstruction(pumpFunBuyDiscriminator, owner, userAccount, minimumAmountOut, amount),
+		)
+	} else {
+		instructions = append(instructions, state.buildTradeInstruction(pumpFunSellDiscriminator, owner, userAccount, amount, minimumAmountOut))
+	}
+
+	latest, err := c.rpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := newSwapTransaction(ctx, c.lookups, instructions, latest.Value.Blockhash, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &swapTransaction{
+		tx:                   tx,
+		quote:                quote,
+		inputMint:            inputMint,
+		outputMint:           outputMint,
+		blockhash:            latest.Value.Blockhash,
+		lastValidBlockHeight: latest.Value.LastValidBlockHeight,
+	}, nil
+}
+
+// buildTradeInstruction builds a Pump.fun buy or sell. A buy takes the tokens
+// to receive and the most SOL to spend, a sell the tokens to spend and the
+// least SOL to receive.
+func (s *bondingCurveState) buildTradeInstruction(discriminator []byte, owner, userAccount solana.PublicKey, tokenAmount, solLimit uint64) solana.Instruction {
+	data := make([]byte, 24)
+	copy(data, discriminator)
+	binary.LittleEndian.PutUint64(data[8:], tokenAmount)
+	binary.LittleEndian.PutUint64(data[16:], solLimit)
+
+	curveAccount, _, _ := solana.FindAssociatedTokenAddress(s.address, s.mint)
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(pumpFunGlobal),
+		solana.Meta(s.feeRecipient).WRITE(),
+		solana.Meta(s.mint),
+		solana.Meta(s.address).WRITE(),
+		solana.Meta(curveAccount).WRITE(),
+		solana.Meta(userAccount).WRITE(),
+		solana.Meta(owner).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	}
+	// Buys pass the rent sysvar after the token program, sells the
+	// associated token program before it
+	if bytes.Equal(discriminator, pumpFunBuyDiscriminator) {
+		accounts = append(accounts, solana.Meta(solana.TokenProgramID), solana.Meta(solana.SysVarRentPubkey))
+	} else {
+		accounts = append(accounts, solana.Meta(solana.SPLAssociatedTokenAccountProgramID), solana.Meta(solana.TokenProgramID))
+	}
+	accounts = append(accounts, solana.Meta(pumpFunEventAuthority), solana.Meta(pumpFunProgramID))
+
+	return solana.NewInstruction(pumpFunProgramID, accounts, data)
+}
@@ -0,0 +1,268 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pumpFunFeeRecipient receives the trading fees in the test global account
+var pumpFunFeeRecipient = solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM")
+
+// bondingCurveData returns a bonding curve account with the given reserves
+func bondingCurveData(virtualTokens, virtualSol, realTokens, realSol uint64, complete bool) []byte {
+	data := make([]byte, bondingCurveSize)
+	copy(data, bondingCurveDiscriminator)
+	binary.LittleEndian.PutUint64(data[curveVirtualTokenReservesOffset:], virtualTokens)
+	binary.LittleEndian.PutUint64(data[curveVirtualSolReservesOffset:], virtualSol)
+	binary.LittleEndian.PutUint64(data[curveRealTokenReservesOffset:], realTokens)
+	binary.LittleEndian.PutUint64(data[curveRealSolReservesOffset:], realSol)
+	if complete {
+		data[curveCompleteOffset] = 1
+	}
+	return data
+}
+
+// pumpFunGlobalData returns the global account charging feeBps on trades
+func pumpFunGlobalData(feeBps uint64) []byte {
+	data := make([]byte, pumpFunGlobalSize)
+	copy(data[globalFeeRecipientOffset:], pumpFunFeeRecipient.Bytes())
+	binary.LittleEndian.PutUint64(data[globalFeeBasisPointsOffset:], feeBps)
+	return data
+}
+
+// newCurveState decodes a fresh bonding curve for usdcMint, as launched with
+// 30 virtual SOL and a 1% fee
+func newCurveState(t *testing.T) *bondingCurveState {
+	address, err := bondingCurveAddress(usdcMint)
+	require.NoError(t, err)
+
+	state, err := decodeBondingCurve(usdcMint, address,
+		bondingCurveData(1_073_000_000_000_000, 30_000_000_000, 793_100_000_000_000, 0, false), pumpFunGlobalData(100))
+	require.NoError(t, err)
+	return state
+}
+
+// curveRPCHandler answers getMultipleAccounts for the bonding curve of
+// usdcMint with curve, and hands every other call to next
+func curveRPCHandler(t *testing.T, curve []byte, next func(req rpcRequest) interface{}) func(req rpcRequest) interface{} {
+	address, err := bondingCurveAddress(usdcMint)
+	require.NoError(t, err)
+
+	return func(req rpcRequest) interface{} {
+		if req.Method == "getMultipleAccounts" {
+			var accounts []string
+			require.NoError(t, json.Unmarshal(req.Params[0], &accounts))
+			if accounts[0] == address.String() {
+				return map[string]interface{}{
+					"context": map[string]interface{}{"slot": 1},
+					"value": []interface{}{
+						accountResult(pumpFunProgramID, curve),
+						accountResult(pumpFunProgramID, pumpFunGlobalData(100)),
+					},
+				}
+			}
+		}
+		return next(req)
+	}
+}
+
+func TestDecodeBondingCurve(t *testing.T) {
+	state := newCurveState(t)
+	assert.Equal(t, "1073000000000000", state.virtualTokenReserves.String())
+	assert.Equal(t, "30000000000", state.virtualSolReserves.String())
+	assert.Equal(t, "793100000000000", state.realTokenReserves.String())
+	assert.False(t, state.complete)
+	assert.Equal(t, pumpFunFeeRecipient, state.feeRecipient)
+	assert.Equal(t, int64(100), state.feeBasisPoints.Int64())
+
+	notCurve := bondingCurveData(1, 1, 1, 0, false)
+	notCurve[0] = 0
+	_, err := decodeBondingCurve(usdcMint, state.address, notCurve, pumpFunGlobalData(100))
+	assert.ErrorContains(t, err, "not a bonding curve")
+
+	_, err = decodeBondingCurve(usdcMint, state.address, bondingCurveData(1, 1, 1, 0, false), pumpFunGlobalData(basisPoints))
+	assert.ErrorContains(t, err, "invalid pump.fun fee")
+
+	_, err = decodeBondingCurve(usdcMint, state.address, bondingCurveData(1, 1, 1, 0, false), make([]byte, 10))
+	assert.Error(t, err)
+}
+
+func TestBondingCurveQuoteExactIn(t *testing.T) {
+	t.Run("buy with SOL", func(t *testing.T) {
+		state := newCurveState(t)
+		quote, err := state.quoteExactIn(solana.SolMint, newBigInt(1_000_000_000), 100)
+		require.NoError(t, err)
+
+		// 1 SOL pays 990,099,009 lamports into the curve and 1% of that in fees
+		assert.Equal(t, usdcMint.String(), quote.OutputMint)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(9_900_991), Decimals: solDecimals}, quote.Fee)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(34_281_150_129_545), Decimals: pumpFunTokenDecimals}, quote.ExpectedAmountOut)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(33_938_338_628_249), Decimals: pumpFunTokenDecimals}, quote.MinimumAmountOut)
+		assert.Equal(t, int64(319), quote.PriceImpactBps)
+		assert.Equal(t, state.address.String(), quote.Pool)
+		assert.Equal(t, pumpFunRoute, quote.Route)
+	})
+
+	t.Run("sell for SOL", func(t *testing.T) {
+		state := newCurveState(t)
+		state.virtualTokenReserves = newBigInt(800_000_000_000_000)
+		state.virtualSolReserves = newBigInt(40_000_000_000)
+		state.realSolReserves = newBigInt(10_000_000_000)
+
+		quote, err := state.quoteExactIn(usdcMint, newBigInt(10_000_000_000_000), 100)
+		require.NoError(t, err)
+
+		// 493,827,160 lamports come out of the curve, less the fee rounded up
+		assert.Equal(t, solana.SolMint.String(), quote.OutputMint)
+		assert.Equal(t, uint8(pumpFunTokenDecimals), quote.AmountIn.Decimals)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(4_938_272), Decimals: solDecimals}, quote.Fee)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(488_888_888), Decimals: solDecimals}, quote.ExpectedAmountOut)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(483_999_999), Decimals: solDecimals}, quote.MinimumAmountOut)
+		assert.Equal(t, int64(123), quote.PriceImpactBps)
+	})
+
+	t.Run("buy capped by the tokens left", func(t *testing.T) {
+		state := newCurveState(t)
+		state.realTokenReserves = newBigInt(1_000)
+
+		quote, err := state.quoteExactIn(solana.SolMint, newBigInt(1_000_000_000), 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1_000), quote.ExpectedAmountOut.Value.Int64())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		state := newCurveState(t)
+
+		_, err := state.quoteExactIn(solana.SolMint, newBigInt(0), 100)
+		assert.True(t, blockchain.IsValidationError(err))
+
+		_, err = state.quoteExactIn(usdcMint, newBigInt(1), 100)
+		assert.True(t, blockchain.IsValidationError(err), "the curve holds no SOL to pay out")
+
+		_, err = state.quoteExactIn(solana.NewWallet().PublicKey(), newBigInt(1_000_000_000), 100)
+		assert.True(t, blockchain.IsValidationError(err))
+	})
+}
+
+func TestPumpFunClientQuote(t *testing.T) {
+	tests := []struct {
+		name   string
+		curve  []byte
+		quoted bool
+	}{
+		{name: "active curve", curve: bondingCurveData(1_073_000_000_000_000, 30_000_000_000, 793_100_000_000_000, 0, false), quoted: true},
+		{name: "completed curve", curve: bondingCurveData(0, 0, 0, 0, true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRPCServer(t, curveRPCHandler(t, tt.curve, func(req rpcRequest) interface{} {
+				t.Errorf("unexpected RPC method %s", req.Method)
+				return nil
+			}))
+
+			client := NewPumpFunClient(rpc.New(server.URL))
+			quote, err := client.Quote(context.Background(), solana.SolMint, usdcMint, blockchain.Amount{Value: newBigInt(1_000_000_000)}, 0)
+			require.NoError(t, err)
+			if !tt.quoted {
+				assert.Nil(t, quote)
+				return
+			}
+			require.NotNil(t, quote)
+			assert.Equal(t, uint16(defaultSlippageBps), quote.SlippageBps)
+		})
+	}
+
+	// Mints that were not launched on Pump.fun have no curve account
+	server := newRPCServer(t, solUSDCPoolAccountsHandler(t))
+	quote, err := NewPumpFunClient(rpc.New(server.URL)).Quote(context.Background(), usdcMint, solana.SolMint, blockchain.Amount{Value: newBigInt(1)}, 0)
+	require.NoError(t, err)
+	assert.Nil(t, quote)
+}
+
+// solUSDCPoolAccountsHandler answers getMultipleAccounts with solUSDCPoolAccounts
+func solUSDCPoolAccountsHandler(t *testing.T) func(req rpcRequest) interface{} {
+	return func(req rpcRequest) interface{} {
+		require.Equal(t, "getMultipleAccounts", req.Method)
+		return solUSDCPoolAccounts(t, req)
+	}
+}
+
+func TestBuildSwapOnBondingCurve(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	var sent []solana.Transaction
+	curve := bondingCurveData(1_073_000_000_000_000, 30_000_000_000, 793_100_000_000_000, 0, false)
+	server := newRPCServer(t, curveRPCHandler(t, curve, swapRPCHandler(t, owner, &sent)))
+	provider := newTestProvider(t, server.URL, nil)
+
+	built, err := provider.BuildSwap(context.Background(), blockchain.BuildSwapRequest{
+		Type:          blockchain.TransactionTypeBuy,
+		WalletAddress: owner.String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, pumpFunRoute, built.Quote.Route)
+
+	var tx solana.Transaction
+	require.NoError(t, tx.UnmarshalBase64(built.Transaction))
+
+	// Compute budget, creation of the token account, then the buy
+	require.Len(t, tx.Message.Instructions, 4)
+	buy := tx.Message.Instructions[3]
+	programID, err := tx.Message.Program(buy.ProgramIDIndex)
+	require.NoError(t, err)
+	assert.Equal(t, pumpFunProgramID, programID)
+
+	// The buy receives the minimum amount out and spends at most the amount
+	assert.Equal(t, pumpFunBuyDiscriminator, []byte(buy.Data[:8]))
+	assert.Equal(t, uint64(33_938_338_628_249), binary.LittleEndian.Uint64(buy.Data[8:]))
+	assert.Equal(t, uint64(1_000_000_000), binary.LittleEndian.Uint64(buy.Data[16:]))
+
+	accounts, err := buy.ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	require.Len(t, accounts, 12)
+	assert.Equal(t, pumpFunGlobal, accounts[0].PublicKey)
+	assert.Equal(t, pumpFunFeeRecipient, accounts[1].PublicKey)
+	assert.True(t, accounts[1].IsWritable)
+	assert.Equal(t, owner, accounts[6].PublicKey)
+	assert.True(t, accounts[6].IsSigner)
+	assert.Equal(t, solana.SysVarRentPubkey, accounts[9].PublicKey)
+	assert.Equal(t, pumpFunEventAuthority, accounts[10].PublicKey)
+
+	// The curve holds no real SOL yet to pay a seller
+	_, err = provider.BuildSwap(context.Background(), blockchain.BuildSwapRequest{
+		Type:          blockchain.TransactionTypeSell,
+		WalletAddress: owner.String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000), Decimals: pumpFunTokenDecimals},
+	})
+	assert.True(t, blockchain.IsValidationError(err), "got %v", err)
+	assert.Empty(t, sent)
+}
+
+func TestBuildSwapAfterCurveCompletes(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	var sent []solana.Transaction
+	server := newRPCServer(t, curveRPCHandler(t, bondingCurveData(0, 0, 0, 0, true), swapRPCHandler(t, owner, &sent)))
+	provider := newTestProvider(t, server.URL, nil)
+
+	// A graduated token trades on its Raydium pool
+	built, err := provider.BuildSwap(context.Background(), blockchain.BuildSwapRequest{
+		Type:          blockchain.TransactionTypeBuy,
+		WalletAddress: owner.String(),
+		TokenAddress:  usdcMint.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, raydiumRoute, built.Quote.Route)
+	assert.Equal(t, solUSDCAmmID.String(), built.Quote.Pool)
+}
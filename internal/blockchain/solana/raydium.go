@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/gagliardetto/solana-go"
-	ata "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
@@ -27,6 +26,8 @@ type RaydiumTopMemeCoinsRequest struct {
 // RaydiumClient handles interactions with the Raydium DEX
 type RaydiumClient struct {
 	rpcClient *rpc.Client
+	fees      *priorityFees // Default priority fee when nil
+	lookups   *lookupTables // Swaps are legacy transactions when nil
 	isDevnet  bool
 }
 
@@ -305,61 +306,9 @@ func (c *RaydiumClient) filterAndSortMemePools(pools []RaydiumPool, timeFrame ti
 	return memeCoins
 }
 
-// SwapTokens executes a token swap on Raydium
-func (c *RaydiumClient) SwapTokens(ctx context.Context, req SwapRequest) (*blockchain.Transaction, error) {
-	// Validate input
-	fromPubKey, err := solana.PublicKeyFromBase58(req.FromAddress)
-	if err != nil {
-		return nil, fmt.Errorf("invalid from address: %w", err)
-	}
-
-	toPubKey, err := solana.PublicKeyFromBase58(req.ToAddress)
-	if err != nil {
-		return nil, fmt.Errorf("invalid to address: %w", err)
-	}
-
-	// Get token accounts
-	fromTokenAccount, err := c.getTokenAccount(ctx, fromPubKey, req.TokenAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get from token account: %w", err)
-	}
-
-	toTokenAccount, err := c.getTokenAccount(ctx, toPubKey, req.TokenAddress)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get to token account: %w", err)
-	}
-
-	// Build the swap instruction
-	swapInstruction := c.buildSwapInstruction(
-		fromTokenAccount,
-		toTokenAccount,
-		req.Amount,
-		req.MinimumAmountOut,
-	)
-
-	// Build the transaction
-	recentBlockhash, err := c.rpcClient.GetRecentBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get recent blockhash: %w", err)
-	}
-
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{swapInstruction},
-		recentBlockhash.Value.Blockhash,
-		solana.TransactionPayer(fromPubKey),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transaction: %w", err)
-	}
-
-	// Sign and send the transaction
-	sig, err := c.rpcClient.SendTransaction(ctx, tx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send transaction: %w", err)
-	}
-
-	// Create transaction record
-	transaction := &blockchain.Transaction{
+// swapRecord creates the transaction record of a broadcast swap
+func swapRecord(req SwapRequest, sig solana.Signature, blockhash solana.Hash) *blockchain.Transaction {
+	return &blockchain.Transaction{
 		ID:            sig.String(),
 		Network:       blockchain.NetworkSolana,
 		Type:          req.Type,
@@ -368,73 +317,51 @@ func (c *RaydiumClient) SwapTokens(ctx context.Context, req SwapRequest) (*block
 		ToAddress:     req.ToAddress,
 		Amount:        req.Amount,
 		TokenAddress:  req.TokenAddress,
+		Route:         raydiumRoute,
 		Signature:     sig.String(),
-		BlockHash:     recentBlockhash.Value.Blockhash.String(),
+		BlockHash:     blockhash.String(),
 		CreatedAt:     req.Timestamp,
 		LastUpdatedAt: req.Timestamp,
 	}
-
-	return transaction, nil
 }
 
+// SwapMode selects which side of a swap is fixed
+type SwapMode int
+
+const (
+	// SwapBaseIn spends exactly Amount and receives at least MinimumAmountOut
+	SwapBaseIn SwapMode = iota
+	// SwapBaseOut receives exactly Amount and spends at most MaximumAmountIn
+	SwapBaseOut
+)
+
 // SwapRequest represents a request to swap tokens on Raydium
 type SwapRequest struct {
 	FromAddress      string
 	ToAddress        string
 	TokenAddress     string
+	PoolAddress      string // Raydium AMM v4 pool; looked up on-chain when empty
+	Mode             SwapMode
 	Amount           blockchain.Amount
 	MinimumAmountOut blockchain.Amount
+	MaximumAmountIn  blockchain.Amount
+	SlippageBps      uint16 // Sets MinimumAmountOut from a quote when it is unset; defaults to 1%
+	ComputeUnitPrice uint64 // Priority fee in micro-lamports per compute unit; estimated when zero
 	Type             blockchain.TransactionType
 	Timestamp        int64
 }
 
-// getTokenAccount gets or creates a token account for a given token
-func (c *RaydiumClient) getTokenAccount(ctx context.Context, owner solana.PublicKey, tokenAddress string) (solana.PublicKey, error) {
-	tokenMint, err := solana.PublicKeyFromBase58(tokenAddress)
-	if err != nil {
-		return solana.PublicKey{}, fmt.Errorf("invalid token address: %w", err)
+// resolvePool returns the configured pool, or looks up the SOL pool of mint
+func (c *RaydiumClient) resolvePool(ctx context.Context, poolAddress string, mint solana.PublicKey) (solana.PublicKey, error) {
+	if poolAddress == "" {
+		return c.findPool(ctx, mint)
 	}
 
-	// Find associated token account
-	tokenAccount, _, err := solana.FindAssociatedTokenAddress(
-		owner,
-		tokenMint,
-	)
+	poolID, err := solana.PublicKeyFromBase58(poolAddress)
 	if err != nil {
-		return solana.PublicKey{}, fmt.Errorf("failed to find token account: %w", err)
-	}
-
-	// Check if account exists
-	_, err = c.rpcClient.GetAccountInfo(ctx, tokenAccount)
-	if err != nil {
-		// Create account if it doesn't exist
-		createIx := ata.NewCreateInstruction(
-			owner,
-			owner,
-			tokenMint,
-		).Build()
-
-		recentBlockhash, err := c.rpcClient.GetRecentBlockhash(ctx, rpc.CommitmentFinalized)
-		if err != nil {
-			return solana.PublicKey{}, fmt.Errorf("failed to get recent blockhash: %w", err)
-		}
-
-		tx, err := solana.NewTransaction(
-			[]solana.Instruction{createIx},
-			recentBlockhash.Value.Blockhash,
-			solana.TransactionPayer(owner),
-		)
-		if err != nil {
-			return solana.PublicKey{}, fmt.Errorf("failed to create transaction: %w", err)
-		}
-
-		_, err = c.rpcClient.SendTransaction(ctx, tx)
-		if err != nil {
-			return solana.PublicKey{}, fmt.Errorf("failed to create token account: %w", err)
-		}
+		return solana.PublicKey{}, blockchain.NewValidationError("invalid pool address: %w", err)
 	}
-
-	return tokenAccount, nil
+	return poolID, nil
 }
 
 // RaydiumSwapInstruction represents a Raydium swap instruction
@@ -459,31 +386,36 @@ func (i *RaydiumSwapInstruction) Data() ([]byte, error) {
 	return i.data, nil
 }
 
-// buildSwapInstruction builds the Raydium swap instruction
+// buildSwapInstruction builds a Raydium AMM v4 swap instruction. The account
+// order is fixed by the program and is the same for SwapBaseIn and SwapBaseOut.
 func (c *RaydiumClient) buildSwapInstruction(
-	fromAccount solana.PublicKey,
-	toAccount solana.PublicKey,
-	amount blockchain.Amount,
-	minimumAmountOut blockchain.Amount,
+	keys *raydiumPoolKeys,
+	userSource solana.PublicKey,
+	userDestination solana.PublicKey,
+	owner solana.PublicKey,
+	data []byte,
 ) solana.Instruction {
-	// Raydium program ID
-	programID := solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
-	if c.isDevnet {
-		programID = solana.MustPublicKeyFromBase58("DnXyn8dAR5fJdqfBQciQ6gPSDNMQSTkQrPsR65ZF5qoW")
-	}
-
-	// Build the instruction data
-	data := []byte{
-		0x0, // Instruction index for swap
-	}
-	data = append(data, amount.Value.Bytes()...)
-	data = append(data, minimumAmountOut.Value.Bytes()...)
-
 	return &RaydiumSwapInstruction{
-		programID: programID,
+		programID: keys.ProgramID,
 		accounts: []*solana.AccountMeta{
-			{PublicKey: fromAccount, IsSigner: false, IsWritable: true},
-			{PublicKey: toAccount, IsSigner: false, IsWritable: true},
+			solana.Meta(solana.TokenProgramID),
+			solana.Meta(keys.AmmID).WRITE(),
+			solana.Meta(keys.Authority),
+			solana.Meta(keys.OpenOrders).WRITE(),
+			solana.Meta(keys.TargetOrders).WRITE(),
+			solana.Meta(keys.BaseVault).WRITE(),
+			solana.Meta(keys.QuoteVault).WRITE(),
+			solana.Meta(keys.MarketProgramID),
+			solana.Meta(keys.MarketID).WRITE(),
+			solana.Meta(keys.MarketBids).WRITE(),
+			solana.Meta(keys.MarketAsks).WRITE(),
+			solana.Meta(keys.MarketEventQueue).WRITE(),
+			solana.Meta(keys.MarketBaseVault).WRITE(),
+			solana.Meta(keys.MarketQuoteVault).WRITE(),
+			solana.Meta(keys.MarketVaultSigner),
+			solana.Meta(userSource).WRITE(),
+			solana.Meta(userDestination).WRITE(),
+			solana.Meta(owner).SIGNER(),
 		},
 		data: data,
 	}
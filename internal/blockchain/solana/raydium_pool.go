@@ -0,0 +1,200 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	// Raydium AMM v4 program IDs
+	raydiumAmmV4ProgramID       = solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
+	raydiumAmmV4DevnetProgramID = solana.MustPublicKeyFromBase58("HWy1jotHpo6UqeQxx49dpYYdQB8wj9Qk9MdxwjLvDHB8")
+)
+
+const (
+	// raydiumAmmInfoSize is the size of a Raydium AMM v4 pool state account
+	raydiumAmmInfoSize = 752
+
+	// serumMarketSize is the size of a Serum/OpenBook v3 market account, including padding
+	serumMarketSize = 388
+
+	// raydiumAuthoritySeed derives the AMM authority together with the pool nonce
+	raydiumAuthoritySeed = "amm authority"
+)
+
+// Offsets into the Raydium AMM v4 pool state (AmmInfo)
+const (
+	ammNonceOffset         = 8
+	ammBaseVaultOffset     = 336
+	ammQuoteVaultOffset    = 368
+	ammBaseMintOffset      = 400
+	ammQuoteMintOffset     = 432
+	ammOpenOrdersOffset    = 496
+	ammMarketIDOffset      = 528
+	ammMarketProgramOffset = 560
+	ammTargetOrdersOffset  = 592
+)
+
+// Offsets into the Serum/OpenBook v3 market state
+const (
+	marketVaultSignerNonceOffset = 45
+	marketBaseVaultOffset        = 117
+	marketQuoteVaultOffset       = 165
+	marketEventQueueOffset       = 253
+	marketBidsOffset             = 285
+	marketAsksOffset             = 317
+)
+
+// raydiumPoolKeys holds every account a Raydium AMM v4 swap touches
+type raydiumPoolKeys struct {
+	ProgramID    solana.PublicKey
+	AmmID        solana.PublicKey
+	Authority    solana.PublicKey
+	OpenOrders   solana.PublicKey
+	TargetOrders solana.PublicKey
+	BaseVault    solana.PublicKey
+	QuoteVault   solana.PublicKey
+	BaseMint     solana.PublicKey
+	QuoteMint    solana.PublicKey
+
+	MarketProgramID   solana.PublicKey
+	MarketID          solana.PublicKey
+	MarketBids        solana.PublicKey
+	MarketAsks        solana.PublicKey
+	MarketEventQueue  solana.PublicKey
+	MarketBaseVault   solana.PublicKey
+	MarketQuoteVault  solana.PublicKey
+	MarketVaultSigner solana.PublicKey
+}
+
+// hasMints reports whether the pool trades a against b, in either order
+func (k *raydiumPoolKeys) hasMints(a, b solana.PublicKey) bool {
+	return (k.BaseMint.Equals(a) && k.QuoteMint.Equals(b)) ||
+		(k.BaseMint.Equals(b) && k.QuoteMint.Equals(a))
+}
+
+// decodeAmmInfo reads the pool accounts from a Raydium AMM v4 pool state
+func decodeAmmInfo(programID, ammID solana.PublicKey, data []byte) (*raydiumPoolKeys, error) {
+	if len(data) < raydiumAmmInfoSize {
+		return nil, fmt.Errorf("invalid AMM account size: %d", len(data))
+	}
+
+	nonce := binary.LittleEndian.Uint64(data[ammNonceOffset:])
+	authority, err := solana.CreateProgramAddress(
+		[][]byte{[]byte(raydiumAuthoritySeed), {byte(nonce)}},
+		programID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive AMM authority: %w", err)
+	}
+
+	return &raydiumPoolKeys{
+		ProgramID:       programID,
+		AmmID:           ammID,
+		Authority:       authority,
+		OpenOrders:      readPublicKey(data, ammOpenOrdersOffset),
+		TargetOrders:    readPublicKey(data, ammTargetOrdersOffset),
+		BaseVault:       readPublicKey(data, ammBaseVaultOffset),
+		QuoteVault:      readPublicKey(data, ammQuoteVaultOffset),
+		BaseMint:        readPublicKey(data, ammBaseMintOffset),
+		QuoteMint:       readPublicKey(data, ammQuoteMintOffset),
+		MarketProgramID: readPublicKey(data, ammMarketProgramOffset),
+		MarketID:        readPublicKey(data, ammMarketIDOffset),
+	}, nil
+}
+
+// decodeMarket fills in the Serum/OpenBook accounts from the pool's market state
+func (k *raydiumPoolKeys) decodeMarket(data []byte) error {
+	if len(data) < serumMarketSize {
+		return fmt.Errorf("invalid market account size: %d", len(data))
+	}
+
+	vaultSigner, err := solana.CreateProgramAddress(
+		[][]byte{k.MarketID.Bytes(), data[marketVaultSignerNonceOffset : marketVaultSignerNonceOffset+8]},
+		k.MarketProgramID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to derive market vault signer: %w", err)
+	}
+
+	k.MarketBids = readPublicKey(data, marketBidsOffset)
+	k.MarketAsks = readPublicKey(data, marketAsksOffset)
+	k.MarketEventQueue = readPublicKey(data, marketEventQueueOffset)
+	k.MarketBaseVault = readPublicKey(data, marketBaseVaultOffset)
+	k.MarketQuoteVault = readPublicKey(data, marketQuoteVaultOffset)
+	k.MarketVaultSigner = vaultSigner
+	return nil
+}
+
+// readPublicKey reads the public key stored at offset
+func readPublicKey(data []byte, offset int) solana.PublicKey {
+	return solana.PublicKeyFromBytes(data[offset : offset+solana.PublicKeyLength])
+}
+
+// programID returns the Raydium AMM v4 program for the client's cluster
+func (c *RaydiumClient) programID() solana.PublicKey {
+	if c.isDevnet {
+		return raydiumAmmV4DevnetProgramID
+	}
+	return raydiumAmmV4ProgramID
+}
+
+// fetchPoolKeys resolves the swap accounts of a pool from its on-chain state
+func (c *RaydiumClient) fetchPoolKeys(ctx context.Context, ammID solana.PublicKey) (*raydiumPoolKeys, error) {
+	amm, err := c.rpcClient.GetAccountInfo(ctx, ammID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool %s: %w", ammID, err)
+	}
+	if !amm.Value.Owner.Equals(c.programID()) {
+		return nil, fmt.Errorf("account %s is not a Raydium AMM v4 pool", ammID)
+	}
+
+	keys, err := decodeAmmInfo(c.programID(), ammID, amm.Value.Data.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pool %s: %w", ammID, err)
+	}
+
+	market, err := c.rpcClient.GetAccountInfo(ctx, keys.MarketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market %s: %w", keys.MarketID, err)
+	}
+	if err := keys.decodeMarket(market.Value.Data.GetBinary()); err != nil {
+		return nil, fmt.Errorf("failed to decode market %s: %w", keys.MarketID, err)
+	}
+
+	return keys, nil
+}
+
+// errPoolNotFound is returned when no Raydium AMM v4 pool trades a token against SOL
+var errPoolNotFound = errors.New("no Raydium pool found")
+
+// findPool returns the Raydium AMM v4 pool trading mint against wrapped SOL
+func (c *RaydiumClient) findPool(ctx context.Context, mint solana.PublicKey) (solana.PublicKey, error) {
+	pairs := [][2]solana.PublicKey{
+		{mint, solana.SolMint},
+		{solana.SolMint, mint},
+	}
+
+	for _, pair := range pairs {
+		accounts, err := c.rpcClient.GetProgramAccountsWithOpts(ctx, c.programID(), &rpc.GetProgramAccountsOpts{
+			Filters: []rpc.RPCFilter{
+				{DataSize: raydiumAmmInfoSize},
+				{Memcmp: &rpc.RPCFilterMemcmp{Offset: ammBaseMintOffset, Bytes: pair[0].Bytes()}},
+				{Memcmp: &rpc.RPCFilterMemcmp{Offset: ammQuoteMintOffset, Bytes: pair[1].Bytes()}},
+			},
+		})
+		if err != nil {
+			return solana.PublicKey{}, fmt.Errorf("failed to search pools: %w", err)
+		}
+		if len(accounts) > 0 {
+			return accounts[0].Pubkey, nil
+		}
+	}
+
+	return solana.PublicKey{}, fmt.Errorf("%w for token %s", errPoolNotFound, mint)
+}
@@ -0,0 +1,218 @@
+package solana
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Accounts of the mainnet SOL-USDC Raydium AMM v4 pool
+var (
+	solUSDCAmmID            = solana.MustPublicKeyFromBase58("58oQChx4yWmvKdwLLZzBi4ChoCc2fqCUWBkwMihLYQo2")
+	solUSDCAuthority        = solana.MustPublicKeyFromBase58("5Q544fKrFoe6tsEbD7S8EmxGTJYAKtTVhAW5Q5pge4j1")
+	solUSDCOpenOrders       = solana.MustPublicKeyFromBase58("HmiHHzq4Fym9e1D4qzLS6LDDM3tNsCTBPDWHTLZ763jY")
+	solUSDCTargetOrders     = solana.MustPublicKeyFromBase58("CZza3Ej4Mc58MnxWA385itCC9jCo3L1D7zc3LKy1bZMR")
+	solUSDCBaseVault        = solana.MustPublicKeyFromBase58("DQyrAcCrDXQ7NeoqGgDCZwBvWDcYmFCjSb9JtteuvPpz")
+	solUSDCQuoteVault       = solana.MustPublicKeyFromBase58("HLmqeL62xR1QoZ1HKKbXRrdN1p3phKpxRMb2VVopvBBz")
+	usdcMint                = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	serumProgramID          = solana.MustPublicKeyFromBase58("srmqPvymJeFKQ4zGQed1GFppgkRHL9kaELCbyksJtPX")
+	solUSDCMarketID         = solana.MustPublicKeyFromBase58("8BnEgHoWFysVcuFFX7QztDmzuH8r5ZFvyP3sYwn1XTh6")
+	solUSDCMarketBids       = solana.MustPublicKeyFromBase58("5jWUncPNBMZJ3sTHKmMLszypVkoRK6bfEQMQUHweeQnh")
+	solUSDCMarketAsks       = solana.MustPublicKeyFromBase58("EaXdHx7x3mdGA38j5RSmKYSXMzAFzzUXCLNBEDXDn1d5")
+	solUSDCMarketEventQueue = solana.MustPublicKeyFromBase58("8CvwxZ9Db6XbLD46NZwwmVDZZRDy7eydFcAGkXKh9axa")
+	solUSDCMarketBaseVault  = solana.MustPublicKeyFromBase58("CKxTHwM9fPMRRvZmFnFoqKNd9pQR21c5Aq9bh5h9oghX")
+	solUSDCMarketQuoteVault = solana.MustPublicKeyFromBase58("6A5NHCj1yF6urc9wZNe6Bcjj4LVszQNj5DwAWG97yzMu")
+	solUSDCVaultSigner      = solana.MustPublicKeyFromBase58("CTz5UMLQm2SRWHzQnU62Pi4yJqbNGjgRBHqqp6oDHfF7")
+)
+
+// solUSDCAmmInfo returns the pool state of the SOL-USDC pool, with only the
+// fields read by the decoder and the quote engine set
+func solUSDCAmmInfo() []byte {
+	data := make([]byte, raydiumAmmInfoSize)
+	binary.LittleEndian.PutUint64(data[ammNonceOffset:], 254)
+	binary.LittleEndian.PutUint64(data[ammBaseDecimalsOffset:], solDecimals)
+	binary.LittleEndian.PutUint64(data[ammQuoteDecimalsOffset:], 6)
+	binary.LittleEndian.PutUint64(data[ammSwapFeeNumeratorOffset:], 25)
+	binary.LittleEndian.PutUint64(data[ammSwapFeeDenominatorOffset:], 10_000)
+	binary.LittleEndian.PutUint64(data[ammNeedTakePnlBaseOffset:], 1_000_000_000)
+	binary.LittleEndian.PutUint64(data[ammNeedTakePnlQuoteOffset:], 500_000_000)
+	copy(data[ammBaseVaultOffset:], solUSDCBaseVault.Bytes())
+	copy(data[ammQuoteVaultOffset:], solUSDCQuoteVault.Bytes())
+	copy(data[ammBaseMintOffset:], solana.SolMint.Bytes())
+	copy(data[ammQuoteMintOffset:], usdcMint.Bytes())
+	copy(data[ammOpenOrdersOffset:], solUSDCOpenOrders.Bytes())
+	copy(data[ammMarketIDOffset:], solUSDCMarketID.Bytes())
+	copy(data[ammMarketProgramOffset:], serumProgramID.Bytes())
+	copy(data[ammTargetOrdersOffset:], solUSDCTargetOrders.Bytes())
+	return data
+}
+
+// solUSDCMarket returns the market state of the SOL-USDC pool, with only the
+// fields read by the decoder set
+func solUSDCMarket() []byte {
+	data := make([]byte, serumMarketSize)
+	binary.LittleEndian.PutUint64(data[marketVaultSignerNonceOffset:], 1)
+	copy(data[marketBaseVaultOffset:], solUSDCMarketBaseVault.Bytes())
+	copy(data[marketQuoteVaultOffset:], solUSDCMarketQuoteVault.Bytes())
+	copy(data[marketEventQueueOffset:], solUSDCMarketEventQueue.Bytes())
+	copy(data[marketBidsOffset:], solUSDCMarketBids.Bytes())
+	copy(data[marketAsksOffset:], solUSDCMarketAsks.Bytes())
+	return data
+}
+
+// solUSDCPoolKeys returns the fully resolved keys of the SOL-USDC pool
+func solUSDCPoolKeys(t *testing.T) *raydiumPoolKeys {
+	keys, err := decodeAmmInfo(raydiumAmmV4ProgramID, solUSDCAmmID, solUSDCAmmInfo())
+	require.NoError(t, err)
+	require.NoError(t, keys.decodeMarket(solUSDCMarket()))
+	return keys
+}
+
+func TestDecodeAmmInfo(t *testing.T) {
+	keys, err := decodeAmmInfo(raydiumAmmV4ProgramID, solUSDCAmmID, solUSDCAmmInfo())
+	require.NoError(t, err)
+
+	// The authority is derived from the nonce and must match the one on mainnet
+	assert.Equal(t, solUSDCAuthority, keys.Authority)
+	assert.Equal(t, solUSDCOpenOrders, keys.OpenOrders)
+	assert.Equal(t, solUSDCTargetOrders, keys.TargetOrders)
+	assert.Equal(t, solUSDCBaseVault, keys.BaseVault)
+	assert.Equal(t, solUSDCQuoteVault, keys.QuoteVault)
+	assert.Equal(t, solana.SolMint, keys.BaseMint)
+	assert.Equal(t, usdcMint, keys.QuoteMint)
+	assert.Equal(t, serumProgramID, keys.MarketProgramID)
+	assert.Equal(t, solUSDCMarketID, keys.MarketID)
+	assert.True(t, keys.hasMints(usdcMint, solana.SolMint))
+
+	_, err = decodeAmmInfo(raydiumAmmV4ProgramID, solUSDCAmmID, make([]byte, 100))
+	assert.Error(t, err)
+}
+
+func TestDecodeMarket(t *testing.T) {
+	keys := solUSDCPoolKeys(t)
+
+	// The vault signer is derived from the nonce and must match the one on mainnet
+	assert.Equal(t, solUSDCVaultSigner, keys.MarketVaultSigner)
+	assert.Equal(t, solUSDCMarketBids, keys.MarketBids)
+	assert.Equal(t, solUSDCMarketAsks, keys.MarketAsks)
+	assert.Equal(t, solUSDCMarketEventQueue, keys.MarketEventQueue)
+	assert.Equal(t, solUSDCMarketBaseVault, keys.MarketBaseVault)
+	assert.Equal(t, solUSDCMarketQuoteVault, keys.MarketQuoteVault)
+
+	assert.Error(t, keys.decodeMarket(make([]byte, 100)))
+}
+
+// rpcRequest is a JSON-RPC request received by a stub RPC server
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// newRPCServer starts a stub JSON-RPC server answering with the result returned by handle
+func newRPCServer(t *testing.T, handle func(req rpcRequest) interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  handle(req),
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// accountResult is the getAccountInfo value of an account holding data
+func accountResult(owner solana.PublicKey, data []byte) map[string]interface{} {
+	return map[string]interface{}{
+		"data":       []string{base64.StdEncoding.EncodeToString(data), "base64"},
+		"executable": false,
+		"lamports":   1,
+		"owner":      owner.String(),
+		"rentEpoch":  0,
+	}
+}
+
+func TestRaydiumClient_FetchPoolKeys(t *testing.T) {
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		var account string
+		require.NoError(t, json.Unmarshal(req.Params[0], &account))
+
+		var value interface{}
+		switch account {
+		case solUSDCAmmID.String():
+			value = accountResult(raydiumAmmV4ProgramID, solUSDCAmmInfo())
+		case solUSDCMarketID.String():
+			value = accountResult(serumProgramID, solUSDCMarket())
+		case usdcMint.String():
+			value = accountResult(solana.TokenProgramID, make([]byte, 82))
+		}
+		return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": value}
+	})
+
+	client := NewRaydiumClient(rpc.New(server.URL), false)
+
+	keys, err := client.fetchPoolKeys(context.Background(), solUSDCAmmID)
+	require.NoError(t, err)
+	assert.Equal(t, solUSDCPoolKeys(t), keys)
+
+	// Accounts not owned by the AMM program are rejected
+	_, err = client.fetchPoolKeys(context.Background(), usdcMint)
+	assert.ErrorContains(t, err, "not a Raydium AMM v4 pool")
+}
+
+func TestRaydiumClient_FindPool(t *testing.T) {
+	var calls int
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		calls++
+		assert.Equal(t, "getProgramAccounts", req.Method)
+
+		var opts struct {
+			Filters []struct {
+				Memcmp *struct {
+					Offset int    `json:"offset"`
+					Bytes  string `json:"bytes"`
+				} `json:"memcmp"`
+			} `json:"filters"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params[1], &opts))
+		require.Len(t, opts.Filters, 3)
+
+		// Only answer when SOL is the base mint and USDC the quote mint, as in the SOL-USDC pool
+		base, quote := opts.Filters[1].Memcmp, opts.Filters[2].Memcmp
+		if base.Offset != ammBaseMintOffset || base.Bytes != solana.SolMint.String() ||
+			quote.Offset != ammQuoteMintOffset || quote.Bytes != usdcMint.String() {
+			return []interface{}{}
+		}
+		return []interface{}{
+			map[string]interface{}{
+				"pubkey":  solUSDCAmmID.String(),
+				"account": accountResult(raydiumAmmV4ProgramID, solUSDCAmmInfo()),
+			},
+		}
+	})
+
+	client := NewRaydiumClient(rpc.New(server.URL), false)
+
+	pool, err := client.findPool(context.Background(), usdcMint)
+	require.NoError(t, err)
+	assert.Equal(t, solUSDCAmmID, pool)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	_, err = client.findPool(context.Background(), solana.MustPublicKeyFromBase58("11111111111111111111111111111111"))
+	assert.ErrorIs(t, err, errPoolNotFound)
+}
@@ -0,0 +1,222 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// basisPoints is the denominator of amounts expressed in basis points
+	basisPoints = 10_000
+
+	// defaultSlippageBps is the slippage tolerance of swaps that set neither a
+	// minimum amount out nor a tolerance
+	defaultSlippageBps = 100
+)
+
+// Offsets into the Raydium AMM v4 pool state (AmmInfo) read when quoting
+const (
+	ammBaseDecimalsOffset       = 32
+	ammQuoteDecimalsOffset      = 40
+	ammSwapFeeNumeratorOffset   = 176
+	ammSwapFeeDenominatorOffset = 184
+	ammNeedTakePnlBaseOffset    = 192
+	ammNeedTakePnlQuoteOffset   = 200
+)
+
+// Offsets into the Serum/OpenBook open orders state, holding the pool's
+// tokens that sit on the order book
+const (
+	openOrdersBaseTotalOffset  = 85
+	openOrdersQuoteTotalOffset = 101
+)
+
+// tokenAccountAmountOffset is the offset of the amount in an SPL token account
+const tokenAccountAmountOffset = 64
+
+// raydiumPoolState is the trading state of a Raydium AMM v4 pool at a slot
+type raydiumPoolState struct {
+	keys           *raydiumPoolKeys
+	baseReserve    *big.Int
+	quoteReserve   *big.Int
+	baseDecimals   uint8
+	quoteDecimals  uint8
+	feeNumerator   *big.Int
+	feeDenominator *big.Int
+}
+
+// fetchPoolState reads the pool state, its vaults and its open orders in a
+// single getMultipleAccounts call, so that they are taken at the same slot
+func (c *RaydiumClient) fetchPoolState(ctx context.Context, keys *raydiumPoolKeys) (*raydiumPoolState, error) {
+	result, err := c.rpcClient.GetMultipleAccounts(ctx, keys.AmmID, keys.BaseVault, keys.QuoteVault, keys.OpenOrders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool accounts: %w", err)
+	}
+	if len(result.Value) != 4 {
+		return nil, fmt.Errorf("expected 4 pool accounts, got %d", len(result.Value))
+	}
+
+	var data [4][]byte
+	for i, account := range result.Value {
+		if account != nil && account.Data != nil {
+			data[i] = account.Data.GetBinary()
+		}
+	}
+	return decodePoolState(keys, data[0], data[1], data[2], data[3])
+}
+
+// decodePoolState computes the reserves of a pool the way the AMM program
+// does: the vault balances plus the tokens on the order book, less the
+// protocol fees not yet taken. The open orders may be missing.
+func decodePoolState(keys *raydiumPoolKeys, amm, baseVault, quoteVault, openOrders []byte) (*raydiumPoolState, error) {
+	if len(amm) < raydiumAmmInfoSize {
+		return nil, fmt.Errorf("invalid AMM account size: %d", len(amm))
+	}
+	if len(baseVault) < tokenAccountAmountOffset+8 || len(quoteVault) < tokenAccountAmountOffset+8 {
+		return nil, fmt.Errorf("invalid vault account sizes: %d and %d", len(baseVault), len(quoteVault))
+	}
+
+	baseReserve := readUint64(baseVault, tokenAccountAmountOffset)
+	quoteReserve := readUint64(quoteVault, tokenAccountAmountOffset)
+	if len(openOrders) >= openOrdersQuoteTotalOffset+8 {
+		baseReserve.Add(baseReserve, readUint64(openOrders, openOrdersBaseTotalOffset))
+		quoteReserve.Add(quoteReserve, readUint64(openOrders, openOrdersQuoteTotalOffset))
+	}
+	baseReserve.Sub(baseReserve, readUint64(amm, ammNeedTakePnlBaseOffset))
+	quoteReserve.Sub(quoteReserve, readUint64(amm, ammNeedTakePnlQuoteOffset))
+	if baseReserve.Sign() <= 0 || quoteReserve.Sign() <= 0 {
+		return nil, fmt.Errorf("pool %s has no liquidity", keys.AmmID)
+	}
+
+	state := &raydiumPoolState{
+		keys:           keys,
+		baseReserve:    baseReserve,
+		quoteReserve:   quoteReserve,
+		baseDecimals:   uint8(binary.LittleEndian.Uint64(amm[ammBaseDecimalsOffset:])),
+		quoteDecimals:  uint8(binary.LittleEndian.Uint64(amm[ammQuoteDecimalsOffset:])),
+		feeNumerator:   readUint64(amm, ammSwapFeeNumeratorOffset),
+		feeDenominator: readUint64(amm, ammSwapFeeDenominatorOffset),
+	}
+	if state.feeDenominator.Sign() == 0 || state.feeNumerator.Cmp(state.feeDenominator) >= 0 {
+		return nil, fmt.Errorf("pool %s has an invalid swap fee %s/%s", keys.AmmID, state.feeNumerator, state.feeDenominator)
+	}
+	return state, nil
+}
+
+// Quote quotes swapping exactly amount of inputMint for outputMint through the
+// Raydium pool that pairs the token with SOL
+func (c *RaydiumClient) Quote(ctx context.Context, inputMint, outputMint solana.PublicKey, amount blockchain.Amount, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	token := outputMint
+	if !inputMint.Equals(solana.SolMint) {
+		token = inputMint
+		if !outputMint.Equals(solana.SolMint) {
+			return nil, blockchain.NewValidationError("only swaps between SOL and a token can be quoted")
+		}
+	}
+	if token.Equals(solana.SolMint) {
+		return nil, blockchain.NewValidationError("input and output mints must differ")
+	}
+	if _, err := amountToUint64(amount); err != nil {
+		return nil, blockchain.NewValidationError("invalid amount: %w", err)
+	}
+
+	poolID, err := c.findPool(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve pool: %w", err)
+	}
+
+	keys, err := c.fetchPoolKeys(ctx, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool keys: %w", err)
+	}
+
+	return c.quoteSwap(ctx, keys, inputMint, SwapRequest{Amount: amount, SlippageBps: slippageBps})
+}
+
+// applyQuote sets the amount, pool and minimum amount out of swapReq from a
+// quote, which must swap inputMint for outputMint on a supported route
+func applyQuote(swapReq *SwapRequest, quote *blockchain.SwapQuote, inputMint, outputMint string) error {
+	switch quote.Route {
+	case raydiumRoute, jupiterRoute, pumpFunRoute:
+	default:
+		return blockchain.NewValidationError("unsupported quote route: %s", quote.Route)
+	}
+	if quote.InputMint != inputMint || quote.OutputMint != outputMint {
+		return blockchain.NewValidationError("quote swaps %s for %s, not %s for %s", quote.InputMint, quote.OutputMint, inputMint, outputMint)
+	}
+
+	swapReq.Amount = quote.AmountIn
+	swapReq.PoolAddress = quote.Pool
+	swapReq.MinimumAmountOut = quote.MinimumAmountOut
+	return nil
+}
+
+// quoteExactIn quotes a swap spending exactly amountIn of inputMint, with the
+// same integer rounding as the AMM program: the fee is rounded up and the
+// amount out down. The minimum amount out allows for slippageBps of price
+// movement before the swap lands.
+func (s *raydiumPoolState) quoteExactIn(inputMint solana.PublicKey, amountIn *big.Int, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	if amountIn == nil || amountIn.Sign() <= 0 {
+		return nil, blockchain.NewValidationError("amount must be greater than zero")
+	}
+	if slippageBps > basisPoints {
+		return nil, blockchain.NewValidationError("slippage must be at most %d basis points", basisPoints)
+	}
+
+	reserveIn, reserveOut := s.baseReserve, s.quoteReserve
+	outputMint := s.keys.QuoteMint
+	inDecimals, outDecimals := s.baseDecimals, s.quoteDecimals
+	switch {
+	case inputMint.Equals(s.keys.BaseMint):
+	case inputMint.Equals(s.keys.QuoteMint):
+		reserveIn, reserveOut = s.quoteReserve, s.baseReserve
+		outputMint = s.keys.BaseMint
+		inDecimals, outDecimals = s.quoteDecimals, s.baseDecimals
+	default:
+		return nil, blockchain.NewValidationError("pool %s does not trade %s", s.keys.AmmID, inputMint)
+	}
+
+	// fee = ceil(amountIn * numerator / denominator)
+	fee := new(big.Int).Mul(amountIn, s.feeNumerator)
+	fee.Add(fee, new(big.Int).Sub(s.feeDenominator, big.NewInt(1)))
+	fee.Quo(fee, s.feeDenominator)
+	amountInLessFee := new(big.Int).Sub(amountIn, fee)
+
+	// Constant product: amountOut = reserveOut * in / (reserveIn + in)
+	reserveInAfter := new(big.Int).Add(reserveIn, amountInLessFee)
+	amountOut := new(big.Int).Mul(reserveOut, amountInLessFee)
+	amountOut.Quo(amountOut, reserveInAfter)
+	if amountOut.Sign() == 0 {
+		return nil, blockchain.NewValidationError("amount is too small to receive any %s", outputMint)
+	}
+
+	// The price moves by in / (reserveIn + in), excluding the fee
+	priceImpact := new(big.Int).Mul(amountInLessFee, big.NewInt(basisPoints))
+	priceImpact.Quo(priceImpact, reserveInAfter)
+
+	minimumAmountOut := new(big.Int).Mul(amountOut, big.NewInt(int64(basisPoints-int(slippageBps))))
+	minimumAmountOut.Quo(minimumAmountOut, big.NewInt(basisPoints))
+
+	return &blockchain.SwapQuote{
+		InputMint:         inputMint.String(),
+		OutputMint:        outputMint.String(),
+		AmountIn:          blockchain.Amount{Value: new(big.Int).Set(amountIn), Decimals: inDecimals},
+		ExpectedAmountOut: blockchain.Amount{Value: amountOut, Decimals: outDecimals},
+		MinimumAmountOut:  blockchain.Amount{Value: minimumAmountOut, Decimals: outDecimals},
+		Fee:               blockchain.Amount{Value: fee, Decimals: inDecimals},
+		PriceImpactBps:    priceImpact.Int64(),
+		SlippageBps:       slippageBps,
+		Pool:              s.keys.AmmID.String(),
+		Route:             raydiumRoute,
+	}, nil
+}
+
+// readUint64 reads the little-endian u64 stored at offset
+func readUint64(data []byte, offset int) *big.Int {
+	return new(big.Int).SetUint64(binary.LittleEndian.Uint64(data[offset:]))
+}
@@ -0,0 +1,201 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenAccountData returns an SPL token account holding amount
+func tokenAccountData(amount uint64) []byte {
+	data := make([]byte, 165)
+	binary.LittleEndian.PutUint64(data[tokenAccountAmountOffset:], amount)
+	return data
+}
+
+// solUSDCOpenOrdersData returns the open orders of the SOL-USDC pool, with only
+// the totals set
+func solUSDCOpenOrdersData() []byte {
+	data := make([]byte, 3228)
+	binary.LittleEndian.PutUint64(data[openOrdersBaseTotalOffset:], 2_000_000_000)
+	binary.LittleEndian.PutUint64(data[openOrdersQuoteTotalOffset:], 1_500_000_000)
+	return data
+}
+
+// solUSDCPoolAccounts answers getMultipleAccounts for the SOL-USDC pool. Once
+// the open orders and the fees not yet taken are accounted for, the pool
+// holds 1,000 SOL and 150,000 USDC.
+func solUSDCPoolAccounts(t *testing.T, req rpcRequest) interface{} {
+	var accounts []string
+	require.NoError(t, json.Unmarshal(req.Params[0], &accounts))
+
+	values := make([]interface{}, len(accounts))
+	for i, account := range accounts {
+		switch account {
+		case solUSDCAmmID.String():
+			values[i] = accountResult(raydiumAmmV4ProgramID, solUSDCAmmInfo())
+		case solUSDCBaseVault.String():
+			values[i] = accountResult(solana.TokenProgramID, tokenAccountData(999_000_000_000))
+		case solUSDCQuoteVault.String():
+			values[i] = accountResult(solana.TokenProgramID, tokenAccountData(149_000_000_000))
+		case solUSDCOpenOrders.String():
+			values[i] = accountResult(serumProgramID, solUSDCOpenOrdersData())
+		}
+	}
+	return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": values}
+}
+
+func TestRaydiumClient_FetchPoolState(t *testing.T) {
+	var calls int
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		calls++
+		require.Equal(t, "getMultipleAccounts", req.Method)
+		return solUSDCPoolAccounts(t, req)
+	})
+
+	client := NewRaydiumClient(rpc.New(server.URL), false)
+	state, err := client.fetchPoolState(context.Background(), solUSDCPoolKeys(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "1000000000000", state.baseReserve.String())
+	assert.Equal(t, "150000000000", state.quoteReserve.String())
+	assert.Equal(t, uint8(solDecimals), state.baseDecimals)
+	assert.Equal(t, uint8(6), state.quoteDecimals)
+	assert.Equal(t, int64(25), state.feeNumerator.Int64())
+	assert.Equal(t, int64(10_000), state.feeDenominator.Int64())
+}
+
+func TestDecodePoolState(t *testing.T) {
+	keys := solUSDCPoolKeys(t)
+
+	// Without open orders only the vaults count
+	state, err := decodePoolState(keys, solUSDCAmmInfo(), tokenAccountData(5_000_000_000), tokenAccountData(1_000_000_000), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "4000000000", state.baseReserve.String())
+	assert.Equal(t, "500000000", state.quoteReserve.String())
+
+	// Vaults holding less than the fees not yet taken leave nothing to trade
+	_, err = decodePoolState(keys, solUSDCAmmInfo(), tokenAccountData(1), tokenAccountData(1), nil)
+	assert.ErrorContains(t, err, "no liquidity")
+
+	_, err = decodePoolState(keys, make([]byte, 100), tokenAccountData(1), tokenAccountData(1), nil)
+	assert.Error(t, err)
+
+	noFee := solUSDCAmmInfo()
+	binary.LittleEndian.PutUint64(noFee[ammSwapFeeDenominatorOffset:], 0)
+	_, err = decodePoolState(keys, noFee, tokenAccountData(5_000_000_000), tokenAccountData(1_000_000_000), nil)
+	assert.ErrorContains(t, err, "invalid swap fee")
+}
+
+func TestQuoteExactIn(t *testing.T) {
+	state, err := decodePoolState(solUSDCPoolKeys(t), solUSDCAmmInfo(),
+		tokenAccountData(999_000_000_000), tokenAccountData(149_000_000_000), solUSDCOpenOrdersData())
+	require.NoError(t, err)
+
+	t.Run("buy with SOL", func(t *testing.T) {
+		quote, err := state.quoteExactIn(solana.SolMint, newBigInt(1_000_000_000), 100)
+		require.NoError(t, err)
+
+		// 0.25% fee rounded up, then 150,000 * 0.9975 / (1,000 + 0.9975) USDC
+		assert.Equal(t, solana.SolMint.String(), quote.InputMint)
+		assert.Equal(t, usdcMint.String(), quote.OutputMint)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(2_500_000), Decimals: solDecimals}, quote.Fee)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(149_475_897), Decimals: 6}, quote.ExpectedAmountOut)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(147_981_138), Decimals: 6}, quote.MinimumAmountOut)
+		assert.Equal(t, int64(9), quote.PriceImpactBps)
+		assert.Equal(t, uint16(100), quote.SlippageBps)
+		assert.Equal(t, solUSDCAmmID.String(), quote.Pool)
+		assert.Equal(t, raydiumRoute, quote.Route)
+	})
+
+	t.Run("sell for SOL", func(t *testing.T) {
+		quote, err := state.quoteExactIn(usdcMint, newBigInt(150_000_000), 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, solana.SolMint.String(), quote.OutputMint)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(375_000), Decimals: 6}, quote.Fee)
+		assert.Equal(t, blockchain.Amount{Value: newBigInt(996_505_985), Decimals: solDecimals}, quote.ExpectedAmountOut)
+		assert.Equal(t, quote.ExpectedAmountOut, quote.MinimumAmountOut)
+	})
+
+	t.Run("large trade moves the price", func(t *testing.T) {
+		quote, err := state.quoteExactIn(solana.SolMint, newBigInt(100_000_000_000), 50)
+		require.NoError(t, err)
+		assert.Equal(t, int64(907), quote.PriceImpactBps)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := state.quoteExactIn(solana.SolMint, newBigInt(0), 100)
+		assert.True(t, blockchain.IsValidationError(err))
+
+		_, err = state.quoteExactIn(solana.SolMint, newBigInt(1), 100)
+		assert.True(t, blockchain.IsValidationError(err), "an amount eaten by the fee receives nothing")
+
+		_, err = state.quoteExactIn(solana.SolMint, newBigInt(1_000_000_000), basisPoints+1)
+		assert.True(t, blockchain.IsValidationError(err))
+
+		_, err = state.quoteExactIn(solana.NewWallet().PublicKey(), newBigInt(1_000_000_000), 100)
+		assert.True(t, blockchain.IsValidationError(err))
+	})
+}
+
+func TestProviderQuote(t *testing.T) {
+	var sent []solana.Transaction
+	provider := newTestProvider(t, newSwapRPCServer(t, solana.NewWallet().PublicKey(), &sent), nil)
+
+	quote, err := provider.Quote(context.Background(), blockchain.QuoteRequest{
+		InputMint:   solana.SolMint.String(),
+		OutputMint:  usdcMint.String(),
+		Amount:      blockchain.Amount{Value: newBigInt(1_000_000_000)},
+		SlippageBps: 50,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(149_475_897), quote.ExpectedAmountOut.Value.Int64())
+	assert.Equal(t, int64(148_728_517), quote.MinimumAmountOut.Value.Int64())
+	assert.Equal(t, solUSDCAmmID.String(), quote.Pool)
+
+	invalid := []blockchain.QuoteRequest{
+		{InputMint: "not a mint", OutputMint: usdcMint.String(), Amount: blockchain.Amount{Value: newBigInt(1)}},
+		{InputMint: solana.SolMint.String(), OutputMint: solana.SolMint.String(), Amount: blockchain.Amount{Value: newBigInt(1)}},
+		{InputMint: usdcMint.String(), OutputMint: solana.NewWallet().PublicKey().String(), Amount: blockchain.Amount{Value: newBigInt(1)}},
+	}
+	for _, req := range invalid {
+		_, err := provider.Quote(context.Background(), req)
+		assert.True(t, blockchain.IsValidationError(err), "got %v", err)
+	}
+	assert.Empty(t, sent)
+}
+
+func TestApplyQuote(t *testing.T) {
+	quote := &blockchain.SwapQuote{
+		InputMint:        solana.SolMint.String(),
+		OutputMint:       usdcMint.String(),
+		AmountIn:         blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+		MinimumAmountOut: blockchain.Amount{Value: newBigInt(148_728_517), Decimals: 6},
+		Pool:             solUSDCAmmID.String(),
+		Route:            raydiumRoute,
+	}
+
+	var req SwapRequest
+	require.NoError(t, applyQuote(&req, quote, solana.SolMint.String(), usdcMint.String()))
+	assert.Equal(t, quote.AmountIn, req.Amount)
+	assert.Equal(t, quote.MinimumAmountOut, req.MinimumAmountOut)
+	assert.Equal(t, solUSDCAmmID.String(), req.PoolAddress)
+
+	// A buy quote can't be used to sell
+	err := applyQuote(&req, quote, usdcMint.String(), solana.SolMint.String())
+	assert.True(t, blockchain.IsValidationError(err))
+
+	other := *quote
+	other.Route = "orca"
+	err = applyQuote(&req, &other, solana.SolMint.String(), usdcMint.String())
+	assert.True(t, blockchain.IsValidationError(err))
+}
@@ -0,0 +1,78 @@
+package solana
+
+import (
+	"encoding/binary"
+	"fmt"
+	"meme-trader/internal/blockchain"
+)
+
+// Raydium AMM v4 instruction opcodes
+const (
+	raydiumSwapBaseInOpcode  = 9
+	raydiumSwapBaseOutOpcode = 11
+
+	// raydiumSwapDataSize is the opcode followed by two little-endian u64 amounts
+	raydiumSwapDataSize = 17
+)
+
+// encodeSwapBaseIn encodes a swap that spends exactly amountIn
+func encodeSwapBaseIn(amountIn, minimumAmountOut uint64) []byte {
+	return encodeSwap(raydiumSwapBaseInOpcode, amountIn, minimumAmountOut)
+}
+
+// encodeSwapBaseOut encodes a swap that receives exactly amountOut
+func encodeSwapBaseOut(maxAmountIn, amountOut uint64) []byte {
+	return encodeSwap(raydiumSwapBaseOutOpcode, maxAmountIn, amountOut)
+}
+
+func encodeSwap(opcode byte, first, second uint64) []byte {
+	data := make([]byte, raydiumSwapDataSize)
+	data[0] = opcode
+	binary.LittleEndian.PutUint64(data[1:9], first)
+	binary.LittleEndian.PutUint64(data[9:17], second)
+	return data
+}
+
+// encodeSwapRequest encodes the instruction data for a swap request
+func encodeSwapRequest(req SwapRequest) ([]byte, error) {
+	amount, err := amountToUint64(req.Amount)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid amount: %w", err)
+	}
+	if amount == 0 {
+		return nil, blockchain.NewValidationError("amount must be greater than zero")
+	}
+
+	switch req.Mode {
+	case SwapBaseIn:
+		minimumAmountOut, err := amountToUint64(req.MinimumAmountOut)
+		if err != nil {
+			return nil, blockchain.NewValidationError("invalid minimum amount out: %w", err)
+		}
+		return encodeSwapBaseIn(amount, minimumAmountOut), nil
+
+	case SwapBaseOut:
+		maxAmountIn, err := amountToUint64(req.MaximumAmountIn)
+		if err != nil {
+			return nil, blockchain.NewValidationError("invalid maximum amount in: %w", err)
+		}
+		if maxAmountIn == 0 {
+			return nil, blockchain.NewValidationError("maximum amount in is required for an exact output swap")
+		}
+		return encodeSwapBaseOut(maxAmountIn, amount), nil
+
+	default:
+		return nil, blockchain.NewValidationError("unknown swap mode: %d", req.Mode)
+	}
+}
+
+// amountToUint64 converts an amount to the u64 used on-chain. A missing value is zero.
+func amountToUint64(amount blockchain.Amount) (uint64, error) {
+	if amount.Value == nil {
+		return 0, nil
+	}
+	if amount.Value.Sign() < 0 || !amount.Value.IsUint64() {
+		return 0, fmt.Errorf("amount %s does not fit in a u64", amount.Value)
+	}
+	return amount.Value.Uint64(), nil
+}
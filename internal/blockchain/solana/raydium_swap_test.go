@@ -0,0 +1,73 @@
+package solana
+
+import (
+	"encoding/hex"
+	"math/big"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeSwapBaseIn(t *testing.T) {
+	// Swap 1 SOL for at least 150 USDC
+	data := encodeSwapBaseIn(1_000_000_000, 150_000_000)
+	assert.Equal(t, "0900ca9a3b0000000080d1f00800000000", hex.EncodeToString(data))
+}
+
+func TestEncodeSwapBaseOut(t *testing.T) {
+	// Receive exactly 1 SOL for at most 160 USDC
+	data := encodeSwapBaseOut(160_000_000, 1_000_000_000)
+	assert.Equal(t, "0b006889090000000000ca9a3b00000000", hex.EncodeToString(data))
+}
+
+func TestEncodeSwapRequest(t *testing.T) {
+	data, err := encodeSwapRequest(SwapRequest{
+		Amount:           blockchain.Amount{Value: newBigInt(1_000_000)},
+		MinimumAmountOut: blockchain.Amount{Value: newBigInt(900_000)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, encodeSwapBaseIn(1_000_000, 900_000), data)
+
+	// A missing minimum means no slippage limit
+	data, err = encodeSwapRequest(SwapRequest{Amount: blockchain.Amount{Value: newBigInt(1_000_000)}})
+	require.NoError(t, err)
+	assert.Equal(t, encodeSwapBaseIn(1_000_000, 0), data)
+
+	data, err = encodeSwapRequest(SwapRequest{
+		Mode:            SwapBaseOut,
+		Amount:          blockchain.Amount{Value: newBigInt(1_000_000)},
+		MaximumAmountIn: blockchain.Amount{Value: newBigInt(1_100_000)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, encodeSwapBaseOut(1_100_000, 1_000_000), data)
+}
+
+func TestEncodeSwapRequestValidation(t *testing.T) {
+	tooLarge := new(big.Int).Lsh(big.NewInt(1), 64)
+
+	tests := []struct {
+		name string
+		req  SwapRequest
+	}{
+		{"missing amount", SwapRequest{}},
+		{"negative amount", SwapRequest{Amount: blockchain.Amount{Value: big.NewInt(-1)}}},
+		{"amount overflows u64", SwapRequest{Amount: blockchain.Amount{Value: tooLarge}}},
+		{"minimum overflows u64", SwapRequest{
+			Amount:           blockchain.Amount{Value: newBigInt(1)},
+			MinimumAmountOut: blockchain.Amount{Value: tooLarge},
+		}},
+		{"exact output without maximum", SwapRequest{
+			Mode:   SwapBaseOut,
+			Amount: blockchain.Amount{Value: newBigInt(1)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := encodeSwapRequest(tt.req)
+			assert.True(t, blockchain.IsValidationError(err), "got %v", err)
+		})
+	}
+}
@@ -2,9 +2,9 @@ package solana
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"math/big"
-	"meme-trader/internal/blockchain"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -144,38 +144,56 @@ func TestRaydiumClient_GetTopMemeCoins(t *testing.T) {
 	assert.Equal(t, -5.2, memeCoins[1].Change24h)
 }
 
-func TestRaydiumClient_SwapTokens(t *testing.T) {
-	// Create a mock RPC client
-	rpcClient := rpc.New("http://localhost:8899")
-
-	// Create Raydium client
-	client := NewRaydiumClient(rpcClient, true)
-
-	// Test buildSwapInstruction
-	fromAccount := solana.NewWallet().PublicKey()
-	toAccount := solana.NewWallet().PublicKey()
-	amount := blockchain.Amount{Value: newBigInt(1000000)}
-	minimumAmountOut := blockchain.Amount{Value: newBigInt(900000)}
+func TestRaydiumClient_BuildSwapInstruction(t *testing.T) {
+	client := NewRaydiumClient(nil, false)
+	keys := solUSDCPoolKeys(t)
 
-	instruction := client.buildSwapInstruction(fromAccount, toAccount, amount, minimumAmountOut)
+	owner := solana.MustPublicKeyFromBase58("9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM")
+	source := solana.MustPublicKeyFromBase58("7UX2i7SucgLMQcfZ75s3VXmZZY4YRUyJN9X1RgfMoDUi")
+	destination := solana.MustPublicKeyFromBase58("3K3Wn8sZjwWr2kXWsJ4cwkTqjLrW4qAtLtrHhHtFtvKZ")
 
-	// Verify instruction
-	assert.NotNil(t, instruction)
-	assert.Equal(t, "DnXyn8dAR5fJdqfBQciQ6gPSDNMQSTkQrPsR65ZF5qoW", instruction.ProgramID().String())
+	instruction := client.buildSwapInstruction(keys, source, destination, owner, encodeSwapBaseIn(1_000_000, 900_000))
+	assert.Equal(t, raydiumAmmV4ProgramID, instruction.ProgramID())
 
-	accounts := instruction.Accounts()
-	require.Len(t, accounts, 2)
-	assert.Equal(t, fromAccount, accounts[0].PublicKey)
-	assert.Equal(t, toAccount, accounts[1].PublicKey)
-	assert.True(t, accounts[0].IsWritable)
-	assert.True(t, accounts[1].IsWritable)
-	assert.False(t, accounts[0].IsSigner)
-	assert.False(t, accounts[1].IsSigner)
+	// Account order and flags expected by the AMM v4 swap instructions
+	expected := []*solana.AccountMeta{
+		{PublicKey: solana.TokenProgramID},
+		{PublicKey: solUSDCAmmID, IsWritable: true},
+		{PublicKey: solUSDCAuthority},
+		{PublicKey: solUSDCOpenOrders, IsWritable: true},
+		{PublicKey: solUSDCTargetOrders, IsWritable: true},
+		{PublicKey: solUSDCBaseVault, IsWritable: true},
+		{PublicKey: solUSDCQuoteVault, IsWritable: true},
+		{PublicKey: serumProgramID},
+		{PublicKey: solUSDCMarketID, IsWritable: true},
+		{PublicKey: solUSDCMarketBids, IsWritable: true},
+		{PublicKey: solUSDCMarketAsks, IsWritable: true},
+		{PublicKey: solUSDCMarketEventQueue, IsWritable: true},
+		{PublicKey: solUSDCMarketBaseVault, IsWritable: true},
+		{PublicKey: solUSDCMarketQuoteVault, IsWritable: true},
+		{PublicKey: solUSDCVaultSigner},
+		{PublicKey: source, IsWritable: true},
+		{PublicKey: destination, IsWritable: true},
+		{PublicKey: owner, IsSigner: true},
+	}
+	assert.Equal(t, expected, instruction.Accounts())
 
 	data, err := instruction.Data()
 	require.NoError(t, err)
-	assert.NotEmpty(t, data)
-	assert.Equal(t, byte(0x0), data[0]) // Verify instruction index
+	assert.Equal(t, "0940420f0000000000a0bb0d0000000000", hex.EncodeToString(data))
+
+	// The compiled transaction carries the same data and all 18 accounts
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{instruction},
+		solana.Hash{},
+		solana.TransactionPayer(owner),
+	)
+	require.NoError(t, err)
+	require.Len(t, tx.Message.Instructions, 1)
+	assert.Equal(t, data, []byte(tx.Message.Instructions[0].Data))
+	assert.Len(t, tx.Message.Instructions[0].Accounts, len(expected))
+	assert.Equal(t, owner, tx.Message.AccountKeys[0])
+	assert.Equal(t, uint8(1), tx.Message.Header.NumRequiredSignatures)
 }
 
 func TestRaydiumClient_GetTokenMetadata(t *testing.T) {
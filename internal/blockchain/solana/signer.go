@@ -0,0 +1,77 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// WalletSigner signs in process with the private keys of stored wallets
+type WalletSigner struct {
+	store blockchain.WalletStore
+}
+
+// NewWalletSigner creates a signer that loads wallet keys from store
+func NewWalletSigner(store blockchain.WalletStore) *WalletSigner {
+	return &WalletSigner{store: store}
+}
+
+// Sign signs message with the stored key of the wallet at address
+func (s *WalletSigner) Sign(ctx context.Context, network blockchain.Network, address string, message []byte) ([]byte, error) {
+	wallet, err := s.store.GetWallet(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wallet %s: %w", address, err)
+	}
+	if wallet.PrivateKey == "" {
+		return nil, fmt.Errorf("%w: wallet %s has no stored key", blockchain.ErrSignerUnavailable, address)
+	}
+
+	key, err := solana.PrivateKeyFromBase58(wallet.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key for wallet %s: %w", address, err)
+	}
+	if key.PublicKey().String() != address {
+		return nil, fmt.Errorf("stored key does not match wallet %s", address)
+	}
+
+	signature, err := key.Sign(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with wallet %s: %w", address, err)
+	}
+	return signature[:], nil
+}
+
+// signTransaction asks signer for every signature the transaction requires,
+// starting with the fee payer
+func signTransaction(ctx context.Context, signer blockchain.Signer, tx *solana.Transaction) error {
+	if signer == nil {
+		return blockchain.ErrSignerUnavailable
+	}
+
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	signers := tx.Message.Signers()
+	signatures := make([]solana.Signature, len(signers))
+	for i, key := range signers {
+		signature, err := signer.Sign(ctx, blockchain.NetworkSolana, key.String(), message)
+		if err != nil {
+			return err
+		}
+		if len(signature) != len(solana.Signature{}) {
+			return fmt.Errorf("invalid signature length %d for %s", len(signature), key)
+		}
+		copy(signatures[i][:], signature)
+	}
+	tx.Signatures = signatures
+
+	// Catch signers that signed with the wrong key before anything is broadcast
+	if err := tx.VerifySignatures(); err != nil {
+		return fmt.Errorf("invalid transaction signature: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryWalletStore is an in-memory blockchain.WalletStore
+type memoryWalletStore map[string]*blockchain.Wallet
+
+func (m memoryWalletStore) SaveWallet(wallet *blockchain.Wallet) error {
+	m[wallet.Address] = wallet
+	return nil
+}
+
+func (m memoryWalletStore) GetWallet(network blockchain.Network, address string) (*blockchain.Wallet, error) {
+	wallet, ok := m[address]
+	if !ok {
+		return nil, errors.New("wallet not found")
+	}
+	return wallet, nil
+}
+
+// storeKey saves a new wallet in store and returns its key
+func storeKey(store memoryWalletStore) solana.PrivateKey {
+	key := solana.NewWallet().PrivateKey
+	address := key.PublicKey().String()
+	store[address] = &blockchain.Wallet{
+		Network:    blockchain.NetworkSolana,
+		Address:    address,
+		PublicKey:  address,
+		PrivateKey: key.String(),
+	}
+	return key
+}
+
+// newTransferTransaction builds an unsigned transfer paid by payer
+func newTransferTransaction(t *testing.T, payer, from, to solana.PublicKey) *solana.Transaction {
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(1, from, to).Build()},
+		solana.Hash{},
+		solana.TransactionPayer(payer),
+	)
+	require.NoError(t, err)
+	return tx
+}
+
+func TestSignTransactionSignsEveryRequiredSigner(t *testing.T) {
+	store := memoryWalletStore{}
+	payer := storeKey(store)
+	owner := storeKey(store)
+	recipient := solana.NewWallet().PublicKey()
+
+	// The payer and the owner of the transferred lamports must both sign
+	tx := newTransferTransaction(t, payer.PublicKey(), owner.PublicKey(), recipient)
+
+	err := signTransaction(context.Background(), NewWalletSigner(store), tx)
+	require.NoError(t, err)
+	require.Len(t, tx.Signatures, 2)
+	assert.NoError(t, tx.VerifySignatures())
+}
+
+func TestSignTransactionErrors(t *testing.T) {
+	store := memoryWalletStore{}
+	payer := storeKey(store)
+	tx := newTransferTransaction(t, payer.PublicKey(), payer.PublicKey(), solana.NewWallet().PublicKey())
+
+	// No signer configured
+	err := signTransaction(context.Background(), nil, tx)
+	assert.ErrorIs(t, err, blockchain.ErrSignerUnavailable)
+
+	// A signer that refuses, as with client-side signing
+	refuse := blockchain.SignerFunc(func(context.Context, blockchain.Network, string, []byte) ([]byte, error) {
+		return nil, blockchain.ErrSignerUnavailable
+	})
+	err = signTransaction(context.Background(), refuse, tx)
+	assert.ErrorIs(t, err, blockchain.ErrSignerUnavailable)
+
+	// A signer that signs with the wrong key
+	other := solana.NewWallet().PrivateKey
+	wrongKey := blockchain.SignerFunc(func(_ context.Context, _ blockchain.Network, _ string, message []byte) ([]byte, error) {
+		signature, err := other.Sign(message)
+		return signature[:], err
+	})
+	err = signTransaction(context.Background(), wrongKey, tx)
+	assert.ErrorContains(t, err, "invalid transaction signature")
+}
+
+func TestWalletSigner(t *testing.T) {
+	store := memoryWalletStore{}
+	key := storeKey(store)
+	signer := NewWalletSigner(store)
+
+	message := []byte("message")
+	signature, err := signer.Sign(context.Background(), blockchain.NetworkSolana, key.PublicKey().String(), message)
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey().Verify(message, solana.SignatureFromBytes(signature)))
+
+	// Unknown wallet
+	_, err = signer.Sign(context.Background(), blockchain.NetworkSolana, solana.NewWallet().PublicKey().String(), message)
+	assert.Error(t, err)
+
+	// Wallet without a stored key
+	watchOnly := solana.NewWallet().PublicKey().String()
+	store[watchOnly] = &blockchain.Wallet{Address: watchOnly}
+	_, err = signer.Sign(context.Background(), blockchain.NetworkSolana, watchOnly, message)
+	assert.ErrorIs(t, err, blockchain.ErrSignerUnavailable)
+
+	// Key that belongs to another wallet
+	mismatched := solana.NewWallet().PublicKey().String()
+	store[mismatched] = &blockchain.Wallet{Address: mismatched, PrivateKey: key.String()}
+	_, err = signer.Sign(context.Background(), blockchain.NetworkSolana, mismatched, message)
+	assert.ErrorContains(t, err, "does not match")
+}
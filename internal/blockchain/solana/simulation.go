@@ -0,0 +1,134 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"meme-trader/internal/blockchain"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// computeUnitMarginPercent is added to the compute units a simulation
+	// consumed, as the pool may have moved by the time the swap lands
+	computeUnitMarginPercent = 10
+
+	// setComputeUnitLimit is the compute budget program instruction setting
+	// the compute unit limit
+	setComputeUnitLimit = 2
+)
+
+// simulationFailures map what failing programs log, or the transaction error
+// itself, to the reason they failed. Patterns are matched lowercase.
+var simulationFailures = []struct {
+	reason   error
+	patterns []string
+}{
+	{blockchain.ErrSlippageExceeded, []string{
+		"exceeds desired slippage limit", // Raydium AMM v4
+		"slippagetoleranceexceeded",      // Jupiter
+		"toomuchsolrequired",             // Pump.fun buy
+		"toolittlesolreceived",           // Pump.fun sell
+	}},
+	{blockchain.ErrAccountFrozen, []string{
+		"account is frozen", // SPL Token
+		"accountfrozen",
+	}},
+	{blockchain.ErrInsufficientFunds, []string{
+		"insufficient funds",    // SPL Token
+		"insufficient lamports", // System program
+		"insufficientfunds",     // InsufficientFundsForFee and InsufficientFundsForRent
+	}},
+	{blockchain.ErrAccountNotFound, []string{
+		"accountnotfound", // AccountNotFound and ProgramAccountNotFound
+		"accountnotinitialized",
+	}},
+}
+
+// simulate runs tx against the latest state without checking its signatures,
+// and returns the compute units it consumed. A transaction that fails returns
+// a SimulationError, with the reason decoded from its logs.
+func simulate(ctx context.Context, rpcClient *rpc.Client, tx *solana.Transaction) (uint64, error) {
+	// Nodes reject transactions without a slot for every required signature,
+	// even when signatures aren't verified. Signing replaces the empty ones.
+	if required := int(tx.Message.Header.NumRequiredSignatures); len(tx.Signatures) != required {
+		tx.Signatures = make([]solana.Signature, required)
+	}
+
+	result, err := rpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		SigVerify:  false,
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate transaction: %w", err)
+	}
+	if result == nil || result.Value == nil {
+		return 0, errors.New("failed to simulate transaction: empty result")
+	}
+
+	simulation := result.Value
+	if simulation.Err != nil {
+		return 0, &blockchain.SimulationError{
+			Err:  simulationFailure(simulation.Err, simulation.Logs),
+			Logs: simulation.Logs,
+		}
+	}
+	if simulation.UnitsConsumed == nil {
+		return 0, nil
+	}
+	return *simulation.UnitsConsumed, nil
+}
+
+// simulationFailure returns the reason a simulated transaction failed with
+// txErr, wrapping the matching blockchain error when its logs or txErr are
+// recognised
+func simulationFailure(txErr interface{}, logs []string) error {
+	message := transactionError(txErr)
+	lines := append([]string{message}, logs...)
+
+	for _, failure := range simulationFailures {
+		for _, line := range lines {
+			lower := strings.ToLower(line)
+			for _, pattern := range failure.patterns {
+				if strings.Contains(lower, pattern) {
+					return fmt.Errorf("%w: %s", failure.reason, message)
+				}
+			}
+		}
+	}
+	return errors.New(message)
+}
+
+// limitComputeUnits lowers the compute unit limit of tx to the units it
+// consumed in simulation, plus a margin, so that its priority fee is only paid
+// on what it uses. Transactions without a limit are left unchanged.
+func limitComputeUnits(tx *solana.Transaction, consumed uint64) {
+	if consumed == 0 {
+		return
+	}
+	limit := consumed + consumed*computeUnitMarginPercent/100
+
+	for i, instruction := range tx.Message.Instructions {
+		program, err := tx.Message.Program(instruction.ProgramIDIndex)
+		if err != nil || !program.Equals(solana.ComputeBudget) {
+			continue
+		}
+		if len(instruction.Data) != 5 || instruction.Data[0] != setComputeUnitLimit {
+			continue
+		}
+
+		current := binary.LittleEndian.Uint32(instruction.Data[1:])
+		if limit >= uint64(current) {
+			return
+		}
+		data := make([]byte, 5)
+		data[0] = setComputeUnitLimit
+		binary.LittleEndian.PutUint32(data[1:], uint32(limit))
+		tx.Message.Instructions[i].Data = data
+		return
+	}
+}
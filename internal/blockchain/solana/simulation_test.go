@@ -0,0 +1,213 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"meme-trader/internal/blockchain"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// instructionError is the error of a transaction whose fifth instruction
+// failed with a custom program error
+var instructionError = map[string]interface{}{
+	"InstructionError": []interface{}{4, map[string]interface{}{"Custom": 30}},
+}
+
+func TestSimulationFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    interface{}
+		logs   []string
+		reason error
+	}{
+		{
+			name:   "raydium slippage",
+			err:    instructionError,
+			logs:   []string{"Program 675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8 invoke [1]", "Program log: Error: exceeds desired slippage limit"},
+			reason: blockchain.ErrSlippageExceeded,
+		},
+		{
+			name:   "jupiter slippage",
+			err:    instructionError,
+			logs:   []string{"Program log: AnchorError occurred. Error Code: SlippageToleranceExceeded. Error Number: 6001."},
+			reason: blockchain.ErrSlippageExceeded,
+		},
+		{
+			name:   "pump.fun buy",
+			err:    instructionError,
+			logs:   []string{"Program log: AnchorError thrown in programs/pump/src/lib.rs:640. Error Code: TooMuchSolRequired. Error Number: 6002."},
+			reason: blockchain.ErrSlippageExceeded,
+		},
+		{
+			name:   "token balance",
+			err:    instructionError,
+			logs:   []string{"Program log: Instruction: Transfer", "Program log: Error: insufficient funds"},
+			reason: blockchain.ErrInsufficientFunds,
+		},
+		{
+			name:   "lamports",
+			err:    instructionError,
+			logs:   []string{"Transfer: insufficient lamports 1000, need 2039280"},
+			reason: blockchain.ErrInsufficientFunds,
+		},
+		{name: "fee", err: "InsufficientFundsForFee", reason: blockchain.ErrInsufficientFunds},
+		{name: "unfunded wallet", err: "AccountNotFound", reason: blockchain.ErrAccountNotFound},
+		{
+			name:   "frozen account",
+			err:    instructionError,
+			logs:   []string{"Program log: Error: Account is frozen"},
+			reason: blockchain.ErrAccountFrozen,
+		},
+		{name: "unknown", err: instructionError, logs: []string{"Program log: something else"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := simulationFailure(tt.err, tt.logs)
+			assert.Contains(t, err.Error(), transactionError(tt.err))
+			if tt.reason == nil {
+				for _, failure := range simulationFailures {
+					assert.NotErrorIs(t, err, failure.reason)
+				}
+				return
+			}
+			assert.ErrorIs(t, err, tt.reason)
+		})
+	}
+}
+
+func TestLimitComputeUnits(t *testing.T) {
+	newTx := func() *solana.Transaction {
+		payer := solana.NewWallet().PublicKey()
+		tx, err := solana.NewTransaction(computeBudgetInstructions(defaultSwapComputeUnits, 1_000), solana.Hash{}, solana.TransactionPayer(payer))
+		require.NoError(t, err)
+		return tx
+	}
+	limit := func(tx *solana.Transaction) uint32 {
+		return binary.LittleEndian.Uint32(tx.Message.Instructions[0].Data[1:])
+	}
+
+	tests := []struct {
+		name     string
+		consumed uint64
+		want     uint32
+	}{
+		{name: "with margin", consumed: 60_000, want: 66_000},
+		{name: "never raised", consumed: 190_000, want: defaultSwapComputeUnits},
+		{name: "unknown", consumed: 0, want: defaultSwapComputeUnits},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newTx()
+			limitComputeUnits(tx, tt.consumed)
+			assert.Equal(t, tt.want, limit(tx))
+		})
+	}
+}
+
+// simulatedTransaction decodes the transaction of a simulateTransaction
+// request, checking it is sent the way nodes accept it: with a slot for every
+// required signature, which isn't verified
+func simulatedTransaction(t *testing.T, req rpcRequest) solana.Transaction {
+	var encoded string
+	require.NoError(t, json.Unmarshal(req.Params[0], &encoded))
+	var tx solana.Transaction
+	require.NoError(t, tx.UnmarshalBase64(encoded))
+	assert.Len(t, tx.Signatures, int(tx.Message.Header.NumRequiredSignatures))
+
+	var opts map[string]interface{}
+	require.NoError(t, json.Unmarshal(req.Params[1], &opts))
+	assert.NotEqual(t, true, opts["sigVerify"])
+	return tx
+}
+
+func TestProviderBuySimulation(t *testing.T) {
+	wallets := memoryWalletStore{}
+	owner := storeKey(wallets)
+
+	tests := []struct {
+		name       string
+		simulation map[string]interface{}
+		reason     error
+	}{
+		{
+			name:       "succeeds",
+			simulation: map[string]interface{}{"err": nil, "logs": []string{}, "unitsConsumed": 60_000},
+		},
+		{
+			name: "exceeds slippage",
+			simulation: map[string]interface{}{
+				"err":           instructionError,
+				"logs":          []string{"Program log: Error: exceeds desired slippage limit"},
+				"unitsConsumed": 40_000,
+			},
+			reason: blockchain.ErrSlippageExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []solana.Transaction
+			handle := swapRPCHandler(t, owner.PublicKey(), &sent)
+			server := newRPCServer(t, func(req rpcRequest) interface{} {
+				if req.Method == "simulateTransaction" {
+					simulatedTransaction(t, req)
+					return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": tt.simulation}
+				}
+				return handle(req)
+			})
+
+			store := &memoryTransactionStore{}
+			provider, err := NewProviderWithOptions(ProviderOptions{
+				RPCEndpoint:              server.URL,
+				WSEndpoint:               "ws://127.0.0.1:1",
+				LazyWS:                   true,
+				Signer:                   NewWalletSigner(wallets),
+				Transactions:             store,
+				ConfirmationPollInterval: time.Hour,
+			})
+			require.NoError(t, err)
+			t.Cleanup(func() { provider.Close() })
+
+			tx, err := provider.Buy(context.Background(), blockchain.BuyRequest{
+				WalletAddress: owner.PublicKey().String(),
+				TokenAddress:  usdcMint.String(),
+				Amount:        blockchain.Amount{Value: newBigInt(1_000_000_000), Decimals: solDecimals},
+			})
+
+			if tt.reason == nil {
+				require.NoError(t, err)
+				require.Len(t, sent, 1)
+				// The compute unit limit covers what the simulation consumed, with a margin
+				limit := sent[0].Message.Instructions[0]
+				assert.Equal(t, []byte{setComputeUnitLimit, 0xd0, 0x01, 0x01, 0x00}, []byte(limit.Data))
+				assert.NoError(t, sent[0].VerifySignatures())
+				return
+			}
+
+			// The swap was not sent, but is recorded as failed with the reason
+			assert.Empty(t, sent)
+			assert.True(t, blockchain.IsSimulationError(err))
+			assert.True(t, blockchain.IsPreSendError(err))
+			assert.ErrorIs(t, err, tt.reason)
+
+			require.NotNil(t, tx)
+			assert.Equal(t, blockchain.TransactionStatusFailed, tx.Status)
+			assert.Equal(t, raydiumRoute, tx.Route)
+			assert.Equal(t, `slippage tolerance exceeded: {"InstructionError":[4,{"Custom":30}]}`, tx.ErrorMessage)
+			assert.Equal(t, *tx, store.last())
+
+			var simulationErr *blockchain.SimulationError
+			require.True(t, errors.As(err, &simulationErr))
+			assert.Equal(t, []string{"Program log: Error: exceeds desired slippage limit"}, simulationErr.Logs)
+		})
+	}
+}
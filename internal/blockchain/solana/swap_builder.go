@@ -0,0 +1,247 @@
+package solana
+
+import (
+	"context"
+	"fmt"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// defaultSwapComputeUnits covers a Raydium AMM v4 swap plus the creation of its token accounts
+	defaultSwapComputeUnits = 200_000
+
+	// defaultComputeUnitPrice is the priority fee, in micro-lamports per compute
+	// unit, when recent fees are lower or unknown
+	defaultComputeUnitPrice = 1_000
+
+	// ataCreateIdempotent is the associated token account program instruction
+	// creating an account unless it already exists
+	ataCreateIdempotent = 1
+)
+
+// swapTransaction is an unsigned swap transaction and what it was built from
+type swapTransaction struct {
+	tx                   *solana.Transaction
+	keys                 *raydiumPoolKeys
+	quote                *blockchain.SwapQuote // Set for exact input swaps
+	inputMint            solana.PublicKey
+	outputMint           solana.PublicKey
+	blockhash            solana.Hash
+	lastValidBlockHeight uint64
+}
+
+// buildSwapTransaction assembles the complete swap transaction for req: compute
+// budget, creation of missing token accounts, wrapping of the SOL spent, the
+// swap itself and unwrapping of the SOL left, paid by the wallet. The
+// transaction is left unsigned.
+func (c *RaydiumClient) buildSwapTransaction(ctx context.Context, req SwapRequest) (*swapTransaction, error) {
+	// Validate input
+	owner, err := solana.PublicKeyFromBase58(req.FromAddress)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid from address: %w", err)
+	}
+
+	tokenMint, err := solana.PublicKeyFromBase58(req.TokenAddress)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid token address: %w", err)
+	}
+
+	// Validate the amounts before any RPC call
+	if _, err := encodeSwapRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Resolve the pool and every account the swap touches
+	poolID, err := c.resolvePool(ctx, req.PoolAddress, tokenMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve pool: %w", err)
+	}
+
+	keys, err := c.fetchPoolKeys(ctx, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool keys: %w", err)
+	}
+	if !keys.hasMints(tokenMint, solana.SolMint) {
+		return nil, blockchain.NewValidationError("pool %s does not trade %s against SOL", poolID, tokenMint)
+	}
+
+	// Buying spends SOL for the token, selling the other way around
+	inputMint, outputMint := solana.SolMint, tokenMint
+	if req.Type == blockchain.TransactionTypeSell {
+		inputMint, outputMint = tokenMint, solana.SolMint
+	}
+
+	// Quote exact input swaps, deriving the minimum amount out from the
+	// slippage tolerance unless the caller set it
+	var quote *blockchain.SwapQuote
+	if req.Mode == SwapBaseIn {
+		quote, err = c.quoteSwap(ctx, keys, inputMint, req)
+		if err != nil {
+			return nil, err
+		}
+		req.MinimumAmountOut = quote.MinimumAmountOut
+	}
+
+	data, err := encodeSwapRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The priority fee follows what recent swaps paid to write the pool
+	price := c.fees.computeUnitPrice(ctx, req.ComputeUnitPrice, keys.AmmID)
+	instructions := computeBudgetInstructions(defaultSwapComputeUnits, price)
+
+	// Create the token accounts in the swap itself. A sold token must already
+	// be held, but the wrapped SOL account may not exist yet.
+	sourceAccount, createSource, err := tokenAccount(owner, inputMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source token account: %w", err)
+	}
+	destinationAccount, createDestination, err := tokenAccount(owner, outputMint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get destination token account: %w", err)
+	}
+
+	// SOL goes through the wallet's wrapped SOL account, which only lives for
+	// the swap: a buy funds it with the most SOL the swap may spend, and it is
+	// closed afterwards, returning what is left, and the proceeds of a sell,
+	// as native SOL
+	wrappedAccount := destinationAccount
+	if inputMint.Equals(solana.SolMint) {
+		wrappedAccount = sourceAccount
+		lamports, err := spendLimit(req)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, createSource)
+		instructions = append(instructions, wrapSOL(owner, sourceAccount, lamports)...)
+	}
+	instructions = append(instructions,
+		createDestination,
+		c.buildSwapInstruction(keys, sourceAccount, destinationAccount, owner, data),
+		unwrapSOL(owner, wrappedAccount),
+	)
+
+	// Build the transaction
+	latest, err := c.rpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := newSwapTransaction(ctx, c.lookups, instructions, latest.Value.Blockhash, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &swapTransaction{
+		tx:                   tx,
+		keys:                 keys,
+		quote:                quote,
+		inputMint:            inputMint,
+		outputMint:           outputMint,
+		blockhash:            latest.Value.Blockhash,
+		lastValidBlockHeight: latest.Value.LastValidBlockHeight,
+	}, nil
+}
+
+// quoteSwap quotes req against the pool's current reserves. A minimum amount
+// out set by the caller takes precedence over the slippage tolerance.
+func (c *RaydiumClient) quoteSwap(ctx context.Context, keys *raydiumPoolKeys, inputMint solana.PublicKey, req SwapRequest) (*blockchain.SwapQuote, error) {
+	state, err := c.fetchPoolState(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool state: %w", err)
+	}
+
+	slippageBps := req.SlippageBps
+	if slippageBps == 0 {
+		slippageBps = defaultSlippageBps
+	}
+
+	quote, err := state.quoteExactIn(inputMint, req.Amount.Value, slippageBps)
+	if err != nil {
+		return nil, err
+	}
+
+	withMinimumAmountOut(quote, req.MinimumAmountOut)
+	return quote, nil
+}
+
+// computeBudgetInstructions set the compute unit limit of a transaction and
+// the priority fee paid per compute unit, in micro-lamports
+func computeBudgetInstructions(units uint32, price uint64) []solana.Instruction {
+	return []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(units).Build(),
+		computebudget.NewSetComputeUnitPriceInstruction(price).Build(),
+	}
+}
+
+// spendLimit returns the most req may spend: the amount of an exact input
+// swap, the maximum amount in of an exact output one
+func spendLimit(req SwapRequest) (uint64, error) {
+	if req.Mode == SwapBaseOut {
+		return amountToUint64(req.MaximumAmountIn)
+	}
+	return amountToUint64(req.Amount)
+}
+
+// wrapSOL moves lamports from owner to its wrapped SOL account, and syncs the
+// token balance of the account with them
+func wrapSOL(owner, account solana.PublicKey, lamports uint64) []solana.Instruction {
+	return []solana.Instruction{
+		system.NewTransferInstruction(lamports, owner, account).Build(),
+		token.NewSyncNativeInstruction(account).Build(),
+	}
+}
+
+// unwrapSOL closes the wrapped SOL account of owner, returning its balance and
+// rent to owner as native SOL. Wrapped SOL held before the swap is unwrapped too.
+func unwrapSOL(owner, account solana.PublicKey) solana.Instruction {
+	return token.NewCloseAccountInstruction(account, owner, owner, nil).Build()
+}
+
+// tokenAccount returns the associated token account of owner for mint, along
+// with the instruction creating it, paid by owner. Creation is idempotent, so
+// it succeeds when the account already exists, or is created by another
+// transaction before the swap lands.
+func tokenAccount(owner, mint solana.PublicKey) (solana.PublicKey, solana.Instruction, error) {
+	return tokenAccountPaidBy(owner, owner, mint, solana.TokenProgramID)
+}
+
+// tokenAccountPaidBy is tokenAccount for a mint of tokenProgram, with the
+// account created at the expense of payer, e.g. for the recipient of a transfer
+func tokenAccountPaidBy(payer, owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, solana.Instruction, error) {
+	account, err := associatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+
+	create := solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, solana.AccountMetaSlice{
+		solana.Meta(payer).WRITE().SIGNER(),
+		solana.Meta(account).WRITE(),
+		solana.Meta(owner),
+		solana.Meta(mint),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(tokenProgram),
+	}, []byte{ataCreateIdempotent})
+	return account, create, nil
+}
+
+// associatedTokenAddress returns the associated token account of owner for a
+// mint of tokenProgram, which is part of the address
+func associatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	account, _, err := solana.FindProgramAddress([][]byte{
+		owner[:],
+		tokenProgram[:],
+		mint[:],
+	}, solana.SPLAssociatedTokenAccountProgramID)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to find token account: %w", err)
+	}
+	return account, nil
+}
@@ -0,0 +1,281 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"meme-trader/internal/blockchain"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// bestQuote quotes swapping exactly amount of inputMint for outputMint on
+// Raydium and, when configured, through Jupiter, and returns the quote with
+// the highest output. A route that can't quote the swap is skipped as long as
+// the other one can. Tokens still on their Pump.fun bonding curve only trade
+// there. A minimum amount out set by the caller takes precedence over the
+// slippage tolerance.
+func (p *Provider) bestQuote(ctx context.Context, inputMint, outputMint solana.PublicKey, amount, minimumAmountOut blockchain.Amount, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	if inputMint.Equals(outputMint) {
+		return nil, blockchain.NewValidationError("input and output mints must differ")
+	}
+	amountIn, err := amountToUint64(amount)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid amount: %w", err)
+	}
+	if amountIn == 0 {
+		return nil, blockchain.NewValidationError("amount must be greater than zero")
+	}
+	if slippageBps > basisPoints {
+		return nil, blockchain.NewValidationError("slippage must be at most %d basis points", basisPoints)
+	}
+
+	if quote, err := p.curveQuote(ctx, inputMint, outputMint, amount, minimumAmountOut, slippageBps); err != nil || quote != nil {
+		return quote, err
+	}
+
+	raydiumQuote := func() (*blockchain.SwapQuote, error) {
+		quote, err := p.raydiumClient.Quote(ctx, inputMint, outputMint, amount, slippageBps)
+		if err != nil {
+			return nil, err
+		}
+		withMinimumAmountOut(quote, minimumAmountOut)
+		return quote, nil
+	}
+	if p.jupiterClient == nil {
+		return raydiumQuote()
+	}
+
+	var (
+		wg                     sync.WaitGroup
+		raydium, jupiter       *blockchain.SwapQuote
+		raydiumErr, jupiterErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		raydium, raydiumErr = raydiumQuote()
+	}()
+	go func() {
+		defer wg.Done()
+		jupiter, jupiterErr = p.jupiterQuote(ctx, inputMint, outputMint, amountIn, minimumAmountOut, slippageBps)
+	}()
+	wg.Wait()
+
+	switch {
+	case raydiumErr != nil && jupiterErr != nil:
+		return nil, fmt.Errorf("no route for %s to %s: raydium: %v; jupiter: %v", inputMint, outputMint, raydiumErr, jupiterErr)
+	case jupiterErr != nil:
+		return raydium, nil
+	case raydiumErr != nil:
+		return jupiter, nil
+	}
+
+	// Prefer the direct swap unless the aggregated route pays more
+	if jupiter.ExpectedAmountOut.Value.Cmp(raydium.ExpectedAmountOut.Value) > 0 {
+		return jupiter, nil
+	}
+	return raydium, nil
+}
+
+// curveQuote quotes a swap on the token's Pump.fun bonding curve, or returns
+// nil when the token is not trading on one
+func (p *Provider) curveQuote(ctx context.Context, inputMint, outputMint solana.PublicKey, amount, minimumAmountOut blockchain.Amount, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	quote, err := p.pumpFunClient.Quote(ctx, inputMint, outputMint, amount, slippageBps)
+	if err != nil || quote == nil {
+		return nil, err
+	}
+	withMinimumAmountOut(quote, minimumAmountOut)
+	return quote, nil
+}
+
+// jupiterQuote quotes a swap through Jupiter, with the decimals of both mints
+func (p *Provider) jupiterQuote(ctx context.Context, inputMint, outputMint solana.PublicKey, amount uint64, minimumAmountOut blockchain.Amount, slippageBps uint16) (*blockchain.SwapQuote, error) {
+	if slippageBps == 0 {
+		slippageBps = defaultSlippageBps
+	}
+
+	quote, err := p.jupiterClient.Quote(ctx, inputMint, outputMint, amount, slippageBps)
+	if err != nil {
+		return nil, err
+	}
+
+	// The swap program only takes a slippage tolerance, so the caller's
+	// minimum is honoured with the largest tolerance that stays above it
+	if minimum := minimumAmountOut.Value; minimum != nil && minimum.Sign() > 0 {
+		expected := quote.ExpectedAmountOut.Value
+		if minimum.Cmp(expected) > 0 {
+			return nil, blockchain.NewValidationError("minimum amount out %s exceeds the quoted %s", minimum, expected)
+		}
+		slippage := new(big.Int).Sub(expected, minimum)
+		slippage.Mul(slippage, big.NewInt(basisPoints))
+		if err := setJupiterSlippage(quote, uint16(slippage.Quo(slippage, expected).Uint64())); err != nil {
+			return nil, err
+		}
+	}
+
+	inDecimals, err := p.mintDecimals(ctx, inputMint)
+	if err != nil {
+		return nil, err
+	}
+	outDecimals, err := p.mintDecimals(ctx, outputMint)
+	if err != nil {
+		return nil, err
+	}
+	quote.AmountIn.Decimals = inDecimals
+	quote.Fee.Decimals = inDecimals
+	quote.ExpectedAmountOut.Decimals = outDecimals
+	quote.MinimumAmountOut.Decimals = outDecimals
+	return quote, nil
+}
+
+// mintDecimals returns the decimals of the token minted by mint
+func (p *Provider) mintDecimals(ctx context.Context, mint solana.PublicKey) (uint8, error) {
+	if mint.Equals(solana.SolMint) {
+		return solDecimals, nil
+	}
+
+	supply, err := p.rpcClient.GetTokenSupply(ctx, mint, p.commitment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get decimals of %s: %w", mint, err)
+	}
+	return supply.Value.Decimals, nil
+}
+
+// withMinimumAmountOut replaces the minimum amount out of quote with one set
+// by the caller, if any, and reports the tolerance it implies instead
+func withMinimumAmountOut(quote *blockchain.SwapQuote, minimumAmountOut blockchain.Amount) {
+	if minimumAmountOut.Value == nil || minimumAmountOut.Value.Sign() == 0 {
+		return
+	}
+
+	expected := quote.ExpectedAmountOut.Value
+	quote.MinimumAmountOut = blockchain.Amount{
+		Value:    new(big.Int).Set(minimumAmountOut.Value),
+		Decimals: quote.ExpectedAmountOut.Decimals,
+	}
+	quote.SlippageBps = 0
+	if quote.MinimumAmountOut.Value.Cmp(expected) < 0 {
+		slippage := new(big.Int).Sub(expected, quote.MinimumAmountOut.Value)
+		slippage.Mul(slippage, big.NewInt(basisPoints))
+		quote.SlippageBps = uint16(slippage.Quo(slippage, expected).Uint64())
+	}
+}
+
+// buildSwap builds the unsigned transaction swapping inputMint for outputMint
+// through the route of quote. Without a quote the swap goes through the
+// token's bonding curve if it is still on one, and takes the route with the
+// best output otherwise.
+func (p *Provider) buildSwap(ctx context.Context, req *SwapRequest, quote *blockchain.SwapQuote, inputMint, outputMint solana.PublicKey) (*swapTransaction, error) {
+	if quote == nil {
+		var err error
+		if p.jupiterClient != nil {
+			quote, err = p.bestQuote(ctx, inputMint, outputMint, req.Amount, req.MinimumAmountOut, req.SlippageBps)
+		} else {
+			quote, err = p.curveQuote(ctx, inputMint, outputMint, req.Amount, req.MinimumAmountOut, req.SlippageBps)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to quote swap: %w", err)
+		}
+	}
+
+	// Raydium quotes the swap itself when building it
+	if quote == nil {
+		return p.raydiumClient.buildSwapTransaction(ctx, *req)
+	}
+
+	if err := applyQuote(req, quote, inputMint.String(), outputMint.String()); err != nil {
+		return nil, err
+	}
+
+	switch quote.Route {
+	case jupiterRoute:
+		if p.jupiterClient == nil {
+			return nil, errors.New("jupiter routing is not configured")
+		}
+		owner, err := solana.PublicKeyFromBase58(req.FromAddress)
+		if err != nil {
+			return nil, blockchain.NewValidationError("invalid from address: %w", err)
+		}
+		price := p.fees.computeUnitPrice(ctx, req.ComputeUnitPrice, routePools(quote)...)
+		return p.jupiterClient.buildSwapTransaction(ctx, quote, owner, price)
+
+	case pumpFunRoute:
+		return p.pumpFunClient.buildSwapTransaction(ctx, *req, quote, inputMint, outputMint)
+
+	default:
+		return p.raydiumClient.buildSwapTransaction(ctx, *req)
+	}
+}
+
+// swap builds req through the route of quote, or the best one when quote is
+// nil, simulates it, signs it with the wallet's key and sends it
+func (p *Provider) swap(ctx context.Context, req SwapRequest, quote *blockchain.SwapQuote, inputMint, outputMint solana.PublicKey) (*blockchain.Transaction, error) {
+	swap, err := p.buildSwap(ctx, &req, quote, inputMint, outputMint)
+	if err != nil {
+		return nil, blockchain.NewPreSendError("failed to build swap transaction: %w", err)
+	}
+
+	// A swap that fails in simulation is never broadcast. One that succeeds
+	// only reserves the compute units it needs.
+	units, err := simulate(ctx, p.rpcClient, swap.tx)
+	var simulationErr *blockchain.SimulationError
+	switch {
+	case errors.As(err, &simulationErr):
+		return p.rejectSwap(ctx, req, swap, simulationErr)
+	case err != nil:
+		return nil, &blockchain.PreSendError{Err: err}
+	}
+	limitComputeUnits(swap.tx, units)
+
+	if err := signTransaction(ctx, p.signer, swap.tx); err != nil {
+		return nil, blockchain.NewPreSendError("failed to sign transaction: %w", err)
+	}
+
+	sig, err := p.rpcClient.SendTransaction(ctx, swap.tx)
+	if err != nil {
+		return nil, classifySendError(err)
+	}
+
+	record := swapRecord(req, sig, swap.blockhash)
+	record.Route = routeOf(swap)
+	p.tracker.track(record, swap.tx, swap.lastValidBlockHeight)
+	return record, nil
+}
+
+// rejectSwap records swap, which failed in simulation with simulationErr, as
+// failed without sending it. It is signed so that it has an ID.
+func (p *Provider) rejectSwap(ctx context.Context, req SwapRequest, swap *swapTransaction, simulationErr *blockchain.SimulationError) (*blockchain.Transaction, error) {
+	if err := signTransaction(ctx, p.signer, swap.tx); err != nil {
+		return nil, blockchain.NewPreSendError("failed to sign transaction: %w", err)
+	}
+
+	record := swapRecord(req, swap.tx.Signatures[0], swap.blockhash)
+	record.Route = routeOf(swap)
+	record.Status = blockchain.TransactionStatusFailed
+	record.ErrorMessage = simulationErr.Err.Error()
+	p.tracker.saveUnsent(record)
+	return record, &blockchain.PreSendError{Err: simulationErr}
+}
+
+// routeOf returns the route swap goes through
+func routeOf(swap *swapTransaction) string {
+	if swap.quote != nil {
+		return swap.quote.Route
+	}
+	return raydiumRoute
+}
+
+// routePools returns the pools a quote is routed through
+func routePools(quote *blockchain.SwapQuote) []solana.PublicKey {
+	var pools []solana.PublicKey
+	for _, pool := range strings.Split(quote.Pool, ",") {
+		if key, err := solana.PublicKeyFromBase58(pool); err == nil {
+			pools = append(pools, key)
+		}
+	}
+	return pools
+}
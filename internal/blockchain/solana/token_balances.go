@@ -0,0 +1,90 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	token2022ProgramID = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+
+	// tokenPrograms are the programs whose accounts hold SPL tokens
+	tokenPrograms = []solana.PublicKey{solana.TokenProgramID, token2022ProgramID}
+)
+
+// parsedTokenAccount is the jsonParsed encoding of a token account
+type parsedTokenAccount struct {
+	Parsed struct {
+		Info struct {
+			Mint        string `json:"mint"`
+			TokenAmount struct {
+				Amount         string `json:"amount"`
+				Decimals       uint8  `json:"decimals"`
+				UIAmountString string `json:"uiAmountString"`
+			} `json:"tokenAmount"`
+		} `json:"info"`
+	} `json:"parsed"`
+}
+
+// GetTokenBalances lists the Token and Token-2022 accounts owned by address,
+// including empty ones
+func (p *Provider) GetTokenBalances(ctx context.Context, address string) ([]blockchain.TokenBalance, error) {
+	owner, err := solana.PublicKeyFromBase58(address)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid address: %w", err)
+	}
+
+	var balances []blockchain.TokenBalance
+	for _, program := range tokenPrograms {
+		program := program
+		result, err := p.rpcClient.GetTokenAccountsByOwner(ctx, owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: &program},
+			&rpc.GetTokenAccountsOpts{Encoding: solana.EncodingJSONParsed, Commitment: p.commitment},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token accounts of program %s: %w", program, err)
+		}
+
+		for _, account := range result.Value {
+			balance, err := tokenBalanceFromAccount(account, program)
+			if err != nil {
+				return nil, err
+			}
+			balances = append(balances, balance)
+		}
+	}
+
+	return balances, nil
+}
+
+// tokenBalanceFromAccount reads the balance of a jsonParsed token account
+func tokenBalanceFromAccount(account *rpc.TokenAccount, program solana.PublicKey) (blockchain.TokenBalance, error) {
+	if account.Account.Data == nil {
+		return blockchain.TokenBalance{}, fmt.Errorf("token account %s has no data", account.Pubkey)
+	}
+
+	var parsed parsedTokenAccount
+	if err := json.Unmarshal(account.Account.Data.GetRawJSON(), &parsed); err != nil {
+		return blockchain.TokenBalance{}, fmt.Errorf("failed to parse token account %s: %w", account.Pubkey, err)
+	}
+
+	info := parsed.Parsed.Info
+	amount, ok := new(big.Int).SetString(info.TokenAmount.Amount, 10)
+	if !ok {
+		return blockchain.TokenBalance{}, fmt.Errorf("invalid amount %q in token account %s", info.TokenAmount.Amount, account.Pubkey)
+	}
+
+	return blockchain.TokenBalance{
+		Account:  account.Pubkey.String(),
+		Mint:     info.Mint,
+		Program:  program.String(),
+		Amount:   blockchain.Amount{Value: amount, Decimals: info.TokenAmount.Decimals},
+		UIAmount: info.TokenAmount.UIAmountString,
+	}, nil
+}
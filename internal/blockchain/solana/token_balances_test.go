@@ -0,0 +1,107 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parsedTokenAccountResult is a jsonParsed getTokenAccountsByOwner entry
+func parsedTokenAccountResult(account, owner, mint, program solana.PublicKey, amount string, decimals uint8, uiAmount string) map[string]interface{} {
+	return map[string]interface{}{
+		"pubkey": account.String(),
+		"account": map[string]interface{}{
+			"data": map[string]interface{}{
+				"program": "spl-token",
+				"parsed": map[string]interface{}{
+					"type": "account",
+					"info": map[string]interface{}{
+						"isNative": false,
+						"mint":     mint.String(),
+						"owner":    owner.String(),
+						"state":    "initialized",
+						"tokenAmount": map[string]interface{}{
+							"amount":         amount,
+							"decimals":       decimals,
+							"uiAmountString": uiAmount,
+						},
+					},
+				},
+				"space": 165,
+			},
+			"executable": false,
+			"lamports":   2039280,
+			"owner":      program.String(),
+			"rentEpoch":  0,
+		},
+	}
+}
+
+func TestProviderGetTokenBalances(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	tokenAccount, token2022Account := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	token2022Mint := solana.NewWallet().PublicKey()
+
+	var programs []string
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		require.Equal(t, "getTokenAccountsByOwner", req.Method)
+
+		var address string
+		require.NoError(t, json.Unmarshal(req.Params[0], &address))
+		assert.Equal(t, owner.String(), address)
+
+		var filter struct {
+			ProgramID string `json:"programId"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params[1], &filter))
+		programs = append(programs, filter.ProgramID)
+
+		var opts struct {
+			Encoding string `json:"encoding"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params[2], &opts))
+		assert.Equal(t, "jsonParsed", opts.Encoding)
+
+		var accounts []interface{}
+		switch filter.ProgramID {
+		case solana.TokenProgramID.String():
+			accounts = append(accounts, parsedTokenAccountResult(tokenAccount, owner, usdcMint, solana.TokenProgramID, "12500000", 6, "12.5"))
+		case token2022ProgramID.String():
+			accounts = append(accounts, parsedTokenAccountResult(token2022Account, owner, token2022Mint, token2022ProgramID, "7", 0, "7"))
+		}
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value":   accounts,
+		}
+	})
+
+	provider := newTestProvider(t, server.URL, nil)
+	balances, err := provider.GetTokenBalances(context.Background(), owner.String())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{solana.TokenProgramID.String(), token2022ProgramID.String()}, programs)
+	require.Len(t, balances, 2)
+
+	assert.Equal(t, tokenAccount.String(), balances[0].Account)
+	assert.Equal(t, usdcMint.String(), balances[0].Mint)
+	assert.Equal(t, solana.TokenProgramID.String(), balances[0].Program)
+	assert.Equal(t, int64(12500000), balances[0].Amount.Value.Int64())
+	assert.Equal(t, uint8(6), balances[0].Amount.Decimals)
+	assert.Equal(t, "12.5", balances[0].UIAmount)
+
+	assert.Equal(t, token2022Account.String(), balances[1].Account)
+	assert.Equal(t, token2022Mint.String(), balances[1].Mint)
+	assert.Equal(t, token2022ProgramID.String(), balances[1].Program)
+	assert.Equal(t, int64(7), balances[1].Amount.Value.Int64())
+}
+
+func TestProviderGetTokenBalancesInvalidAddress(t *testing.T) {
+	provider := newTestProvider(t, "http://127.0.0.1:1", nil)
+	_, err := provider.GetTokenBalances(context.Background(), "not an address")
+	assert.True(t, blockchain.IsValidationError(err), "got %v", err)
+}
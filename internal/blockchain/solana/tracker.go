@@ -0,0 +1,267 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"meme-trader/internal/blockchain"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// errBlockhashExpired is recorded on transactions that never landed
+const errBlockhashExpired = "transaction expired: blockhash is no longer valid"
+
+// confirmationTracker follows sent transactions from pending to confirmed and
+// finalized, or failed, and saves every status change. It is woken up by
+// signatureSubscribe notifications and polls getSignatureStatuses in case the
+// WebSocket is unavailable or a notification is missed.
+type confirmationTracker struct {
+	rpcClient    *rpc.Client
+	ws           func(context.Context) (*ws.Client, error)
+	store        blockchain.TransactionStore
+	pollInterval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newConfirmationTracker(rpcClient *rpc.Client, wsClient func(context.Context) (*ws.Client, error), store blockchain.TransactionStore, pollInterval time.Duration) *confirmationTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &confirmationTracker{
+		rpcClient:    rpcClient,
+		ws:           wsClient,
+		store:        store,
+		pollInterval: pollInterval,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// trackedTransaction is a sent transaction and its latest known state
+type trackedTransaction struct {
+	record               blockchain.Transaction
+	signature            solana.Signature
+	tx                   *solana.Transaction // Rebroadcast until it lands or its blockhash expires
+	lastValidBlockHeight uint64
+	detailed             bool // Slot, block time and fee have been fetched
+	unsaved              bool // The latest change hasn't been saved yet
+}
+
+// track follows tx, sent as record, until it is finalized, fails or its
+// blockhash expires after lastValidBlockHeight. record is copied, so the
+// caller keeps ownership of it.
+func (t *confirmationTracker) track(record *blockchain.Transaction, tx *solana.Transaction, lastValidBlockHeight uint64) {
+	tracked := &trackedTransaction{
+		record:               *record,
+		signature:            tx.Signatures[0],
+		tx:                   tx,
+		lastValidBlockHeight: lastValidBlockHeight,
+		unsaved:              true,
+	}
+
+	t.wg.Add(1)
+	go t.follow(tracked)
+}
+
+// stop stops tracking and waits for the tracking goroutines to return
+func (t *confirmationTracker) stop() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+func (t *confirmationTracker) follow(tracked *trackedTransaction) {
+	defer t.wg.Done()
+
+	notified, unsubscribe := t.subscribe(tracked.signature)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	// Save the pending transaction before it changes
+	t.save(tracked)
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-notified:
+			// Confirmed; check right away rather than on the next tick
+			notified = nil
+		case <-ticker.C:
+		}
+
+		done := t.check(t.ctx, tracked)
+		t.save(tracked)
+		if done && !tracked.unsaved {
+			return
+		}
+	}
+}
+
+// subscribe asks for a notification once signature is confirmed. The returned
+// channel is nil, and never fires, when the WebSocket is unavailable.
+func (t *confirmationTracker) subscribe(signature solana.Signature) (<-chan struct{}, func()) {
+	wsClient, err := t.ws(t.ctx)
+	if err != nil {
+		return nil, func() {}
+	}
+
+	sub, err := wsClient.SignatureSubscribe(signature, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, func() {}
+	}
+
+	notified := make(chan struct{}, 1)
+	go func() {
+		if result, err := sub.Recv(); err == nil && result != nil {
+			notified <- struct{}{}
+		}
+	}()
+
+	return notified, sub.Unsubscribe
+}
+
+// check updates tracked from the cluster and reports whether it reached a
+// final status
+func (t *confirmationTracker) check(ctx context.Context, tracked *trackedTransaction) bool {
+	statuses, err := t.rpcClient.GetSignatureStatuses(ctx, false, tracked.signature)
+	if err != nil || len(statuses.Value) == 0 {
+		// Try again on the next tick
+		return false
+	}
+
+	status := statuses.Value[0]
+	if status == nil {
+		return t.checkExpiry(ctx, tracked)
+	}
+
+	switch {
+	case status.Err != nil:
+		t.update(ctx, tracked, blockchain.TransactionStatusFailed, status.Slot, transactionError(status.Err))
+		return true
+	case status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+		t.update(ctx, tracked, blockchain.TransactionStatusFinalized, status.Slot, "")
+		return true
+	case status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed:
+		t.update(ctx, tracked, blockchain.TransactionStatusConfirmed, status.Slot, "")
+	}
+
+	return false
+}
+
+// checkExpiry fails tracked once its blockhash has expired without it
+// landing, and rebroadcasts it until then
+func (t *confirmationTracker) checkExpiry(ctx context.Context, tracked *trackedTransaction) bool {
+	height, err := t.rpcClient.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return false
+	}
+
+	if height > tracked.lastValidBlockHeight {
+		t.update(ctx, tracked, blockchain.TransactionStatusFailed, 0, errBlockhashExpired)
+		return true
+	}
+
+	t.rebroadcast(ctx, tracked)
+	return false
+}
+
+// update moves tracked to status, fetching its slot, block time and fee the
+// first time it is seen on-chain
+func (t *confirmationTracker) update(ctx context.Context, tracked *trackedTransaction, status blockchain.TransactionStatus, slot uint64, errMessage string) {
+	changed := tracked.record.Status != status
+	if slot > 0 && !tracked.detailed && t.fetchDetails(ctx, tracked) {
+		tracked.detailed = true
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	if slot > 0 {
+		tracked.record.BlockNumber = slot
+	}
+	if errMessage != "" {
+		tracked.record.ErrorMessage = errMessage
+	}
+	tracked.record.Status = status
+	tracked.record.LastUpdatedAt = time.Now().Unix()
+	tracked.unsaved = true
+}
+
+// fetchDetails records the block time, fee and amount received of tracked,
+// and reports whether they were available
+func (t *confirmationTracker) fetchDetails(ctx context.Context, tracked *trackedTransaction) bool {
+	maxVersion := uint64(0)
+	result, err := t.rpcClient.GetTransaction(ctx, tracked.signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return false
+	}
+
+	wallet, _ := solana.PublicKeyFromBase58(tracked.record.FromAddress)
+	decoded, err := decodeTransaction(tracked.signature.String(), result, wallet)
+	if err != nil {
+		return false
+	}
+
+	tracked.record.Timestamp = decoded.Timestamp
+	tracked.record.GasFee = decoded.GasFee
+	if decoded.Type == tracked.record.Type {
+		tracked.record.AmountOut = decoded.AmountOut
+	}
+	return true
+}
+
+// rebroadcast sends tracked again while it hasn't landed, as validators drop
+// transactions under load
+func (t *confirmationTracker) rebroadcast(ctx context.Context, tracked *trackedTransaction) {
+	// A failed rebroadcast is harmless: the original send may still land
+	t.rpcClient.SendTransactionWithOpts(ctx, tracked.tx, rpc.TransactionOpts{
+		SkipPreflight: true,
+	})
+}
+
+// save persists the latest change of tracked, if any. Failed saves are
+// retried on the next check.
+func (t *confirmationTracker) save(tracked *trackedTransaction) {
+	if !tracked.unsaved || t.store == nil {
+		tracked.unsaved = false
+		return
+	}
+
+	record := tracked.record
+	if err := t.store.SaveTransaction(&record); err == nil {
+		tracked.unsaved = false
+	}
+}
+
+// saveUnsent saves record, which failed before it was sent and so is not
+// tracked. Saving is best effort, as the caller is told of the failure anyway.
+func (t *confirmationTracker) saveUnsent(record *blockchain.Transaction) {
+	if t.store == nil {
+		return
+	}
+	saved := *record
+	t.store.SaveTransaction(&saved)
+}
+
+// transactionError formats the error of a failed transaction, such as
+// {"InstructionError":[3,{"Custom":30}]}
+func transactionError(err interface{}) string {
+	encoded, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		return fmt.Sprint(err)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,226 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"meme-trader/internal/blockchain"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTransactionStore records every saved version of each transaction
+type memoryTransactionStore struct {
+	saved []blockchain.Transaction
+	mu    sync.Mutex
+}
+
+func (m *memoryTransactionStore) SaveTransaction(tx *blockchain.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.saved = append(m.saved, *tx)
+	return nil
+}
+
+// statuses returns the saved statuses in order
+func (m *memoryTransactionStore) statuses() []blockchain.TransactionStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]blockchain.TransactionStatus, len(m.saved))
+	for i, tx := range m.saved {
+		statuses[i] = tx.Status
+	}
+	return statuses
+}
+
+// last returns the last saved transaction
+func (m *memoryTransactionStore) last() blockchain.Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.saved[len(m.saved)-1]
+}
+
+// trackerChain is the cluster state served to the tracker
+type trackerChain struct {
+	statuses    []interface{} // getSignatureStatuses answers in turn, the last one repeated
+	blockHeight uint64
+	sent        int
+	mu          sync.Mutex
+}
+
+func (c *trackerChain) handle(t *testing.T, tx *solana.Transaction) func(req rpcRequest) interface{} {
+	return func(req rpcRequest) interface{} {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		switch req.Method {
+		case "getSignatureStatuses":
+			status := c.statuses[0]
+			if len(c.statuses) > 1 {
+				c.statuses = c.statuses[1:]
+			}
+			return map[string]interface{}{
+				"context": map[string]interface{}{"slot": 1},
+				"value":   []interface{}{status},
+			}
+
+		case "getBlockHeight":
+			return c.blockHeight
+
+		case "sendTransaction":
+			c.sent++
+			return tx.Signatures[0].String()
+
+		case "getTransaction":
+			encoded, err := tx.ToBase64()
+			require.NoError(t, err)
+			return map[string]interface{}{
+				"slot":        100,
+				"blockTime":   1700000000,
+				"transaction": []string{encoded, "base64"},
+				"meta": map[string]interface{}{
+					"err":          nil,
+					"fee":          5000,
+					"preBalances":  []uint64{},
+					"postBalances": []uint64{},
+				},
+			}
+		}
+
+		t.Errorf("unexpected RPC method %s", req.Method)
+		return nil
+	}
+}
+
+func (c *trackerChain) sends() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sent
+}
+
+// status builds a getSignatureStatuses entry
+func status(confirmation string, err interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"slot":               100,
+		"confirmations":      nil,
+		"err":                err,
+		"confirmationStatus": confirmation,
+	}
+}
+
+// startTracking tracks a signed transfer against chain, without a WebSocket
+func startTracking(t *testing.T, chain *trackerChain, store *memoryTransactionStore) *blockchain.Transaction {
+	key := solana.NewWallet().PrivateKey
+	tx := newTransferTransaction(t, key.PublicKey(), key.PublicKey(), solana.NewWallet().PublicKey())
+	_, err := tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &key })
+	require.NoError(t, err)
+
+	server := newRPCServer(t, chain.handle(t, tx))
+	rpcClient := rpc.New(server.URL)
+	noWS := func(context.Context) (*ws.Client, error) { return nil, errors.New("no websocket") }
+
+	tracker := newConfirmationTracker(rpcClient, noWS, store, 5*time.Millisecond)
+	t.Cleanup(tracker.stop)
+
+	record := &blockchain.Transaction{
+		ID:        tx.Signatures[0].String(),
+		Network:   blockchain.NetworkSolana,
+		Type:      blockchain.TransactionTypeBuy,
+		Status:    blockchain.TransactionStatusPending,
+		Signature: tx.Signatures[0].String(),
+	}
+	tracker.track(record, tx, 50)
+	return record
+}
+
+func TestConfirmationTrackerFinalizes(t *testing.T) {
+	chain := &trackerChain{
+		statuses: []interface{}{
+			nil,
+			status("processed", nil),
+			status("confirmed", nil),
+			status("finalized", nil),
+		},
+		blockHeight: 10,
+	}
+	store := &memoryTransactionStore{}
+	record := startTracking(t, chain, store)
+
+	require.Eventually(t, func() bool {
+		statuses := store.statuses()
+		return len(statuses) > 0 && statuses[len(statuses)-1] == blockchain.TransactionStatusFinalized
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, []blockchain.TransactionStatus{
+		blockchain.TransactionStatusPending,
+		blockchain.TransactionStatusConfirmed,
+		blockchain.TransactionStatusFinalized,
+	}, store.statuses())
+
+	final := store.last()
+	assert.Equal(t, record.ID, final.ID)
+	assert.Equal(t, uint64(100), final.BlockNumber)
+	assert.Equal(t, int64(1700000000), final.Timestamp)
+	assert.Equal(t, int64(5000), final.GasFee.Value.Int64())
+	assert.Equal(t, uint8(solDecimals), final.GasFee.Decimals)
+	assert.Empty(t, final.ErrorMessage)
+
+	// Rebroadcast while unknown to the cluster, and the caller's copy is untouched
+	assert.Equal(t, 1, chain.sends())
+	assert.Equal(t, blockchain.TransactionStatusPending, record.Status)
+}
+
+func TestConfirmationTrackerRecordsFailure(t *testing.T) {
+	instructionErr := map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 1}}}
+	chain := &trackerChain{
+		statuses:    []interface{}{status("confirmed", instructionErr)},
+		blockHeight: 10,
+	}
+	store := &memoryTransactionStore{}
+	startTracking(t, chain, store)
+
+	require.Eventually(t, func() bool {
+		return len(store.statuses()) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	final := store.last()
+	assert.Equal(t, blockchain.TransactionStatusFailed, final.Status)
+	assert.JSONEq(t, `{"InstructionError":[0,{"Custom":1}]}`, final.ErrorMessage)
+	assert.Equal(t, uint64(100), final.BlockNumber)
+	assert.Equal(t, int64(5000), final.GasFee.Value.Int64())
+}
+
+func TestConfirmationTrackerExpires(t *testing.T) {
+	chain := &trackerChain{
+		statuses:    []interface{}{nil},
+		blockHeight: 51,
+	}
+	store := &memoryTransactionStore{}
+	startTracking(t, chain, store)
+
+	require.Eventually(t, func() bool {
+		return len(store.statuses()) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	final := store.last()
+	assert.Equal(t, blockchain.TransactionStatusFailed, final.Status)
+	assert.Equal(t, errBlockhashExpired, final.ErrorMessage)
+	assert.Zero(t, chain.sends())
+}
+
+func TestTransactionError(t *testing.T) {
+	var err interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"InstructionError":[3,{"Custom":30}]}`), &err))
+	assert.Equal(t, `{"InstructionError":[3,{"Custom":30}]}`, transactionError(err))
+	assert.Equal(t, `"AccountNotFound"`, transactionError("AccountNotFound"))
+}
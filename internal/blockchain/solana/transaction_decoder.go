@@ -0,0 +1,289 @@
+package solana
+
+import (
+	"fmt"
+	"math/big"
+	"meme-trader/internal/blockchain"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Routes that trades are reported under
+const (
+	raydiumRoute = "raydium"
+	jupiterRoute = "jupiter"
+	pumpFunRoute = "pumpfun"
+)
+
+var (
+	jupiterV6ProgramID = solana.MustPublicKeyFromBase58("JUP6LkbZbjS1jKKwapdHNy74zcZ3tLUZoi5QNyVTaV4")
+	pumpFunProgramID   = solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P")
+
+	// routePrograms maps the DEX programs trades go through to their route
+	routePrograms = map[solana.PublicKey]string{
+		raydiumAmmV4ProgramID:       raydiumRoute,
+		raydiumAmmV4DevnetProgramID: raydiumRoute,
+		jupiterV6ProgramID:          jupiterRoute,
+		pumpFunProgramID:            pumpFunRoute,
+	}
+)
+
+// decodeTransaction converts a transaction fetched with getTransaction into a
+// trade record, as seen by wallet. An empty wallet stands for the fee payer.
+// Swaps between SOL and a token are recognized from the balance changes of
+// the wallet, whichever program made them.
+func decodeTransaction(signature string, result *rpc.GetTransactionResult, wallet solana.PublicKey) (*blockchain.Transaction, error) {
+	if result.Transaction == nil || result.Meta == nil {
+		return nil, fmt.Errorf("transaction %s has no data or metadata", signature)
+	}
+
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction %s: %w", signature, err)
+	}
+
+	meta := result.Meta
+	accounts := transactionAccounts(tx, meta)
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("transaction %s has no accounts", signature)
+	}
+	if wallet.IsZero() {
+		wallet = accounts[0]
+	}
+
+	record := &blockchain.Transaction{
+		ID:          signature,
+		Network:     blockchain.NetworkSolana,
+		Status:      blockchain.TransactionStatusConfirmed,
+		FromAddress: wallet.String(),
+		Route:       transactionRoute(tx, meta, accounts),
+		Signature:   signature,
+		BlockHash:   tx.Message.RecentBlockhash.String(),
+		BlockNumber: result.Slot,
+		GasFee: blockchain.Amount{
+			Value:    new(big.Int).SetUint64(meta.Fee),
+			Decimals: solDecimals,
+		},
+	}
+	if result.BlockTime != nil {
+		record.Timestamp = int64(*result.BlockTime)
+		record.CreatedAt = record.Timestamp
+		record.LastUpdatedAt = record.Timestamp
+	}
+	if meta.Err != nil {
+		record.Status = blockchain.TransactionStatusFailed
+		record.ErrorMessage = transactionError(meta.Err)
+	}
+
+	changes, err := walletBalanceChanges(meta, accounts, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read balances of transaction %s: %w", signature, err)
+	}
+	changes.applySwap(record)
+
+	return record, nil
+}
+
+// transactionAccounts lists the accounts of tx in the order used by the
+// balances and instructions, including those loaded from lookup tables
+func transactionAccounts(tx *solana.Transaction, meta *rpc.TransactionMeta) solana.PublicKeySlice {
+	accounts := make(solana.PublicKeySlice, 0, len(tx.Message.AccountKeys)+len(meta.LoadedAddresses.Writable)+len(meta.LoadedAddresses.ReadOnly))
+	accounts = append(accounts, tx.Message.AccountKeys...)
+	accounts = append(accounts, meta.LoadedAddresses.Writable...)
+	return append(accounts, meta.LoadedAddresses.ReadOnly...)
+}
+
+// transactionRoute returns the route of the first DEX program invoked by tx,
+// preferring the programs called directly, such as an aggregator, over those
+// they call
+func transactionRoute(tx *solana.Transaction, meta *rpc.TransactionMeta, accounts solana.PublicKeySlice) string {
+	route := func(instructions []solana.CompiledInstruction) string {
+		for _, instruction := range instructions {
+			if int(instruction.ProgramIDIndex) >= len(accounts) {
+				continue
+			}
+			if route, ok := routePrograms[accounts[instruction.ProgramIDIndex]]; ok {
+				return route
+			}
+		}
+		return ""
+	}
+
+	if r := route(tx.Message.Instructions); r != "" {
+		return r
+	}
+	for _, inner := range meta.InnerInstructions {
+		if r := route(inner.Instructions); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+// balanceChanges is how much SOL and tokens a wallet gained, negative when spent
+type balanceChanges struct {
+	lamports *big.Int // Native and wrapped SOL, excluding the fee and token account rent
+	tokens   map[solana.PublicKey]*tokenChange
+}
+
+type tokenChange struct {
+	amount   *big.Int
+	decimals uint8
+}
+
+// walletBalanceChanges sums the SOL and token balance changes of wallet.
+// Wrapped SOL counts as SOL, and the rent of token accounts opened or closed
+// by the transaction is left out, so that only the traded amounts remain.
+func walletBalanceChanges(meta *rpc.TransactionMeta, accounts solana.PublicKeySlice, wallet solana.PublicKey) (*balanceChanges, error) {
+	changes := &balanceChanges{
+		lamports: new(big.Int),
+		tokens:   make(map[solana.PublicKey]*tokenChange),
+	}
+
+	for i, account := range accounts {
+		if !account.Equals(wallet) || i >= len(meta.PreBalances) || i >= len(meta.PostBalances) {
+			continue
+		}
+		changes.lamports.Add(changes.lamports, lamportChange(meta, i))
+		if i == 0 {
+			// The fee payer also paid the fee, which isn't part of the trade
+			changes.lamports.Add(changes.lamports, new(big.Int).SetUint64(meta.Fee))
+		}
+	}
+
+	pre, err := walletTokenBalances(meta.PreTokenBalances, wallet)
+	if err != nil {
+		return nil, err
+	}
+	post, err := walletTokenBalances(meta.PostTokenBalances, wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	for index := range union(pre, post) {
+		before, after := pre[index], post[index]
+
+		balance := after
+		if balance == nil {
+			balance = before
+		}
+		delta := new(big.Int)
+		if after != nil {
+			delta.Add(delta, after.amount)
+		}
+		if before != nil {
+			delta.Sub(delta, before.amount)
+		}
+
+		if balance.mint.Equals(solana.SolMint) {
+			changes.lamports.Add(changes.lamports, delta)
+		} else {
+			change, ok := changes.tokens[balance.mint]
+			if !ok {
+				change = &tokenChange{amount: new(big.Int), decimals: balance.decimals}
+				changes.tokens[balance.mint] = change
+			}
+			change.amount.Add(change.amount, delta)
+		}
+
+		// Rent paid for a new token account, or refunded by closing one, went
+		// through the wallet's lamports
+		if int(index) < len(meta.PreBalances) && int(index) < len(meta.PostBalances) && (before == nil) != (after == nil) {
+			rent := new(big.Int).Abs(lamportChange(meta, int(index)))
+			if balance.mint.Equals(solana.SolMint) {
+				rent.Sub(rent, balance.amount)
+			}
+			if before == nil {
+				changes.lamports.Add(changes.lamports, rent)
+			} else {
+				changes.lamports.Sub(changes.lamports, rent)
+			}
+		}
+	}
+
+	return changes, nil
+}
+
+// applySwap records changes on record as a buy or a sell when the wallet
+// traded SOL for a single token, or the other way around
+func (c *balanceChanges) applySwap(record *blockchain.Transaction) {
+	var mint solana.PublicKey
+	var token *tokenChange
+	for m, change := range c.tokens {
+		if change.amount.Sign() == 0 {
+			continue
+		}
+		if token != nil {
+			// Token to token swaps aren't buys or sells
+			return
+		}
+		mint, token = m, change
+	}
+	if token == nil {
+		return
+	}
+
+	sol := blockchain.Amount{Value: new(big.Int).Abs(c.lamports), Decimals: solDecimals}
+	tokens := blockchain.Amount{Value: new(big.Int).Abs(token.amount), Decimals: token.decimals}
+
+	switch {
+	case token.amount.Sign() > 0 && c.lamports.Sign() < 0:
+		record.Type = blockchain.TransactionTypeBuy
+		record.Amount, record.AmountOut = sol, tokens
+	case token.amount.Sign() < 0 && c.lamports.Sign() > 0:
+		record.Type = blockchain.TransactionTypeSell
+		record.Amount, record.AmountOut = tokens, sol
+	default:
+		return
+	}
+	record.ToAddress = mint.String()
+	record.TokenAddress = mint.String()
+}
+
+// tokenBalance is the balance of a token account
+type tokenBalance struct {
+	mint     solana.PublicKey
+	amount   *big.Int
+	decimals uint8
+}
+
+// walletTokenBalances returns the balances of the token accounts owned by
+// wallet, by account index
+func walletTokenBalances(balances []rpc.TokenBalance, wallet solana.PublicKey) (map[uint16]*tokenBalance, error) {
+	owned := make(map[uint16]*tokenBalance)
+	for _, balance := range balances {
+		if balance.Owner == nil || !balance.Owner.Equals(wallet) || balance.UiTokenAmount == nil {
+			continue
+		}
+
+		amount, ok := new(big.Int).SetString(balance.UiTokenAmount.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid token amount %q", balance.UiTokenAmount.Amount)
+		}
+		owned[balance.AccountIndex] = &tokenBalance{
+			mint:     balance.Mint,
+			amount:   amount,
+			decimals: balance.UiTokenAmount.Decimals,
+		}
+	}
+	return owned, nil
+}
+
+// lamportChange returns the change of the lamports of the account at index
+func lamportChange(meta *rpc.TransactionMeta, index int) *big.Int {
+	change := new(big.Int).SetUint64(meta.PostBalances[index])
+	return change.Sub(change, new(big.Int).SetUint64(meta.PreBalances[index]))
+}
+
+// union returns the account indexes present in either balance set
+func union(a, b map[uint16]*tokenBalance) map[uint16]struct{} {
+	indexes := make(map[uint16]struct{}, len(a)+len(b))
+	for index := range a {
+		indexes[index] = struct{}{}
+	}
+	for index := range b {
+		indexes[index] = struct{}{}
+	}
+	return indexes
+}
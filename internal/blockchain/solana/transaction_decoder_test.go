@@ -0,0 +1,395 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"meme-trader/internal/blockchain"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tokenAccountRent = 2_039_280
+	testFee          = 5_000
+	testSlot         = 250_000_000
+	testBlockTime    = 1_700_000_000
+)
+
+var testTokenMint = solana.MustPublicKeyFromBase58("7GCihgDB8fe6KNjn2MYtkzZcRjQy3t9GHdC8uHYmW2hr")
+
+// tradeFixture is a signed trade and the accounts it touches
+type tradeFixture struct {
+	tx           *solana.Transaction
+	wallet       solana.PublicKey
+	tokenAccount solana.PublicKey
+	wsolAccount  solana.PublicKey
+	poolVault    solana.PublicKey
+}
+
+// newTradeFixture builds a transaction calling program with the wallet, its
+// token accounts and a pool vault, and the inner program if any
+func newTradeFixture(t *testing.T, program solana.PublicKey, inner solana.PublicKey) *tradeFixture {
+	key := solana.NewWallet().PrivateKey
+	f := &tradeFixture{
+		wallet:       key.PublicKey(),
+		tokenAccount: solana.NewWallet().PublicKey(),
+		wsolAccount:  solana.NewWallet().PublicKey(),
+		poolVault:    solana.NewWallet().PublicKey(),
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(f.wallet).WRITE().SIGNER(),
+		solana.Meta(f.tokenAccount).WRITE(),
+		solana.Meta(f.wsolAccount).WRITE(),
+		solana.Meta(f.poolVault).WRITE(),
+	}
+	if !inner.IsZero() {
+		accounts = append(accounts, solana.Meta(inner))
+	}
+
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{solana.NewInstruction(program, accounts, []byte{9})},
+		solana.Hash{1},
+		solana.TransactionPayer(f.wallet),
+	)
+	require.NoError(t, err)
+	_, err = tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &key })
+	require.NoError(t, err)
+
+	f.tx = tx
+	return f
+}
+
+// index returns the position of account in the transaction
+func (f *tradeFixture) index(account solana.PublicKey) int {
+	for i, key := range f.tx.Message.AccountKeys {
+		if key.Equals(account) {
+			return i
+		}
+	}
+	return -1
+}
+
+// lamports returns balances for every account, with the given overrides
+func (f *tradeFixture) lamports(overrides map[solana.PublicKey]uint64) []uint64 {
+	balances := make([]uint64, len(f.tx.Message.AccountKeys))
+	for i := range balances {
+		balances[i] = 1
+	}
+	for account, balance := range overrides {
+		balances[f.index(account)] = balance
+	}
+	return balances
+}
+
+// tokenBalance builds a getTransaction token balance entry
+func (f *tradeFixture) tokenBalance(account, owner, mint solana.PublicKey, amount string, decimals int) map[string]interface{} {
+	return map[string]interface{}{
+		"accountIndex": f.index(account),
+		"mint":         mint.String(),
+		"owner":        owner.String(),
+		"uiTokenAmount": map[string]interface{}{
+			"amount":         amount,
+			"decimals":       decimals,
+			"uiAmountString": amount,
+		},
+	}
+}
+
+// result builds the getTransaction response for the fixture and meta
+func (f *tradeFixture) result(t *testing.T, meta map[string]interface{}) map[string]interface{} {
+	encoded, err := f.tx.ToBase64()
+	require.NoError(t, err)
+
+	meta["fee"] = testFee
+	return map[string]interface{}{
+		"slot":        testSlot,
+		"blockTime":   testBlockTime,
+		"transaction": []string{encoded, "base64"},
+		"meta":        meta,
+	}
+}
+
+// decode parses response as the RPC client would and decodes it for wallet
+func decode(t *testing.T, f *tradeFixture, response map[string]interface{}, wallet solana.PublicKey) *blockchain.Transaction {
+	data, err := json.Marshal(response)
+	require.NoError(t, err)
+
+	var result rpc.GetTransactionResult
+	require.NoError(t, json.Unmarshal(data, &result))
+
+	tx, err := decodeTransaction(f.tx.Signatures[0].String(), &result, wallet)
+	require.NoError(t, err)
+	return tx
+}
+
+// buyResponse spends 1 SOL on 5 tokens through Raydium, opening the token account
+func buyResponse(t *testing.T, f *tradeFixture) map[string]interface{} {
+	poolAuthority := solana.NewWallet().PublicKey()
+	return f.result(t, map[string]interface{}{
+		"err": nil,
+		"preBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet:       10_000_000_000,
+			f.tokenAccount: 0,
+		}),
+		"postBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet:       10_000_000_000 - 1_000_000_000 - testFee - tokenAccountRent,
+			f.tokenAccount: tokenAccountRent,
+		}),
+		"preTokenBalances": []interface{}{
+			f.tokenBalance(f.poolVault, poolAuthority, testTokenMint, "100000000", 6),
+		},
+		"postTokenBalances": []interface{}{
+			f.tokenBalance(f.tokenAccount, f.wallet, testTokenMint, "5000000", 6),
+			f.tokenBalance(f.poolVault, poolAuthority, testTokenMint, "95000000", 6),
+		},
+	})
+}
+
+func TestDecodeTransactionBuy(t *testing.T) {
+	f := newTradeFixture(t, raydiumAmmV4ProgramID, solana.PublicKey{})
+	tx := decode(t, f, buyResponse(t, f), solana.PublicKey{})
+
+	assert.Equal(t, f.tx.Signatures[0].String(), tx.ID)
+	assert.Equal(t, blockchain.TransactionTypeBuy, tx.Type)
+	assert.Equal(t, blockchain.TransactionStatusConfirmed, tx.Status)
+	assert.Equal(t, f.wallet.String(), tx.FromAddress)
+	assert.Equal(t, testTokenMint.String(), tx.TokenAddress)
+	assert.Equal(t, testTokenMint.String(), tx.ToAddress)
+	assert.Equal(t, raydiumRoute, tx.Route)
+	assert.Equal(t, int64(1_000_000_000), tx.Amount.Value.Int64())
+	assert.Equal(t, uint8(solDecimals), tx.Amount.Decimals)
+	assert.Equal(t, int64(5_000_000), tx.AmountOut.Value.Int64())
+	assert.Equal(t, uint8(6), tx.AmountOut.Decimals)
+	assert.Equal(t, int64(testFee), tx.GasFee.Value.Int64())
+	assert.Equal(t, uint64(testSlot), tx.BlockNumber)
+	assert.Equal(t, int64(testBlockTime), tx.Timestamp)
+	assert.Equal(t, solana.Hash{1}.String(), tx.BlockHash)
+}
+
+func TestDecodeTransactionSellThroughJupiter(t *testing.T) {
+	f := newTradeFixture(t, jupiterV6ProgramID, raydiumAmmV4ProgramID)
+	raydium := f.index(raydiumAmmV4ProgramID)
+
+	// 5 tokens sold for 0.9 wrapped SOL, kept in an existing account
+	response := f.result(t, map[string]interface{}{
+		"err": nil,
+		"preBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet:      1_000_000_000,
+			f.wsolAccount: tokenAccountRent,
+		}),
+		"postBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet:      1_000_000_000 - testFee,
+			f.wsolAccount: tokenAccountRent + 900_000_000,
+		}),
+		"preTokenBalances": []interface{}{
+			f.tokenBalance(f.tokenAccount, f.wallet, testTokenMint, "5000000", 6),
+			f.tokenBalance(f.wsolAccount, f.wallet, solana.SolMint, "0", 9),
+		},
+		"postTokenBalances": []interface{}{
+			f.tokenBalance(f.tokenAccount, f.wallet, testTokenMint, "0", 6),
+			f.tokenBalance(f.wsolAccount, f.wallet, solana.SolMint, "900000000", 9),
+		},
+		"innerInstructions": []interface{}{
+			map[string]interface{}{
+				"index": 0,
+				"instructions": []interface{}{
+					map[string]interface{}{"programIdIndex": raydium, "accounts": []int{}, "data": "A"},
+				},
+			},
+		},
+	})
+	tx := decode(t, f, response, solana.PublicKey{})
+
+	assert.Equal(t, blockchain.TransactionTypeSell, tx.Type)
+	assert.Equal(t, jupiterRoute, tx.Route)
+	assert.Equal(t, int64(5_000_000), tx.Amount.Value.Int64())
+	assert.Equal(t, uint8(6), tx.Amount.Decimals)
+	assert.Equal(t, int64(900_000_000), tx.AmountOut.Value.Int64())
+	assert.Equal(t, uint8(solDecimals), tx.AmountOut.Decimals)
+}
+
+func TestDecodeTransactionSellClosingWrappedSOL(t *testing.T) {
+	f := newTradeFixture(t, pumpFunProgramID, solana.PublicKey{})
+
+	// The wrapped SOL account is opened and closed within the transaction, so
+	// has no balances, and the emptied token account is closed too
+	response := f.result(t, map[string]interface{}{
+		"err": nil,
+		"preBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet:       1_000_000_000,
+			f.tokenAccount: tokenAccountRent,
+			f.wsolAccount:  0,
+		}),
+		"postBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet:       1_000_000_000 - testFee + 300_000_000 + tokenAccountRent,
+			f.tokenAccount: 0,
+			f.wsolAccount:  0,
+		}),
+		"preTokenBalances": []interface{}{
+			f.tokenBalance(f.tokenAccount, f.wallet, testTokenMint, "1000000", 6),
+		},
+		"postTokenBalances": []interface{}{},
+	})
+	tx := decode(t, f, response, solana.PublicKey{})
+
+	assert.Equal(t, blockchain.TransactionTypeSell, tx.Type)
+	assert.Equal(t, pumpFunRoute, tx.Route)
+	assert.Equal(t, int64(1_000_000), tx.Amount.Value.Int64())
+	assert.Equal(t, int64(300_000_000), tx.AmountOut.Value.Int64())
+}
+
+func TestDecodeTransactionFailed(t *testing.T) {
+	f := newTradeFixture(t, raydiumAmmV4ProgramID, solana.PublicKey{})
+
+	response := f.result(t, map[string]interface{}{
+		"err": map[string]interface{}{"InstructionError": []interface{}{0, map[string]interface{}{"Custom": 30}}},
+		"preBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet: 1_000_000_000,
+		}),
+		"postBalances": f.lamports(map[solana.PublicKey]uint64{
+			f.wallet: 1_000_000_000 - testFee,
+		}),
+	})
+	tx := decode(t, f, response, solana.PublicKey{})
+
+	assert.Equal(t, blockchain.TransactionStatusFailed, tx.Status)
+	assert.Equal(t, `{"InstructionError":[0,{"Custom":30}]}`, tx.ErrorMessage)
+	assert.Empty(t, tx.Type)
+	assert.Equal(t, raydiumRoute, tx.Route)
+	assert.Equal(t, int64(testFee), tx.GasFee.Value.Int64())
+}
+
+func TestDecodeTransactionForAnotherWallet(t *testing.T) {
+	f := newTradeFixture(t, raydiumAmmV4ProgramID, solana.PublicKey{})
+
+	// A wallet whose balances didn't change didn't trade
+	tx := decode(t, f, buyResponse(t, f), solana.NewWallet().PublicKey())
+	assert.Empty(t, tx.Type)
+	assert.Nil(t, tx.Amount.Value)
+}
+
+func TestProviderGetTransactionDecodes(t *testing.T) {
+	f := newTradeFixture(t, raydiumAmmV4ProgramID, solana.PublicKey{})
+	response := buyResponse(t, f)
+
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		require.Equal(t, "getTransaction", req.Method)
+
+		var opts map[string]interface{}
+		require.NoError(t, json.Unmarshal(req.Params[1], &opts))
+		assert.Equal(t, "base64", opts["encoding"])
+		assert.Equal(t, float64(0), opts["maxSupportedTransactionVersion"])
+		return response
+	})
+	provider := newTestProvider(t, server.URL, nil)
+
+	tx, err := provider.GetTransaction(context.Background(), f.tx.Signatures[0].String())
+	require.NoError(t, err)
+	assert.Equal(t, blockchain.TransactionTypeBuy, tx.Type)
+	assert.Equal(t, blockchain.TransactionStatusFinalized, tx.Status)
+
+	_, err = provider.GetTransaction(context.Background(), "not a signature")
+	assert.True(t, blockchain.IsValidationError(err))
+}
+
+func TestProviderGetTransactionsPage(t *testing.T) {
+	f := newTradeFixture(t, raydiumAmmV4ProgramID, solana.PublicKey{})
+	response := buyResponse(t, f)
+
+	signatures := []string{
+		solana.Signature{1}.String(),
+		solana.Signature{2}.String(),
+		solana.Signature{3}.String(),
+	}
+	before, until := solana.Signature{9}.String(), solana.Signature{8}.String()
+
+	server := newRPCServer(t, func(req rpcRequest) interface{} {
+		var signature string
+		require.NoError(t, json.Unmarshal(req.Params[0], &signature))
+
+		switch req.Method {
+		case "getSignaturesForAddress":
+			assert.Equal(t, f.wallet.String(), signature)
+
+			var opts map[string]interface{}
+			require.NoError(t, json.Unmarshal(req.Params[1], &opts))
+			assert.Equal(t, float64(3), opts["limit"])
+			assert.Equal(t, before, opts["before"])
+			assert.Equal(t, until, opts["until"])
+			assert.Equal(t, "finalized", opts["commitment"])
+
+			result := make([]map[string]interface{}, len(signatures))
+			for i, sig := range signatures {
+				result[i] = map[string]interface{}{"signature": sig, "slot": testSlot, "err": nil}
+			}
+			return result
+
+		case "getTransaction":
+			// The second transaction is unknown to this node
+			if signature == signatures[1] {
+				return nil
+			}
+			return response
+		}
+
+		t.Errorf("unexpected RPC method %s", req.Method)
+		return nil
+	})
+	provider := newTestProvider(t, server.URL, nil)
+
+	page, err := provider.GetTransactions(context.Background(), f.wallet.String(), blockchain.TransactionsRequest{
+		Limit:  3,
+		Before: before,
+		Until:  until,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, page.Transactions, 2)
+	assert.Equal(t, signatures[0], page.Transactions[0].ID)
+	assert.Equal(t, signatures[2], page.Transactions[1].ID)
+	assert.Equal(t, blockchain.TransactionTypeBuy, page.Transactions[0].Type)
+
+	require.Len(t, page.Failed, 1)
+	assert.Equal(t, signatures[1], page.Failed[0].Signature)
+	assert.Contains(t, page.Failed[0].Error, "not found")
+
+	// A full page links to the next one
+	assert.Equal(t, signatures[2], page.NextBefore)
+
+	_, err = provider.GetTransactions(context.Background(), f.wallet.String(), blockchain.TransactionsRequest{Before: "invalid"})
+	assert.True(t, blockchain.IsValidationError(err))
+}
+
+func TestProviderGetTransactionsPageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		expected float64
+	}{
+		{name: "default", limit: 0, expected: defaultTransactionsPageSize},
+		{name: "as requested", limit: 50, expected: 50},
+		{name: "capped", limit: 1000, expected: maxTransactionsPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRPCServer(t, func(req rpcRequest) interface{} {
+				require.Equal(t, "getSignaturesForAddress", req.Method)
+				var opts map[string]interface{}
+				require.NoError(t, json.Unmarshal(req.Params[1], &opts))
+				assert.Equal(t, tt.expected, opts["limit"])
+				return []interface{}{}
+			})
+			provider := newTestProvider(t, server.URL, nil)
+
+			page, err := provider.GetTransactions(context.Background(), solana.NewWallet().PublicKey().String(), blockchain.TransactionsRequest{Limit: tt.limit})
+			require.NoError(t, err)
+			assert.Empty(t, page.Transactions)
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package solana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"meme-trader/internal/blockchain"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// transferComputeUnits covers a token transfer plus the creation of the
+	// recipient's token account
+	transferComputeUnits = 50_000
+
+	// signatureFee is the base fee, in lamports, of each transaction signature
+	signatureFee = 5_000
+
+	// mintAccountSize is the size of a mint of the Token program; Token-2022
+	// mints with extensions are longer, and start with the same fields
+	mintAccountSize = 82
+
+	// mintDecimalsOffset is where a mint stores its decimals, after the mint
+	// authority and the supply
+	mintDecimalsOffset = 44
+
+	// tokenAccountSize is the size of a token account of the Token program
+	tokenAccountSize = 165
+
+	// accountTypeMint is the Token-2022 account type of mints with extensions
+	accountTypeMint = 1
+)
+
+// transferTransaction is an unsigned transfer transaction and what it was built from
+type transferTransaction struct {
+	tx                   *solana.Transaction
+	decimals             uint8
+	blockhash            solana.Hash
+	lastValidBlockHeight uint64
+}
+
+// Transfer sends SOL, or the token minted by req.TokenAddress, from a wallet
+// held by the signer to req.ToAddress. The transfer is simulated before it is
+// sent, like swaps, and tracked until it confirms.
+func (p *Provider) Transfer(ctx context.Context, req blockchain.TransferRequest) (*blockchain.Transaction, error) {
+	if !p.IsValidAddress(req.WalletAddress) {
+		return nil, blockchain.NewValidationError("invalid wallet address")
+	}
+	if !p.IsValidAddress(req.ToAddress) {
+		return nil, blockchain.NewValidationError("invalid recipient address")
+	}
+	if req.TokenAddress != "" && !p.IsValidAddress(req.TokenAddress) {
+		return nil, blockchain.NewValidationError("invalid token address")
+	}
+	if req.WalletAddress == req.ToAddress {
+		return nil, blockchain.NewValidationError("recipient must differ from the wallet")
+	}
+	amount, err := amountToUint64(req.Amount)
+	if err != nil {
+		return nil, blockchain.NewValidationError("invalid amount: %w", err)
+	}
+	if amount == 0 {
+		return nil, blockchain.NewValidationError("amount must be greater than zero")
+	}
+
+	transfer, err := p.buildTransfer(ctx, req, amount)
+	if err != nil {
+		return nil, blockchain.NewPreSendError("failed to build transfer transaction: %w", err)
+	}
+
+	record := transferRecord(req, transfer)
+	units, err := simulate(ctx, p.rpcClient, transfer.tx)
+	var simulationErr *blockchain.SimulationError
+	switch {
+	case errors.As(err, &simulationErr):
+		// Recorded as failed without being sent; signed so that it has an ID
+		if err := signTransaction(ctx, p.signer, transfer.tx); err != nil {
+			return nil, blockchain.NewPreSendError("failed to sign transaction: %w", err)
+		}
+		record.ID = transfer.tx.Signatures[0].String()
+		record.Signature = record.ID
+		record.Status = blockchain.TransactionStatusFailed
+		record.ErrorMessage = simulationErr.Err.Error()
+		p.tracker.saveUnsent(record)
+		return record, &blockchain.PreSendError{Err: simulationErr}
+	case err != nil:
+		return nil, &blockchain.PreSendError{Err: err}
+	}
+	limitComputeUnits(transfer.tx, units)
+
+	if err := signTransaction(ctx, p.signer, transfer.tx); err != nil {
+		return nil, blockchain.NewPreSendError("failed to sign transaction: %w", err)
+	}
+
+	sig, err := p.rpcClient.SendTransaction(ctx, transfer.tx)
+	if err != nil {
+		return nil, classifySendError(err)
+	}
+
+	record.ID = sig.String()
+	record.Signature = sig.String()
+	p.tracker.track(record, transfer.tx, transfer.lastValidBlockHeight)
+	return record, nil
+}
+
+// buildTransfer assembles the unsigned transaction sending amount of SOL, or
+// of the token of req, paid by the wallet
+func (p *Provider) buildTransfer(ctx context.Context, req blockchain.TransferRequest, amount uint64) (*transferTransaction, error) {
+	owner := solana.MustPublicKeyFromBase58(req.WalletAddress)
+	recipient := solana.MustPublicKeyFromBase58(req.ToAddress)
+
+	transfer := &transferTransaction{decimals: solDecimals}
+	var instructions []solana.Instruction
+	if req.TokenAddress == "" {
+		price := p.fees.computeUnitPrice(ctx, req.PriorityFee, owner, recipient)
+		if err := p.checkRentExemption(ctx, owner, recipient, amount, price); err != nil {
+			return nil, err
+		}
+		instructions = append(computeBudgetInstructions(transferComputeUnits, price),
+			system.NewTransferInstruction(amount, owner, recipient).Build())
+	} else {
+		mint := solana.MustPublicKeyFromBase58(req.TokenAddress)
+		tokenInstructions, err := p.tokenTransferInstructions(ctx, req, owner, recipient, mint, amount, transfer)
+		if err != nil {
+			return nil, err
+		}
+		// Fees are estimated from the accounts the transfer writes to
+		var writable []solana.PublicKey
+		for _, account := range tokenInstructions[len(tokenInstructions)-1].Accounts() {
+			if account.IsWritable {
+				writable = append(writable, account.PublicKey)
+			}
+		}
+		price := p.fees.computeUnitPrice(ctx, req.PriorityFee, writable...)
+		instructions = append(computeBudgetInstructions(transferComputeUnits, price), tokenInstructions...)
+	}
+
+	latest, err := p.rpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest blockhash: %w", err)
+	}
+
+	tx, err := newTransaction(instructions, latest.Value.Blockhash, owner)
+	if err != nil {
+		return nil, err
+	}
+	transfer.tx = tx
+	transfer.blockhash = latest.Value.Blockhash
+	transfer.lastValidBlockHeight = latest.Value.LastValidBlockHeight
+	return transfer, nil
+}
+
+// tokenTransferInstructions returns the instructions moving amount of mint
+// from the token account of owner to that of recipient, creating the latter
+// when asked to. The transfer itself comes last.
+func (p *Provider) tokenTransferInstructions(ctx context.Context, req blockchain.TransferRequest, owner, recipient, mint solana.PublicKey, amount uint64, transfer *transferTransaction) ([]solana.Instruction, error) {
+	program, decimals, err := p.mintAccount(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	transfer.decimals = decimals
+
+	// Token-2022 accounts live at other addresses than those of the Token program
+	source, err := associatedTokenAddress(owner, mint, program)
+	if err != nil {
+		return nil, err
+	}
+	destination, create, err := tokenAccountPaidBy(owner, recipient, mint, program)
+	if err != nil {
+		return nil, err
+	}
+
+	var instructions []solana.Instruction
+	if req.CreateRecipientAccount {
+		instructions = append(instructions, create)
+	} else {
+		_, err := p.rpcClient.GetAccountInfoWithOpts(ctx, destination, &rpc.GetAccountInfoOpts{Commitment: p.commitment})
+		if errors.Is(err, rpc.ErrNotFound) {
+			return nil, blockchain.NewValidationError("recipient has no token account for %s; set create_recipient_account to create it", mint)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recipient token account: %w", err)
+		}
+	}
+
+	// TransferChecked fails unless decimals match the mint's. Token-2022 takes
+	// the same instruction as the Token program.
+	transferChecked := token.NewTransferCheckedInstruction(amount, decimals, source, mint, destination, owner, nil).Build()
+	data, err := transferChecked.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transfer: %w", err)
+	}
+	instructions = append(instructions, solana.NewInstruction(program, transferChecked.Accounts(), data))
+	return instructions, nil
+}
+
+// mintAccount returns the token program that owns mint, Token or Token-2022,
+// and the decimals of the mint
+func (p *Provider) mintAccount(ctx context.Context, mint solana.PublicKey) (solana.PublicKey, uint8, error) {
+	account, err := p.rpcClient.GetAccountInfoWithOpts(ctx, mint, &rpc.GetAccountInfoOpts{Commitment: p.commitment})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return solana.PublicKey{}, 0, blockchain.NewValidationError("token mint %s not found", mint)
+	}
+	if err != nil {
+		return solana.PublicKey{}, 0, fmt.Errorf("failed to get token mint %s: %w", mint, err)
+	}
+
+	program := account.Value.Owner
+	if !program.Equals(solana.TokenProgramID) && !program.Equals(token2022ProgramID) {
+		return solana.PublicKey{}, 0, blockchain.NewValidationError("%s is not a token mint", mint)
+	}
+	data := account.Value.Data.GetBinary()
+	if !isMintData(data) {
+		return solana.PublicKey{}, 0, blockchain.NewValidationError("%s is not a token mint", mint)
+	}
+	return program, data[mintDecimalsOffset], nil
+}
+
+// isMintData reports whether data is a mint rather than a token account. The
+// Token-2022 extensions of either follow the size of a token account, and an
+// account type byte.
+func isMintData(data []byte) bool {
+	switch {
+	case len(data) == mintAccountSize:
+		return true
+	case len(data) > tokenAccountSize:
+		return data[tokenAccountSize] == accountTypeMint
+	}
+	return false
+}
+
+// checkRentExemption rejects SOL transfers the runtime would: ones leaving the
+// wallet with a balance below the rent-exempt minimum after fees, other than
+// emptying it, and ones funding a new recipient account with less than it
+func (p *Provider) checkRentExemption(ctx context.Context, owner, recipient solana.PublicKey, amount, computeUnitPrice uint64) error {
+	minimum, err := p.rpcClient.GetMinimumBalanceForRentExemption(ctx, 0, p.commitment)
+	if err != nil {
+		return fmt.Errorf("failed to get rent-exempt minimum: %w", err)
+	}
+
+	accounts, err := p.rpcClient.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{owner, recipient}, &rpc.GetMultipleAccountsOpts{Commitment: p.commitment})
+	if err != nil {
+		return fmt.Errorf("failed to get accounts: %w", err)
+	}
+	lamports := func(i int) uint64 {
+		if i >= len(accounts.Value) || accounts.Value[i] == nil {
+			return 0
+		}
+		return accounts.Value[i].Lamports
+	}
+
+	// The fee is at most what the compute unit limit set before simulation costs
+	priorityFee := new(big.Int).SetUint64(computeUnitPrice)
+	priorityFee.Mul(priorityFee, big.NewInt(transferComputeUnits))
+	priorityFee.Add(priorityFee, big.NewInt(999_999))
+	priorityFee.Quo(priorityFee, big.NewInt(1_000_000))
+	fee := priorityFee.Add(priorityFee, big.NewInt(signatureFee))
+
+	// The amount is the caller's, so the total is summed without overflowing
+	balance := lamports(0)
+	remaining := new(big.Int).SetUint64(balance)
+	remaining.Sub(remaining, new(big.Int).SetUint64(amount))
+	remaining.Sub(remaining, fee)
+	if remaining.Sign() < 0 {
+		return blockchain.NewValidationError("%w: the wallet holds %d lamports, less than the %d sent and up to %s in fees",
+			blockchain.ErrInsufficientFunds, balance, amount, fee)
+	}
+	if remaining.Sign() > 0 && remaining.Cmp(new(big.Int).SetUint64(minimum)) < 0 {
+		return blockchain.NewValidationError("%w: the wallet holds %d lamports, and must keep %d for rent exemption, or nothing, after sending %d and paying up to %s in fees",
+			blockchain.ErrInsufficientFunds, balance, minimum, amount, fee)
+	}
+	if lamports(1) == 0 && amount < minimum {
+		return blockchain.NewValidationError("the recipient account does not exist, and needs at least %d lamports to be rent-exempt", minimum)
+	}
+	return nil
+}
+
+// transferRecord is the pending transaction of req, until it is signed
+func transferRecord(req blockchain.TransferRequest, transfer *transferTransaction) *blockchain.Transaction {
+	now := time.Now().Unix()
+	return &blockchain.Transaction{
+		Network:       blockchain.NetworkSolana,
+		Type:          blockchain.TransactionTypeTransfer,
+		Status:        blockchain.TransactionStatusPending,
+		FromAddress:   req.WalletAddress,
+		ToAddress:     req.ToAddress,
+		Amount:        blockchain.Amount{Value: new(big.Int).Set(req.Amount.Value), Decimals: transfer.decimals},
+		TokenAddress:  req.TokenAddress,
+		BlockHash:     transfer.blockhash.String(),
+		CreatedAt:     now,
+		LastUpdatedAt: now,
+	}
+}
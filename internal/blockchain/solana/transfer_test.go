@@ -0,0 +1,311 @@
+package solana
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"meme-trader/internal/blockchain"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// rentExemptMinimum is the rent-exempt minimum of an account without data
+	rentExemptMinimum = 890_880
+
+	// transferFee is the most a SOL transfer pays in fees at the compute unit
+	// price estimated from the fees of swapRPCHandler (8,000 micro-lamports)
+	transferFee = signatureFee + 400
+)
+
+// token2022Mint is a mint of the Token-2022 program, with extensions
+var token2022Mint = solana.MustPublicKeyFromBase58("2b1kV6DkPAnxd5ixfnxCpjxmKwqjjaYmCZfHsFu24GXo")
+
+// mintData is an initialized mint of size bytes, a Token-2022 one with
+// extensions when larger than a token account
+func mintData(decimals uint8, size int) []byte {
+	data := make([]byte, size)
+	data[mintDecimalsOffset] = decimals
+	data[mintDecimalsOffset+1] = 1
+	if size > tokenAccountSize {
+		data[tokenAccountSize] = accountTypeMint
+	}
+	return data
+}
+
+// transferRPCHandler answers the RPC calls of transfers from accounts holding
+// lamports, where token accounts exist when listed in tokenAccounts, and
+// passes the rest to swapRPCHandler
+func transferRPCHandler(t *testing.T, lamports map[solana.PublicKey]uint64, tokenAccounts []solana.PublicKey, sent *[]solana.Transaction) func(req rpcRequest) interface{} {
+	next := swapRPCHandler(t, solana.PublicKey{}, sent)
+	return func(req rpcRequest) interface{} {
+		switch req.Method {
+		case "getMinimumBalanceForRentExemption":
+			return rentExemptMinimum
+
+		case "getMultipleAccounts":
+			var accounts []string
+			require.NoError(t, json.Unmarshal(req.Params[0], &accounts))
+			values := make([]interface{}, len(accounts))
+			for i, account := range accounts {
+				if balance, ok := lamports[solana.MustPublicKeyFromBase58(account)]; ok {
+					value := accountResult(solana.SystemProgramID, nil)
+					value["lamports"] = balance
+					values[i] = value
+				}
+			}
+			return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": values}
+
+		case "getAccountInfo":
+			var account string
+			require.NoError(t, json.Unmarshal(req.Params[0], &account))
+			var value interface{}
+			switch account {
+			case usdcMint.String():
+				value = accountResult(solana.TokenProgramID, mintData(6, mintAccountSize))
+			case token2022Mint.String():
+				value = accountResult(token2022ProgramID, mintData(6, tokenAccountSize+10))
+			}
+			for _, tokenAccount := range tokenAccounts {
+				if tokenAccount.String() == account {
+					value = accountResult(solana.TokenProgramID, make([]byte, 165))
+				}
+			}
+			return map[string]interface{}{"context": map[string]interface{}{"slot": 1}, "value": value}
+		}
+		return next(req)
+	}
+}
+
+// newTransferProvider creates a provider signing for the wallets of store,
+// answering RPC calls with handle
+func newTransferProvider(t *testing.T, handle func(req rpcRequest) interface{}, wallets memoryWalletStore, store *memoryTransactionStore) *Provider {
+	provider, err := NewProviderWithOptions(ProviderOptions{
+		RPCEndpoint:              newRPCServer(t, handle).URL,
+		WSEndpoint:               "ws://127.0.0.1:1",
+		LazyWS:                   true,
+		Signer:                   NewWalletSigner(wallets),
+		Transactions:             store,
+		ConfirmationPollInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func TestTransferSOL(t *testing.T) {
+	wallets := memoryWalletStore{}
+	owner := storeKey(wallets).PublicKey()
+	existing := solana.NewWallet().PublicKey()
+	unfunded := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name     string
+		to       string
+		amount   uint64
+		balance  uint64
+		errorMsg string
+	}{
+		{name: "succeeds", to: existing.String(), amount: 1_000_000_000, balance: 2_000_000_000},
+		{name: "funds a new account", to: unfunded.String(), amount: rentExemptMinimum, balance: 2_000_000_000},
+		{name: "empties the wallet", to: existing.String(), amount: 1_000_000_000, balance: 1_000_000_000 + transferFee},
+		{name: "invalid recipient", to: "not-an-address", amount: 1_000, balance: 2_000_000_000, errorMsg: "invalid recipient address"},
+		{name: "to itself", to: owner.String(), amount: 1_000, balance: 2_000_000_000, errorMsg: "recipient must differ from the wallet"},
+		{name: "zero amount", to: existing.String(), balance: 2_000_000_000, errorMsg: "amount must be greater than zero"},
+		{name: "below rent exemption", to: existing.String(), amount: 1_000_000_000, balance: 1_000_100_000, errorMsg: "must keep 890880 for rent exemption"},
+		{name: "more than the balance", to: existing.String(), amount: math.MaxUint64 - 1_000, balance: 2_000_000_000, errorMsg: "insufficient funds"},
+		{name: "new account below rent exemption", to: unfunded.String(), amount: 1_000, balance: 2_000_000_000, errorMsg: "needs at least 890880 lamports to be rent-exempt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []solana.Transaction
+			lamports := map[solana.PublicKey]uint64{owner: tt.balance, existing: 1}
+			provider := newTransferProvider(t, transferRPCHandler(t, lamports, nil, &sent), wallets, &memoryTransactionStore{})
+
+			tx, err := provider.Transfer(context.Background(), blockchain.TransferRequest{
+				WalletAddress: owner.String(),
+				ToAddress:     tt.to,
+				Amount:        blockchain.Amount{Value: newBigInt(tt.amount), Decimals: solDecimals},
+			})
+
+			if tt.errorMsg != "" {
+				assert.Nil(t, tx)
+				assert.True(t, blockchain.IsValidationError(err))
+				assert.ErrorContains(t, err, tt.errorMsg)
+				assert.Empty(t, sent)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, sent, 1)
+			assert.NoError(t, sent[0].VerifySignatures())
+
+			// A system transfer (2) of the amount, after the compute budget
+			instructions := sent[0].Message.Instructions
+			require.Len(t, instructions, 3)
+			transfer := instructions[2]
+			assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(transfer.Data))
+			assert.Equal(t, tt.amount, binary.LittleEndian.Uint64(transfer.Data[4:]))
+
+			assert.Equal(t, blockchain.TransactionTypeTransfer, tx.Type)
+			assert.Equal(t, blockchain.TransactionStatusPending, tx.Status)
+			assert.Equal(t, sent[0].Signatures[0].String(), tx.ID)
+			assert.Equal(t, owner.String(), tx.FromAddress)
+			assert.Equal(t, tt.to, tx.ToAddress)
+			assert.Empty(t, tx.TokenAddress)
+			assert.Equal(t, blockchain.Amount{Value: newBigInt(tt.amount), Decimals: solDecimals}, tx.Amount)
+		})
+	}
+
+	// Insufficient funds are a validation error, like the other checks
+	var sent []solana.Transaction
+	provider := newTransferProvider(t, transferRPCHandler(t, map[solana.PublicKey]uint64{owner: 1_000}, nil, &sent), wallets, &memoryTransactionStore{})
+	_, err := provider.Transfer(context.Background(), blockchain.TransferRequest{
+		WalletAddress: owner.String(),
+		ToAddress:     existing.String(),
+		Amount:        blockchain.Amount{Value: newBigInt(1_000_000), Decimals: solDecimals},
+	})
+	assert.ErrorIs(t, err, blockchain.ErrInsufficientFunds)
+}
+
+func TestTransferToken(t *testing.T) {
+	wallets := memoryWalletStore{}
+	owner := storeKey(wallets).PublicKey()
+	recipient := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name     string
+		mint     solana.PublicKey
+		program  solana.PublicKey
+		exists   bool
+		create   bool
+		errorMsg string
+	}{
+		{name: "existing account", mint: usdcMint, program: solana.TokenProgramID, exists: true},
+		{name: "creates the account", mint: usdcMint, program: solana.TokenProgramID, create: true},
+		{name: "missing account", mint: usdcMint, program: solana.TokenProgramID, errorMsg: "recipient has no token account"},
+		{name: "token-2022", mint: token2022Mint, program: token2022ProgramID, exists: true},
+		{name: "creates a token-2022 account", mint: token2022Mint, program: token2022ProgramID, create: true},
+		{name: "unknown mint", mint: solana.NewWallet().PublicKey(), program: solana.TokenProgramID, errorMsg: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := associatedTokenAddress(owner, tt.mint, tt.program)
+			require.NoError(t, err)
+			destination, err := associatedTokenAddress(recipient, tt.mint, tt.program)
+			require.NoError(t, err)
+			tokenAccounts := []solana.PublicKey{source}
+			if tt.exists {
+				tokenAccounts = append(tokenAccounts, destination)
+			}
+
+			var sent []solana.Transaction
+			provider := newTransferProvider(t, transferRPCHandler(t, nil, tokenAccounts, &sent), wallets, &memoryTransactionStore{})
+
+			tx, err := provider.Transfer(context.Background(), blockchain.TransferRequest{
+				WalletAddress:          owner.String(),
+				ToAddress:              recipient.String(),
+				TokenAddress:           tt.mint.String(),
+				Amount:                 blockchain.Amount{Value: newBigInt(25_000_000)},
+				CreateRecipientAccount: tt.create,
+			})
+
+			if tt.errorMsg != "" {
+				assert.True(t, blockchain.IsValidationError(err))
+				assert.ErrorContains(t, err, tt.errorMsg)
+				assert.Empty(t, sent)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, sent, 1)
+			message := &sent[0].Message
+			accounts := func(instruction solana.CompiledInstruction) []solana.PublicKey {
+				metas, err := instruction.ResolveInstructionAccounts(message)
+				require.NoError(t, err)
+				keys := make([]solana.PublicKey, len(metas))
+				for j, meta := range metas {
+					keys[j] = meta.PublicKey
+				}
+				return keys
+			}
+
+			// The recipient's account is created at the wallet's expense
+			instructions := message.Instructions
+			if tt.create {
+				require.Len(t, instructions, 4)
+				create := instructions[2]
+				assert.Equal(t, solana.SPLAssociatedTokenAccountProgramID, message.AccountKeys[create.ProgramIDIndex])
+				assert.Equal(t, []solana.PublicKey{owner, destination, recipient, tt.mint, solana.SystemProgramID, tt.program}, accounts(create))
+			} else {
+				require.Len(t, instructions, 3)
+			}
+
+			// TransferChecked (12) of the amount, with the decimals of the
+			// mint, by the program of the mint
+			transfer := instructions[len(instructions)-1]
+			assert.Equal(t, tt.program, message.AccountKeys[transfer.ProgramIDIndex])
+			assert.Equal(t, byte(12), transfer.Data[0])
+			assert.Equal(t, uint64(25_000_000), binary.LittleEndian.Uint64(transfer.Data[1:]))
+			assert.Equal(t, byte(6), transfer.Data[9])
+			assert.Equal(t, []solana.PublicKey{source, tt.mint, destination, owner}, accounts(transfer))
+
+			assert.Equal(t, blockchain.TransactionTypeTransfer, tx.Type)
+			assert.Equal(t, tt.mint.String(), tx.TokenAddress)
+			assert.Equal(t, blockchain.Amount{Value: newBigInt(25_000_000), Decimals: 6}, tx.Amount)
+		})
+	}
+}
+
+func TestTransferSimulationFailure(t *testing.T) {
+	wallets := memoryWalletStore{}
+	owner := storeKey(wallets).PublicKey()
+	recipient := solana.NewWallet().PublicKey()
+	source, _, err := solana.FindAssociatedTokenAddress(owner, usdcMint)
+	require.NoError(t, err)
+
+	var sent []solana.Transaction
+	handle := transferRPCHandler(t, nil, []solana.PublicKey{source}, &sent)
+	logs := []string{"Program log: Instruction: TransferChecked", "Program log: Error: insufficient funds"}
+	store := &memoryTransactionStore{}
+	simulated := 0
+	provider := newTransferProvider(t, func(req rpcRequest) interface{} {
+		if req.Method == "simulateTransaction" {
+			// Simulated unsigned, with an empty slot for the wallet's signature
+			tx := simulatedTransaction(t, req)
+			assert.Equal(t, solana.Signature{}, tx.Signatures[0])
+			simulated++
+			return map[string]interface{}{
+				"context": map[string]interface{}{"slot": 1},
+				"value":   map[string]interface{}{"err": instructionError, "logs": logs, "unitsConsumed": 4_000},
+			}
+		}
+		return handle(req)
+	}, wallets, store)
+
+	tx, err := provider.Transfer(context.Background(), blockchain.TransferRequest{
+		WalletAddress:          owner.String(),
+		ToAddress:              recipient.String(),
+		TokenAddress:           usdcMint.String(),
+		Amount:                 blockchain.Amount{Value: newBigInt(25_000_000)},
+		CreateRecipientAccount: true,
+	})
+
+	// The transfer was not sent, but is recorded as a failed transfer
+	assert.Equal(t, 1, simulated)
+	assert.Empty(t, sent)
+	assert.True(t, blockchain.IsSimulationError(err))
+	assert.ErrorIs(t, err, blockchain.ErrInsufficientFunds)
+	require.NotNil(t, tx)
+	assert.Equal(t, blockchain.TransactionTypeTransfer, tx.Type)
+	assert.Equal(t, blockchain.TransactionStatusFailed, tx.Status)
+	assert.NotEmpty(t, tx.ID)
+	assert.Equal(t, *tx, store.last())
+}
@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// quoteTTL is how long a quote can be executed. Pool prices move quickly,
+	// so a quote is only meant to cover the time the user takes to confirm it.
+	quoteTTL = 30 * time.Second
+
+	// pendingBuildTTL is how long a built transaction can be submitted. Solana
+	// blockhashes expire after about a minute, so older builds can't land anyway.
+	pendingBuildTTL = 2 * time.Minute
+)
+
+// ttlStore keeps values under random IDs until they expire, e.g. the quotes
+// shown to users until they are executed
+type ttlStore[T any] struct {
+	ttl   time.Duration
+	items map[string]ttlItem[T]
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+// ttlItem is a value of a ttlStore and the Unix time it expires after
+type ttlItem[T any] struct {
+	value     T
+	expiresAt int64
+}
+
+func newTTLStore[T any](ttl time.Duration) *ttlStore[T] {
+	return &ttlStore[T]{
+		ttl:   ttl,
+		items: make(map[string]ttlItem[T]),
+		now:   time.Now,
+	}
+}
+
+// add keeps value until it expires, and returns its ID and the Unix time it
+// expires after
+func (s *ttlStore[T]) add(value T) (string, int64, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", 0, fmt.Errorf("failed to generate ID: %w", err)
+	}
+	id := hex.EncodeToString(raw)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	expiresAt := now.Add(s.ttl).Unix()
+	s.items[id] = ttlItem[T]{value: value, expiresAt: expiresAt}
+	return id, expiresAt, nil
+}
+
+// get returns the value with the given ID unless it has expired
+func (s *ttlStore[T]) get(id string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok || s.now().Unix() > item.expiresAt {
+		var zero T
+		return zero, false
+	}
+	return item.value, true
+}
+
+// take is get, forgetting the value so that it is taken at most once
+func (s *ttlStore[T]) take(id string) (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok || s.now().Unix() > item.expiresAt {
+		var zero T
+		return zero, false
+	}
+	delete(s.items, id)
+	return item.value, true
+}
+
+// put keeps value under id until expiresAt, e.g. to return a taken value
+func (s *ttlStore[T]) put(id string, value T, expiresAt int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[id] = ttlItem[T]{value: value, expiresAt: expiresAt}
+}
+
+// remove forgets the value with the given ID
+func (s *ttlStore[T]) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, id)
+}
+
+// sweep drops the expired values
+func (s *ttlStore[T]) sweep(now time.Time) {
+	for id, item := range s.items {
+		if now.Unix() > item.expiresAt {
+			delete(s.items, id)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTLStore(t *testing.T) {
+	store := newTTLStore[string](time.Minute)
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+
+	first, expiresAt, err := store.add("first")
+	require.NoError(t, err)
+	assert.Len(t, first, 32)
+	assert.Equal(t, now.Add(time.Minute).Unix(), expiresAt)
+
+	value, ok := store.get(first)
+	assert.True(t, ok)
+	assert.Equal(t, "first", value)
+
+	// Taken values are gone
+	value, ok = store.take(first)
+	assert.True(t, ok)
+	assert.Equal(t, "first", value)
+	_, ok = store.take(first)
+	assert.False(t, ok)
+
+	// Until they are put back
+	store.put(first, "first", expiresAt)
+	value, ok = store.get(first)
+	assert.True(t, ok)
+	assert.Equal(t, "first", value)
+	store.remove(first)
+
+	// Expired values can't be read and are swept on the next add
+	second, _, err := store.add("second")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	now = now.Add(time.Minute + time.Second)
+	_, ok = store.get(second)
+	assert.False(t, ok)
+	_, ok = store.take(second)
+	assert.False(t, ok)
+
+	third, _, err := store.add("third")
+	require.NoError(t, err)
+	assert.Len(t, store.items, 1)
+
+	store.remove(third)
+	_, ok = store.get(third)
+	assert.False(t, ok)
+}
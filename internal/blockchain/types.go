@@ -22,15 +22,27 @@ type Amount struct {
 	Decimals uint8
 }
 
+// Float64 returns the amount in whole units, e.g. 1.5 for 1500000 with 6 decimals
+func (a Amount) Float64() float64 {
+	if a.Value == nil {
+		return 0
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(a.Decimals)), nil)
+	value, _ := new(big.Float).Quo(new(big.Float).SetInt(a.Value), new(big.Float).SetInt(scale)).Float64()
+	return value
+}
+
 // Wallet represents a blockchain wallet
 type Wallet struct {
-	ID            string
-	Network       Network
-	Address       string
-	PublicKey     string
-	PrivateKey    string // Should be encrypted in production
-	CreatedAt     int64
-	LastUpdatedAt int64
+	ID              string
+	Network         Network
+	Address         string
+	PublicKey       string
+	PrivateKey      string // Should be encrypted in production
+	AccessToken     string // Authorizes spending from the wallet; only returned when it is created
+	AccessTokenHash string // SHA-256 of AccessToken, as stored
+	CreatedAt       int64
+	LastUpdatedAt   int64
 }
 
 // Transaction represents a blockchain transaction
@@ -41,8 +53,10 @@ type Transaction struct {
 	Status        TransactionStatus
 	FromAddress   string
 	ToAddress     string
-	Amount        Amount
+	Amount        Amount // Amount spent: SOL for a buy, tokens for a sell
+	AmountOut     Amount // Amount received, once known
 	TokenAddress  string
+	Route         string // DEX the trade went through, e.g. "raydium"
 	Signature     string
 	BlockHash     string
 	BlockNumber   uint64
@@ -58,6 +72,8 @@ type TransactionType string
 const (
 	TransactionTypeBuy  TransactionType = "buy"
 	TransactionTypeSell TransactionType = "sell"
+	// TransactionTypeTransfer moves SOL or tokens out of a wallet, without a swap
+	TransactionTypeTransfer TransactionType = "transfer"
 )
 
 type TransactionStatus string
@@ -65,9 +81,46 @@ type TransactionStatus string
 const (
 	TransactionStatusPending   TransactionStatus = "pending"
 	TransactionStatusConfirmed TransactionStatus = "confirmed"
+	TransactionStatusFinalized TransactionStatus = "finalized"
 	TransactionStatusFailed    TransactionStatus = "failed"
 )
 
+// TransactionStore persists transactions as their status changes
+type TransactionStore interface {
+	SaveTransaction(tx *Transaction) error
+}
+
+// TokenBalance is the balance of a token account owned by a wallet
+type TokenBalance struct {
+	Account  string // Token account address
+	Mint     string
+	Program  string // Token program owning the account: Token or Token-2022
+	Amount   Amount
+	UIAmount string // Amount in whole units, as formatted by the node
+}
+
+// Holding is a token balance valued in USD
+type Holding struct {
+	TokenBalance
+	PriceUSD float64
+	ValueUSD float64
+	Priced   bool // False when the token has no known price
+}
+
+// Portfolio is a snapshot of the token holdings of a wallet
+type Portfolio struct {
+	Network       Network
+	Address       string
+	Holdings      []Holding
+	TotalValueUSD float64 // Sum of the priced holdings
+	Timestamp     int64
+}
+
+// PriceStore looks up the latest USD prices of tokens by address
+type PriceStore interface {
+	GetTokenPrices(addresses []string) (map[string]float64, error)
+}
+
 // MemeCoin represents a meme coin with its market data
 type MemeCoin struct {
 	Address     string
@@ -97,12 +150,19 @@ type Provider interface {
 	CreateWallet(ctx context.Context) (*Wallet, error)
 	GetWallet(ctx context.Context, address string) (*Wallet, error)
 	GetBalance(ctx context.Context, address string) (Amount, error)
+	GetTokenBalances(ctx context.Context, address string) ([]TokenBalance, error)
 
 	// Transaction operations
+	Quote(ctx context.Context, req QuoteRequest) (*SwapQuote, error)
 	Buy(ctx context.Context, req BuyRequest) (*Transaction, error)
 	Sell(ctx context.Context, req SellRequest) (*Transaction, error)
+	Transfer(ctx context.Context, req TransferRequest) (*Transaction, error)
 	GetTransaction(ctx context.Context, txID string) (*Transaction, error)
-	GetTransactions(ctx context.Context, address string, limit int) ([]Transaction, error)
+	GetTransactions(ctx context.Context, address string, req TransactionsRequest) (*TransactionPage, error)
+
+	// Client-side signing
+	BuildSwap(ctx context.Context, req BuildSwapRequest) (*UnsignedTransaction, error)
+	SubmitTransaction(ctx context.Context, built *UnsignedTransaction, signedTx string) (*Transaction, error)
 
 	// Meme coin operations
 	GetTopMemeCoins(ctx context.Context, req TopMemeCoinsRequest) ([]MemeCoin, error)
@@ -113,7 +173,11 @@ type BuyRequest struct {
 	WalletAddress string
 	TokenAddress  string
 	Amount        Amount
-	MaxPrice      Amount // Maximum price willing to pay (slippage protection)
+	MaxPrice      Amount     // Maximum price willing to pay (slippage protection)
+	SlippageBps   uint16     // Slippage tolerance in basis points, used when MaxPrice is unset
+	PriorityFee   uint64     // Compute unit price in micro-lamports; estimated from recent fees when zero
+	QuoteID       string     // Executes a quote returned by Service.Quote instead
+	Quote         *SwapQuote // The quote of QuoteID, resolved by the service
 }
 
 // SellRequest represents a request to sell tokens
@@ -121,7 +185,106 @@ type SellRequest struct {
 	WalletAddress string
 	TokenAddress  string
 	Amount        Amount
-	MinPrice      Amount // Minimum price willing to accept (slippage protection)
+	MinPrice      Amount     // Minimum price willing to accept (slippage protection)
+	SlippageBps   uint16     // Slippage tolerance in basis points, used when MinPrice is unset
+	PriorityFee   uint64     // Compute unit price in micro-lamports; estimated from recent fees when zero
+	QuoteID       string     // Executes a quote returned by Service.Quote instead
+	Quote         *SwapQuote // The quote of QuoteID, resolved by the service
+}
+
+// TransferRequest represents a request to send SOL or tokens to another address
+type TransferRequest struct {
+	WalletAddress          string
+	ToAddress              string
+	TokenAddress           string // Mint of the token sent; SOL when empty
+	Amount                 Amount // In the smallest unit: lamports, or base units of the token
+	CreateRecipientAccount bool   // Creates the recipient's token account, paid by the wallet, when missing
+	PriorityFee            uint64 // Compute unit price in micro-lamports; estimated from recent fees when zero
+}
+
+// TransactionsRequest selects a page of a wallet's history, newest first
+type TransactionsRequest struct {
+	Limit  int    // Maximum number of transactions; the provider defaults and caps it
+	Before string // Start after this signature, e.g. the NextBefore of the previous page
+	Until  string // Stop at this signature, e.g. the newest one already seen
+}
+
+// TransactionPage is a page of a wallet's history
+type TransactionPage struct {
+	Transactions []Transaction
+	Failed       []FailedTransaction // Transactions of the page that couldn't be fetched
+	NextBefore   string              // Cursor of the next, older page; empty on the last page
+}
+
+// FailedTransaction is a transaction of a history page that couldn't be fetched
+type FailedTransaction struct {
+	Signature string
+	Error     string
+}
+
+// BuildSwapRequest represents a request to build an unsigned buy or sell
+// transaction that the wallet signs itself
+type BuildSwapRequest struct {
+	Type             TransactionType // TransactionTypeBuy or TransactionTypeSell
+	WalletAddress    string
+	TokenAddress     string
+	Amount           Amount // Amount spent: SOL for a buy, tokens for a sell
+	MinimumAmountOut Amount // Minimum amount received (slippage protection)
+	SlippageBps      uint16 // Slippage tolerance in basis points, used when MinimumAmountOut is unset
+	PriorityFee      uint64 // Compute unit price in micro-lamports; estimated from recent fees when zero
+}
+
+// QuoteRequest asks for the terms of swapping an exact amount of one token for another
+type QuoteRequest struct {
+	InputMint   string
+	OutputMint  string
+	Amount      Amount // Amount of InputMint spent
+	SlippageBps uint16 // Slippage tolerance in basis points; the provider picks a default when zero
+}
+
+// Quote is a swap quote that buys and sells can execute until it expires
+type Quote struct {
+	ID      string
+	Network Network
+	SwapQuote
+	ExpiresAt int64 // Unix time after which the quote can no longer be executed
+}
+
+// SwapQuote describes the terms of a swap
+type SwapQuote struct {
+	InputMint         string
+	OutputMint        string
+	AmountIn          Amount
+	ExpectedAmountOut Amount // At the pool's current reserves
+	MinimumAmountOut  Amount
+	Fee               Amount // Pool fee, paid in the input token; in SOL for Pump.fun sells
+	PriceImpactBps    int64  // How much the swap moves the pool price, in basis points
+	SlippageBps       uint16 // Price movement allowed by MinimumAmountOut, in basis points
+	Pool              string // Pool the swap is routed through; comma separated for multi-hop routes
+	Route             string // DEX or aggregator the swap is routed through, e.g. "raydium" or "jupiter"
+	RouteData         []byte `json:"-"` // Route-specific data needed to execute the quote
+}
+
+// UnsignedTransaction is a transaction built for client-side signing
+type UnsignedTransaction struct {
+	ID                   string // Identifies the build when submitting the signed transaction
+	Network              Network
+	Type                 TransactionType
+	WalletAddress        string
+	TokenAddress         string
+	Amount               Amount
+	Transaction          string   // Base64 wire format with empty signature slots
+	Signers              []string // Addresses that must sign, fee payer first
+	BlockHash            string
+	LastValidBlockHeight uint64 // The transaction can't land after this block height
+	Quote                SwapQuote
+	ExpiresAt            int64 // Unix time after which the build can no longer be submitted
+}
+
+// SubmitTransactionRequest represents a client-signed transaction to broadcast
+type SubmitTransactionRequest struct {
+	ID          string // ID of the UnsignedTransaction that was signed
+	Transaction string // Base64 signed transaction
 }
 
 // Service provides a high-level interface for blockchain operations
@@ -129,15 +292,37 @@ type Service interface {
 	// Provider management
 	RegisterProvider(provider Provider) error
 	RegisterProviderWithConfig(provider Provider, config ProviderConfig) error
+	SetHedging(network Network, config HedgeConfig)
+	SetStrategy(network Network, strategy BalancingStrategy)
+	SetPriceStore(store PriceStore)
+	Close() error
+
+	// Provider administration
+	ProviderSnapshots() map[Network][]ProviderSnapshot
+	DrainProvider(network Network, name string) error
+	DisableProvider(network Network, name string) error
+	EnableProvider(network Network, name string) error
+	SetProviderPriority(network Network, name string, priority int) error
 
 	// Wallet operations
 	CreateWallet(ctx context.Context, network Network) (*Wallet, error)
 	GetWallet(ctx context.Context, network Network, address string) (*Wallet, error)
+	AuthorizeWallet(ctx context.Context, network Network, address, token string) error
 	GetBalance(ctx context.Context, network Network, address string) (Amount, error)
+	GetPortfolio(ctx context.Context, network Network, address string) (*Portfolio, error)
 
 	// Transaction operations
+	Quote(ctx context.Context, network Network, req QuoteRequest) (*Quote, error)
 	Buy(ctx context.Context, network Network, req BuyRequest) (*Transaction, error)
 	Sell(ctx context.Context, network Network, req SellRequest) (*Transaction, error)
+	Transfer(ctx context.Context, network Network, req TransferRequest) (*Transaction, error)
 	GetTransaction(ctx context.Context, network Network, txID string) (*Transaction, error)
-	GetTransactions(ctx context.Context, network Network, address string, limit int) ([]Transaction, error)
+	GetTransactions(ctx context.Context, network Network, address string, req TransactionsRequest) (*TransactionPage, error)
+
+	// Client-side signing
+	BuildSwap(ctx context.Context, network Network, req BuildSwapRequest) (*UnsignedTransaction, error)
+	SubmitTransaction(ctx context.Context, network Network, req SubmitTransactionRequest) (*Transaction, error)
+
+	// Meme coin operations
+	GetTopMemeCoins(ctx context.Context, network Network, req TopMemeCoinsRequest) ([]MemeCoin, error)
 }
@@ -0,0 +1,52 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// accessTokenBytes is the entropy of a wallet access token
+const accessTokenBytes = 32
+
+// ErrUnauthorized is returned when a request spending from a wallet doesn't
+// carry the access token issued with it
+var ErrUnauthorized = errors.New("unauthorized")
+
+// newAccessToken returns a random wallet access token and the hash it is stored as
+func newAccessToken() (string, string, error) {
+	raw := make([]byte, accessTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	return token, hashAccessToken(token), nil
+}
+
+// hashAccessToken returns the hex SHA-256 of token
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthorizeWallet checks that token is the access token issued when the
+// wallet at address was created. Wallets that aren't stored, or were stored
+// without a token, can't be authorized.
+func (s *service) AuthorizeWallet(ctx context.Context, network Network, address, token string) error {
+	if s.wallets == nil || token == "" {
+		return ErrUnauthorized
+	}
+
+	wallet, err := s.wallets.GetWallet(network, address)
+	if err != nil || wallet.AccessTokenHash == "" {
+		return ErrUnauthorized
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAccessToken(token)), []byte(wallet.AccessTokenHash)) != 1 {
+		return ErrUnauthorized
+	}
+	return nil
+}
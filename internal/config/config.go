@@ -4,14 +4,21 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	ServerPort     string
-	SolanaEndpoint string
-	DatabaseURL    string
+	ServerPort              string
+	SolanaEndpoint          string
+	SolanaWSEndpoint        string   // Derived from SolanaEndpoint when empty
+	SolanaCommitment        string   // Commitment level for reads; defaults to finalized
+	SolanaFallbackEndpoints []string // Extra RPC endpoints registered as lower priority providers
+	JupiterEndpoint         string   // Jupiter v6 swap API URL; empty routes swaps through Raydium only
+	SolanaLookupTables      []string // Address lookup tables swaps load accounts from
+	DatabaseURL             string
+	AdminToken              string // Bearer token for the admin API; empty disables it
 }
 
 func NewConfig() *Config {
@@ -35,9 +42,15 @@ func NewConfig() *Config {
 	}
 
 	return &Config{
-		ServerPort:     getEnvOrDefault("SERVER_PORT", "8080"),
-		SolanaEndpoint: getEnvOrDefault("SOLANA_ENDPOINT", "https://api.devnet.solana.com"),
-		DatabaseURL:    dbURL,
+		ServerPort:              getEnvOrDefault("SERVER_PORT", "8080"),
+		SolanaEndpoint:          getEnvOrDefault("SOLANA_ENDPOINT", "https://api.devnet.solana.com"),
+		SolanaWSEndpoint:        os.Getenv("SOLANA_WS_ENDPOINT"),
+		SolanaCommitment:        os.Getenv("SOLANA_COMMITMENT"),
+		SolanaFallbackEndpoints: getEnvList("SOLANA_FALLBACK_ENDPOINTS"),
+		JupiterEndpoint:         os.Getenv("JUPITER_ENDPOINT"),
+		SolanaLookupTables:      getEnvList("SOLANA_LOOKUP_TABLES"),
+		DatabaseURL:             dbURL,
+		AdminToken:              os.Getenv("ADMIN_TOKEN"),
 	}
 }
 
@@ -48,10 +61,26 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList splits a comma separated environment variable, skipping empty items
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // Validate checks if all required environment variables are set
 func (c *Config) Validate() error {
 	if c.DatabaseURL == "postgres://:@localhost:5432/memetrader?sslmode=disable" {
 		return fmt.Errorf("database credentials not provided. Please set DATABASE_URL or individual DB_* environment variables")
 	}
+	switch c.SolanaCommitment {
+	case "", "processed", "confirmed", "finalized":
+	default:
+		return fmt.Errorf("invalid SOLANA_COMMITMENT %q: must be processed, confirmed or finalized", c.SolanaCommitment)
+	}
 	return nil
 }
@@ -26,6 +26,15 @@ func createBlockchainTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create wallets table: %w", err)
 	}
 
+	// Wallets created before access tokens have none, and can't be spent from
+	_, err = db.Exec(`
+		ALTER TABLE wallets
+			ADD COLUMN IF NOT EXISTS access_token_hash TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add access token column to wallets table: %w", err)
+	}
+
 	// Create transactions table
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS blockchain_transactions (
@@ -53,6 +62,17 @@ func createBlockchainTables(db *sql.DB) error {
 		return fmt.Errorf("failed to create blockchain_transactions table: %w", err)
 	}
 
+	// Add the columns of decoded trades to existing tables
+	_, err = db.Exec(`
+		ALTER TABLE blockchain_transactions
+			ADD COLUMN IF NOT EXISTS amount_out_value TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS amount_out_decimals INTEGER NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS route TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add trade columns to blockchain_transactions table: %w", err)
+	}
+
 	return nil
 }
 
@@ -61,15 +81,16 @@ func (db *Database) SaveWallet(wallet *blockchain.Wallet) error {
 	_, err := db.db.Exec(`
 		INSERT INTO wallets (
 			id, network, address, public_key, private_key,
-			created_at, last_updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			access_token_hash, created_at, last_updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		ON CONFLICT (network, address) DO UPDATE SET
 			public_key = EXCLUDED.public_key,
 			private_key = EXCLUDED.private_key,
+			access_token_hash = EXCLUDED.access_token_hash,
 			last_updated_at = EXCLUDED.last_updated_at
 	`,
 		wallet.ID, wallet.Network, wallet.Address, wallet.PublicKey,
-		wallet.PrivateKey, wallet.CreatedAt, wallet.LastUpdatedAt,
+		wallet.PrivateKey, wallet.AccessTokenHash, wallet.CreatedAt, wallet.LastUpdatedAt,
 	)
 
 	if err != nil {
@@ -84,12 +105,12 @@ func (db *Database) GetWallet(network blockchain.Network, address string) (*bloc
 	var wallet blockchain.Wallet
 	err := db.db.QueryRow(`
 		SELECT id, network, address, public_key, private_key,
-			created_at, last_updated_at
+			access_token_hash, created_at, last_updated_at
 		FROM wallets
 		WHERE network = $1 AND address = $2
 	`, network, address).Scan(
 		&wallet.ID, &wallet.Network, &wallet.Address, &wallet.PublicKey,
-		&wallet.PrivateKey, &wallet.CreatedAt, &wallet.LastUpdatedAt,
+		&wallet.PrivateKey, &wallet.AccessTokenHash, &wallet.CreatedAt, &wallet.LastUpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -117,28 +138,42 @@ func (db *Database) SaveTransaction(tx *blockchain.Transaction) error {
 		}
 	}
 
+	var amountOutValue []byte
+	if tx.AmountOut.Value != nil {
+		amountOutValue, err = json.Marshal(tx.AmountOut.Value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal amount out value: %w", err)
+		}
+	}
+
 	_, err = db.db.Exec(`
 		INSERT INTO blockchain_transactions (
 			id, network, type, status, from_address, to_address,
 			amount_value, amount_decimals, token_address, signature,
 			block_hash, block_number, timestamp, gas_fee_value,
-			gas_fee_decimals, error_message, created_at, last_updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+			gas_fee_decimals, error_message, created_at, last_updated_at,
+			amount_out_value, amount_out_decimals, route
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		ON CONFLICT (id) DO UPDATE SET
 			status = EXCLUDED.status,
 			signature = EXCLUDED.signature,
 			block_hash = EXCLUDED.block_hash,
 			block_number = EXCLUDED.block_number,
+			timestamp = EXCLUDED.timestamp,
 			gas_fee_value = EXCLUDED.gas_fee_value,
 			gas_fee_decimals = EXCLUDED.gas_fee_decimals,
 			error_message = EXCLUDED.error_message,
-			last_updated_at = EXCLUDED.last_updated_at
+			last_updated_at = EXCLUDED.last_updated_at,
+			amount_out_value = EXCLUDED.amount_out_value,
+			amount_out_decimals = EXCLUDED.amount_out_decimals,
+			route = EXCLUDED.route
 	`,
 		tx.ID, tx.Network, tx.Type, tx.Status, tx.FromAddress, tx.ToAddress,
 		string(amountValue), tx.Amount.Decimals, tx.TokenAddress, tx.Signature,
 		tx.BlockHash, tx.BlockNumber, tx.Timestamp,
 		string(gasFeeValue), tx.GasFee.Decimals, tx.ErrorMessage,
 		tx.CreatedAt, tx.LastUpdatedAt,
+		string(amountOutValue), tx.AmountOut.Decimals, tx.Route,
 	)
 
 	if err != nil {
@@ -151,13 +186,14 @@ func (db *Database) SaveTransaction(tx *blockchain.Transaction) error {
 // GetTransaction retrieves a blockchain transaction by ID
 func (db *Database) GetTransaction(network blockchain.Network, txID string) (*blockchain.Transaction, error) {
 	var tx blockchain.Transaction
-	var amountValue, gasFeeValue string
+	var amountValue, gasFeeValue, amountOutValue string
 
 	err := db.db.QueryRow(`
 		SELECT id, network, type, status, from_address, to_address,
 			amount_value, amount_decimals, token_address, signature,
 			block_hash, block_number, timestamp, gas_fee_value,
-			gas_fee_decimals, error_message, created_at, last_updated_at
+			gas_fee_decimals, error_message, created_at, last_updated_at,
+			amount_out_value, amount_out_decimals, route
 		FROM blockchain_transactions
 		WHERE network = $1 AND id = $2
 	`, network, txID).Scan(
@@ -166,6 +202,7 @@ func (db *Database) GetTransaction(network blockchain.Network, txID string) (*bl
 		&tx.BlockHash, &tx.BlockNumber, &tx.Timestamp,
 		&gasFeeValue, &tx.GasFee.Decimals, &tx.ErrorMessage,
 		&tx.CreatedAt, &tx.LastUpdatedAt,
+		&amountOutValue, &tx.AmountOut.Decimals, &tx.Route,
 	)
 
 	if err == sql.ErrNoRows {
@@ -189,6 +226,14 @@ func (db *Database) GetTransaction(network blockchain.Network, txID string) (*bl
 		}
 	}
 
+	// Parse amount out value if present
+	if amountOutValue != "" {
+		err = json.Unmarshal([]byte(amountOutValue), &tx.AmountOut.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal amount out value: %w", err)
+		}
+	}
+
 	return &tx, nil
 }
 
@@ -198,7 +243,8 @@ func (db *Database) GetTransactions(network blockchain.Network, address string,
 		SELECT id, network, type, status, from_address, to_address,
 			amount_value, amount_decimals, token_address, signature,
 			block_hash, block_number, timestamp, gas_fee_value,
-			gas_fee_decimals, error_message, created_at, last_updated_at
+			gas_fee_decimals, error_message, created_at, last_updated_at,
+			amount_out_value, amount_out_decimals, route
 		FROM blockchain_transactions
 		WHERE network = $1 AND (from_address = $2 OR to_address = $2)
 		ORDER BY timestamp DESC
@@ -212,7 +258,7 @@ func (db *Database) GetTransactions(network blockchain.Network, address string,
 	var transactions []blockchain.Transaction
 	for rows.Next() {
 		var tx blockchain.Transaction
-		var amountValue, gasFeeValue string
+		var amountValue, gasFeeValue, amountOutValue string
 
 		err := rows.Scan(
 			&tx.ID, &tx.Network, &tx.Type, &tx.Status, &tx.FromAddress, &tx.ToAddress,
@@ -220,6 +266,7 @@ func (db *Database) GetTransactions(network blockchain.Network, address string,
 			&tx.BlockHash, &tx.BlockNumber, &tx.Timestamp,
 			&gasFeeValue, &tx.GasFee.Decimals, &tx.ErrorMessage,
 			&tx.CreatedAt, &tx.LastUpdatedAt,
+			&amountOutValue, &tx.AmountOut.Decimals, &tx.Route,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -239,6 +286,14 @@ func (db *Database) GetTransactions(network blockchain.Network, address string,
 			}
 		}
 
+		// Parse amount out value if present
+		if amountOutValue != "" {
+			err = json.Unmarshal([]byte(amountOutValue), &tx.AmountOut.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal amount out value: %w", err)
+			}
+		}
+
 		transactions = append(transactions, tx)
 	}
 
@@ -6,7 +6,7 @@ import (
 	"log"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type Database struct {
@@ -58,6 +58,11 @@ func NewDatabase(connStr string) (*Database, error) {
 	return &Database{db: db}, nil
 }
 
+// Close closes the connection pool
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
 func createTables(db *sql.DB) error {
 	// Create memecoins table
 	_, err := db.Exec(`
@@ -203,6 +208,33 @@ func (db *Database) GetMemeCoinByID(id string) (*MemeCoin, error) {
 	return &coin, nil
 }
 
+// GetTokenPrices returns the latest USD price of the meme coins with the
+// given contract addresses. Addresses without a known price are left out.
+func (db *Database) GetTokenPrices(addresses []string) (map[string]float64, error) {
+	rows, err := db.db.Query(`
+		SELECT DISTINCT ON (contract_address) contract_address, price
+		FROM memecoins
+		WHERE contract_address = ANY($1)
+		ORDER BY contract_address, last_updated DESC
+	`, pq.Array(addresses))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := make(map[string]float64)
+	for rows.Next() {
+		var address string
+		var price float64
+		if err := rows.Scan(&address, &price); err != nil {
+			return nil, fmt.Errorf("failed to scan token price: %w", err)
+		}
+		prices[address] = price
+	}
+
+	return prices, rows.Err()
+}
+
 func (db *Database) AddPriceHistory(history *PriceHistory) error {
 	_, err := db.db.Exec(`
 		INSERT INTO price_history (coin_id, price, volume, timestamp)